@@ -2,92 +2,415 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"runtime/debug"
+	"strings"
 	"syscall"
 	"time"
 
-	"routing-api/internal/circuit"
+	"routing-api/internal/accesslog"
 	"routing-api/internal/config"
-	"routing-api/internal/loadbalancer"
 	"routing-api/internal/logger"
+	"routing-api/internal/metrics"
 	"routing-api/internal/middleware"
 	"routing-api/internal/proxy"
+	"routing-api/internal/routing"
+	"routing-api/internal/tracing"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
+const configWatchInterval = 2 * time.Second
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+const usage = `Usage: routing-api [command] [flags]
+
+Commands:
+  run           start the server (the default)
+  validate      load and validate the configuration, then exit
+  print-config  print the effective configuration with secrets redacted
+  version       print the version
+
+Flags:
+`
+
+type options struct {
+	configPath string
+	overrides  config.Overrides
+}
+
+func (o options) load() (*config.Config, error) {
+	return config.LoadFileWithOverrides(o.configPath, o.overrides)
+}
+
 func main() {
-	cfg, err := config.Load()
+	command := "run"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet("routing-api "+command, flag.ExitOnError)
+	configPath := flags.String("config", "", "path to a YAML or JSON configuration file")
+	listen := flags.String("listen", "", "address of the first listener, e.g. :8080 (overrides PORT)")
+	logLevel := flags.String("log-level", "", "debug, info, warn or error (overrides LOG_LEVEL)")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := options{
+		configPath: *configPath,
+		overrides:  config.Overrides{ListenAddress: *listen, LogLevel: *logLevel},
+	}
+	switch command {
+	case "run":
+		run(opts)
+	case "validate":
+		os.Exit(validate(opts))
+	case "print-config":
+		os.Exit(printConfig(opts))
+	case "version":
+		fmt.Println(versionString())
+	case "help":
+		flags.SetOutput(os.Stdout)
+		flags.Usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// validate loads the configuration and reports every problem with it, exiting
+// non-zero when there are any so it can gate deployments in CI.
+func validate(opts options) int {
+	cfg, err := opts.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Configuration is valid: %d listeners, %d pools, %d routes\n", len(cfg.Listeners), len(cfg.Pools), len(cfg.Routes))
+	return 0
+}
+
+// printConfig writes the configuration in effect after defaults, the config file
+// and environment variables are merged, as YAML with credentials redacted.
+func printConfig(opts options) int {
+	cfg, err := opts.load()
 	if err != nil {
-		zap.L().Fatal("Failed to load configuration", zap.Error(err))
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
 
-	if err := logger.Init(cfg.LogLevel); err != nil {
-		zap.L().Fatal("Failed to initialize logger", zap.Error(err))
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := encoder.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func versionString() string {
+	v := "routing-api " + version
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			v += " (" + setting.Value[:12] + ")"
+		}
+	}
+	return v + " " + info.GoVersion
+}
+
+func run(opts options) {
+	cfg, err := opts.load()
+	if err != nil {
+		// The logger is configured from the configuration, so report to stderr.
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := logger.Init(cfg.LogLevel, cfg.LogFormat); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger:", err)
+		os.Exit(1)
 	}
 	defer logger.Sync()
 
 	log := logger.Global()
+	setLogLevels(cfg, log)
 	log.Info("Starting routing API server",
 		zap.String("port", cfg.Port),
+		zap.String("config_file", opts.configPath),
 		zap.String("environment", cfg.Environment),
 		zap.Strings("servers", cfg.ApplicationAPIs),
+		zap.Int("pools", len(cfg.Pools)),
+		zap.Int("routes", len(cfg.Routes)),
 		zap.String("log_level", cfg.LogLevel),
+		zap.Any("log_levels", cfg.LogLevels),
 	)
 
-	circuitConfig := circuit.CircuitBreakerConfig{
-		MaxFailures:  cfg.MaxFailures,
-		ResetTimeout: cfg.ResetTimeout,
+	var tracer *tracing.Tracer
+	if cfg.Tracing.Enabled() {
+		tracer = tracing.NewTracer(cfg.Tracing, log)
+		tracing.SetGlobal(tracer)
+		tracer.Start()
+		log.Info("Tracing enabled",
+			zap.String("endpoint", cfg.Redacted().Tracing.Endpoint),
+			zap.Float64("sample_ratio", cfg.Tracing.SampleRatio),
+		)
 	}
 
-	loadBalancerFactory := loadbalancer.NewLoadBalancerFactory()
-	loadBalancer := loadBalancerFactory.CreateLoadBalancer(cfg.BalancerType, cfg.ApplicationAPIs, circuitConfig, log)
-	clientProvider := loadbalancer.NewLoadBalancerAdapter(loadBalancer)
-	handler := proxy.NewProxyHandler(clientProvider, log)
+	routes, err := routing.NewRouter(cfg, log)
+	if err != nil {
+		log.Fatal("Failed to build route table", zap.Error(err))
+	}
+	probes := proxy.NewProbeHandler(routes)
+	routing.RegisterMetrics(metrics.Default, routes)
 
 	router := mux.NewRouter()
 
-	router.Use(middleware.LoggingMiddleware())
-
-	router.HandleFunc("/health", handler.HealthHandler).Methods("GET")
-	router.PathPrefix("/").HandlerFunc(handler.ProxyRequest)
-
-	server := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	router.Use(middleware.RequestID)
+	var accessLog *accesslog.Logger
+	if cfg.AccessLog.Enabled() {
+		accessLog, err = accesslog.New(cfg.AccessLog, log)
+		if err != nil {
+			log.Fatal("Failed to open access log", zap.Error(err))
+		}
+		defer accessLog.Close()
+		router.Use(accessLog.Middleware)
 	}
 
+	router.HandleFunc("/health", probes.HealthHandler).Methods("GET")
+	router.HandleFunc("/livez", probes.LivenessHandler).Methods("GET")
+	router.HandleFunc("/readyz", probes.ReadinessHandler).Methods("GET")
+	router.HandleFunc("/status", probes.StatusHandler).Methods("GET")
+	router.PathPrefix("/").Handler(routes)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go handler.StartHealthChecks(ctx, cfg.HealthCheckInterval)
+	routes.Start(ctx)
 
-	go func() {
-		log.Info("Server starting", zap.String("addr", server.Addr))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Server failed", zap.Error(err))
-		}
-	}()
+	var servers []*http.Server
+	for _, listener := range cfg.Listeners {
+		server := newServer(listener.Address, router)
+		servers = append(servers, server)
+
+		go func(listener config.ListenerConfig) {
+			tls := listener.TLSCertFile != ""
+			log.Info("Server starting", zap.String("addr", listener.Address), zap.Bool("tls", tls))
+			var err error
+			if tls {
+				err = server.ListenAndServeTLS(listener.TLSCertFile, listener.TLSKeyFile)
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatal("Server failed", zap.Error(err))
+			}
+		}(listener)
+	}
+
+	if cfg.AdminAddress != "" {
+		admin := newServer(cfg.AdminAddress, adminHandler(cfg, routes))
+		servers = append(servers, admin)
+
+		go func() {
+			log.Info("Admin API starting", zap.String("addr", cfg.AdminAddress), zap.Bool("token", cfg.AdminToken != ""))
+			if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("Admin API failed", zap.Error(err))
+			}
+		}()
+	}
+
+	if cfg.MetricsAddress != "" {
+		metricsServer := newServer(cfg.MetricsAddress, metricsHandler())
+		servers = append(servers, metricsServer)
+
+		go func() {
+			log.Info("Metrics starting", zap.String("addr", cfg.MetricsAddress))
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("Metrics listener failed", zap.Error(err))
+			}
+		}()
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	toggleDebug := make(chan os.Signal, 1)
+	signal.Notify(toggleDebug, syscall.SIGUSR1)
+	debugging := false
+	changes := config.WatchFile(ctx, opts.configPath, configWatchInterval)
+
+	for running := true; running; {
+		select {
+		case <-quit:
+			running = false
+		case <-hangup:
+			reopenAccessLog(accessLog, log)
+			cfg = reloadConfig(opts, cfg, routes, log)
+		case <-changes:
+			cfg = reloadConfig(opts, cfg, routes, log)
+		case <-toggleDebug:
+			debugging = !debugging
+			if debugging {
+				debugLogging(log)
+			} else {
+				setLogLevels(cfg, log)
+			}
+		}
+	}
 
 	log.Info("Shutting down server...")
+	probes.StartDrain()
+	if cfg.ShutdownDelay > 0 {
+		log.Info("Draining before shutdown", zap.Duration("delay", cfg.ShutdownDelay))
+		time.Sleep(cfg.ShutdownDelay)
+	}
 	cancel()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatal("Forced shutdown", zap.Error(err))
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Fatal("Forced shutdown", zap.Error(err))
+		}
+	}
+	if tracer != nil {
+		tracer.Shutdown(shutdownCtx)
 	}
 
 	log.Info("Server stopped")
 }
+
+// reloadConfig loads the configuration again and swaps in a new route table,
+// keeping the current one when the configuration is invalid. Listeners are
+// bound at startup and need a restart to change.
+func reloadConfig(opts options, current *config.Config, routes *routing.Router, log logger.Logger) *config.Config {
+	log.Info("Reloading configuration", zap.String("config_file", opts.configPath))
+
+	cfg, err := opts.load()
+	if err == nil {
+		err = routes.Reload(cfg)
+	}
+	if err != nil {
+		log.Error("Configuration rejected, keeping the current configuration", zap.Error(err))
+		return current
+	}
+
+	if !reflect.DeepEqual(cfg.Listeners, current.Listeners) {
+		log.Warn("Listener changes take effect after a restart")
+	}
+	if cfg.AdminAddress != current.AdminAddress || cfg.AdminToken != current.AdminToken {
+		log.Warn("Admin API changes take effect after a restart")
+	}
+	if cfg.MetricsAddress != current.MetricsAddress {
+		log.Warn("Metrics address changes take effect after a restart")
+	}
+	if cfg.Tracing != current.Tracing {
+		log.Warn("Tracing changes take effect after a restart")
+	}
+	if !reflect.DeepEqual(cfg.AccessLog, current.AccessLog) {
+		log.Warn("Access log changes take effect after a restart")
+	}
+	if cfg.LogFormat != current.LogFormat {
+		log.Warn("Log format changes take effect after a restart")
+	}
+	// Levels set through the admin API or SIGUSR1 are kept unless the
+	// configured ones changed.
+	if cfg.LogLevel != current.LogLevel || !maps.Equal(cfg.LogLevels, current.LogLevels) {
+		setLogLevels(cfg, log)
+	}
+	log.Info("Configuration reloaded",
+		zap.Int("pools", len(cfg.Pools)),
+		zap.Int("routes", len(cfg.Routes)),
+	)
+	return cfg
+}
+
+// setLogLevels applies the configured global and component log levels.
+func setLogLevels(cfg *config.Config, log logger.Logger) {
+	logger.SetLevel(cfg.LogLevel)
+	for _, component := range logger.Components {
+		logger.SetComponentLevel(component, cfg.LogLevels[component])
+	}
+	log.Info("Log levels set", zap.String("level", cfg.LogLevel), zap.Any("components", cfg.LogLevels))
+}
+
+// debugLogging turns on debug logs everywhere until SIGUSR1 is sent again.
+func debugLogging(log logger.Logger) {
+	logger.SetLevel("debug")
+	for _, component := range logger.Components {
+		logger.SetComponentLevel(component, "")
+	}
+	log.Info("Debug logging on until the next SIGUSR1")
+}
+
+// reopenAccessLog lets the access log file be rotated by renaming it and sending
+// SIGHUP.
+func reopenAccessLog(accessLog *accesslog.Logger, log logger.Logger) {
+	if accessLog == nil {
+		return
+	}
+	if err := accessLog.Reopen(); err != nil {
+		log.Error("Cannot reopen access log", zap.Error(err))
+	}
+}
+
+func metricsHandler() http.Handler {
+	router := mux.NewRouter()
+	router.Handle("/metrics", metrics.Default.Handler()).Methods("GET")
+	return router
+}
+
+// adminHandler serves the admin API. Without a token only local clients may use
+// it.
+func adminHandler(cfg *config.Config, routes *routing.Router) http.Handler {
+	admin := routing.NewAdminHandler(routes)
+	if cfg.AdminToken != "" {
+		return middleware.BearerToken(cfg.AdminToken, admin)
+	}
+	return middleware.LoopbackOnly(admin)
+}
+
+func newServer(addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		Protocols:    new(http.Protocols),
+	}
+	server.Protocols.SetHTTP1(true)
+	server.Protocols.SetHTTP2(true)
+	server.Protocols.SetUnencryptedHTTP2(true)
+	return server
+}
@@ -1,10 +1,12 @@
 module routing-api
 
-go 1.21
+go 1.24
 
 require (
 	github.com/gorilla/mux v1.8.1
 	github.com/stretchr/testify v1.11.1
+	go.uber.org/zap v1.27.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,6 +14,4 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
-	go.uber.org/zap v1.27.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
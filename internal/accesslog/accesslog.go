@@ -0,0 +1,262 @@
+// Package accesslog writes a line for every request the router serves, in JSON,
+// in the Apache combined format or from a template.
+package accesslog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+	"routing-api/internal/middleware"
+
+	"go.uber.org/zap"
+)
+
+// Entry is what is known about a request once it has been served.
+type Entry struct {
+	Time            time.Time
+	RequestID       string
+	ClientIP        string
+	Method          string
+	Host            string
+	URI             string
+	Protocol        string
+	Status          int
+	Bytes           int64
+	Duration        time.Duration
+	Route           string
+	Backend         string
+	UpstreamLatency time.Duration
+	UserAgent       string
+	Referer         string
+	Error           string
+}
+
+// Logger is the middleware that writes access log lines.
+type Logger struct {
+	format  formatter
+	output  *output
+	ratio   float64
+	exclude []string
+}
+
+func New(accessLog config.AccessLogConfig, logger logger.Logger) (*Logger, error) {
+	format, err := newFormatter(accessLog.Format, accessLog.Template)
+	if err != nil {
+		return nil, err
+	}
+	output, err := openOutput(accessLog.File, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{
+		format:  format,
+		output:  output,
+		ratio:   accessLog.SampleRatio,
+		exclude: accessLog.ExcludePaths,
+	}, nil
+}
+
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.excluded(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
+		details := &Details{}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), detailsKey{}, details)))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if !l.sampled(status) {
+			return
+		}
+		l.write(&Entry{
+			Time:            start,
+			RequestID:       r.Header.Get(middleware.RequestIDHeader),
+			ClientIP:        clientIP(r),
+			Method:          r.Method,
+			Host:            r.Host,
+			URI:             r.RequestURI,
+			Protocol:        r.Proto,
+			Status:          status,
+			Bytes:           recorder.bytes,
+			Duration:        time.Since(start),
+			Route:           details.route,
+			Backend:         details.backend,
+			UpstreamLatency: details.upstreamLatency,
+			UserAgent:       r.UserAgent(),
+			Referer:         r.Referer(),
+			Error:           details.err,
+		})
+	})
+}
+
+// Reopen opens the log file again, for use after it has been rotated.
+func (l *Logger) Reopen() error {
+	return l.output.reopen()
+}
+
+func (l *Logger) Close() error {
+	return l.output.close()
+}
+
+func (l *Logger) write(entry *Entry) {
+	l.output.write(l.format(nil, entry))
+}
+
+// excluded reports whether path is one of the excluded paths or, for those
+// ending in /*, below one.
+func (l *Logger) excluded(path string) bool {
+	for _, exclude := range l.exclude {
+		if prefix, ok := strings.CutSuffix(exclude, "/*"); ok {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		} else if path == exclude {
+			return true
+		}
+	}
+	return false
+}
+
+// sampled decides whether a request is logged. Server errors always are.
+func (l *Logger) sampled(status int) bool {
+	return l.ratio >= 1 || status >= 500 || rand.Float64() < l.ratio
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// output serializes lines to standard output or a file.
+type output struct {
+	mutex   sync.Mutex
+	path    string
+	writer  io.Writer
+	file    *os.File
+	failing atomic.Bool
+	logger  logger.Logger
+}
+
+func openOutput(path string, logger logger.Logger) (*output, error) {
+	o := &output{path: path, writer: os.Stdout, logger: logger}
+	if path != "" {
+		file, err := openFile(path)
+		if err != nil {
+			return nil, err
+		}
+		o.writer, o.file = file, file
+	}
+	return o, nil
+}
+
+func openFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open access log: %w", err)
+	}
+	return file, nil
+}
+
+// write logs the first of a run of failed writes, so a full disk does not flood
+// the application log.
+func (o *output) write(line []byte) {
+	o.mutex.Lock()
+	_, err := o.writer.Write(line)
+	o.mutex.Unlock()
+
+	if err == nil {
+		o.failing.Store(false)
+	} else if !o.failing.Swap(true) {
+		o.logger.Warn("Cannot write access log", zap.String("file", o.path), zap.Error(err))
+	}
+}
+
+func (o *output) reopen() error {
+	if o.file == nil {
+		return nil
+	}
+	file, err := openFile(o.path)
+	if err != nil {
+		return err
+	}
+	o.mutex.Lock()
+	old := o.file
+	o.writer, o.file = file, file
+	o.mutex.Unlock()
+	return old.Close()
+}
+
+func (o *output) close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	return o.file.Close()
+}
+
+// responseRecorder notes the status and size of a response. Flushing and
+// hijacking reach the underlying writer.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) FlushError() error {
+	return http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *responseRecorder) Flush() {
+	r.FlushError()
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,190 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+	"routing-api/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testLogger struct{}
+
+func (l *testLogger) Debug(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Info(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Warn(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Error(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Fatal(msg string, fields ...zap.Field)  {}
+func (l *testLogger) With(fields ...zap.Field) logger.Logger { return l }
+func (l *testLogger) Sync() error                            { return nil }
+
+func newTestLogger(t *testing.T, accessLog config.AccessLogConfig) (*Logger, string) {
+	t.Helper()
+	accessLog.File = filepath.Join(t.TempDir(), "access.log")
+	if accessLog.SampleRatio == 0 {
+		accessLog.SampleRatio = 1
+	}
+	l, err := New(accessLog, &testLogger{})
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	return l, accessLog.File
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestMiddleware_JSON(t *testing.T) {
+	l, path := newTestLogger(t, config.AccessLogConfig{Format: config.AccessLogJSON})
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		details := FromContext(r.Context())
+		details.SetRoute("/orders/*")
+		details.SetBackend("http://10.0.0.5:8080")
+		details.SetUpstreamLatency(12345 * time.Microsecond)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	req := httptest.NewRequest("POST", "/orders/1?expand=items", nil)
+	req.RemoteAddr = "192.0.2.10:51234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 1)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "abc-123", entry["request_id"])
+	assert.Equal(t, "192.0.2.10", entry["client_ip"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/orders/1?expand=items", entry["uri"])
+	assert.Equal(t, "HTTP/1.1", entry["protocol"])
+	assert.Equal(t, float64(201), entry["status"])
+	assert.Equal(t, float64(7), entry["bytes"])
+	assert.Equal(t, "/orders/*", entry["route"])
+	assert.Equal(t, "http://10.0.0.5:8080", entry["backend"])
+	assert.Equal(t, 12.345, entry["upstream_latency_ms"])
+	assert.Equal(t, "curl/8.0", entry["user_agent"])
+	assert.Contains(t, entry, "duration_ms")
+	assert.NotContains(t, entry, "error")
+	assert.NotContains(t, entry, "referer")
+}
+
+func TestFormatCombined(t *testing.T) {
+	entry := &Entry{
+		Time:      time.Date(2024, 3, 5, 14, 7, 9, 0, time.FixedZone("", 3600)),
+		ClientIP:  "192.0.2.10",
+		Method:    "GET",
+		URI:       "/a\"b",
+		Protocol:  "HTTP/1.1",
+		Status:    200,
+		Bytes:     512,
+		UserAgent: "agent\nforged",
+	}
+	assert.Equal(t,
+		`192.0.2.10 - - [05/Mar/2024:14:07:09 +0100] "GET /a\"b HTTP/1.1" 200 512 "-" "agent\x0aforged"`+"\n",
+		string(formatCombined(nil, entry)),
+	)
+
+	entry.Bytes = 0
+	entry.Referer = "https://example.com/"
+	assert.Contains(t, string(formatCombined(nil, entry)), `200 - "https://example.com/"`)
+}
+
+func TestParseTemplate(t *testing.T) {
+	format, err := parseTemplate("{client_ip} {method} {uri} {status} {duration_ms}ms {backend} {upstream_latency_ms} err={error} {")
+	require.NoError(t, err)
+	entry := &Entry{
+		ClientIP: "192.0.2.10",
+		Method:   "GET",
+		URI:      "/",
+		Status:   502,
+		Duration: 1500 * time.Microsecond,
+		Error:    `dial "tcp"`,
+	}
+	assert.Equal(t, `192.0.2.10 GET / 502 1.500ms - - err=dial \"tcp\" {`+"\n", string(format(nil, entry)))
+
+	_, err = parseTemplate("{client_ip} {latency}")
+	assert.EqualError(t, err, "unknown access log field {latency}")
+
+	_, err = New(config.AccessLogConfig{Format: config.AccessLogTemplate, Template: "{nope}"}, &testLogger{})
+	assert.Error(t, err)
+
+	for _, name := range config.AccessLogFields {
+		assert.Contains(t, templateFields, name)
+	}
+	assert.Len(t, templateFields, len(config.AccessLogFields))
+}
+
+func TestMiddleware_ExcludeAndSample(t *testing.T) {
+	l, path := newTestLogger(t, config.AccessLogConfig{
+		Format:       config.AccessLogTemplate,
+		Template:     "{uri} {status}",
+		ExcludePaths: []string{"/health", "/static/*"},
+	})
+	l.ratio = 0
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	for _, target := range []string{"/health", "/static", "/static/app.js", "/orders", "/fail", "/healthz"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	assert.Equal(t, []string{"/fail 503"}, readLines(t, path))
+
+	l.ratio = 1
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/static/", nil))
+	assert.Equal(t, []string{"/fail 503", "/healthz 200"}, readLines(t, path))
+}
+
+func TestMiddleware_Flush(t *testing.T) {
+	l, _ := newTestLogger(t, config.AccessLogConfig{Format: config.AccessLogJSON})
+	recorder := httptest.NewRecorder()
+	l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		assert.NoError(t, http.NewResponseController(w).Flush())
+		_, _, err := http.NewResponseController(w).Hijack()
+		assert.ErrorIs(t, err, http.ErrNotSupported)
+	})).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.True(t, recorder.Flushed)
+}
+
+func TestLogger_Reopen(t *testing.T) {
+	l, path := newTestLogger(t, config.AccessLogConfig{Format: config.AccessLogTemplate, Template: "{uri}"})
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/before", nil))
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, l.Reopen())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/after", nil))
+
+	assert.Equal(t, []string{"/before"}, readLines(t, path+".1"))
+	assert.Equal(t, []string{"/after"}, readLines(t, path))
+}
+
+func TestDetails_Nil(t *testing.T) {
+	details := FromContext(httptest.NewRequest("GET", "/", nil).Context())
+	assert.Nil(t, details)
+	details.SetRoute("/")
+	details.SetBackend("http://localhost:8080")
+	details.SetUpstreamLatency(time.Second)
+	details.SetError("failed")
+}
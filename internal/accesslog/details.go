@@ -0,0 +1,53 @@
+package accesslog
+
+import (
+	"context"
+	"time"
+)
+
+type detailsKey struct{}
+
+// Details are what the handler serving a request knows that the access log
+// cannot see from outside, such as the backend it chose. Their methods do
+// nothing on a nil Details, so handlers need not check whether the request is
+// being logged.
+type Details struct {
+	route           string
+	backend         string
+	upstreamLatency time.Duration
+	err             string
+}
+
+// FromContext returns the details of the request with context ctx, or nil when
+// it is not being logged.
+func FromContext(ctx context.Context) *Details {
+	details, _ := ctx.Value(detailsKey{}).(*Details)
+	return details
+}
+
+func (d *Details) SetRoute(route string) {
+	if d != nil {
+		d.route = route
+	}
+}
+
+func (d *Details) SetBackend(backend string) {
+	if d != nil {
+		d.backend = backend
+	}
+}
+
+// SetUpstreamLatency records how long the backend took to return response
+// headers.
+func (d *Details) SetUpstreamLatency(latency time.Duration) {
+	if d != nil {
+		d.upstreamLatency = latency
+	}
+}
+
+// SetError records why the request failed.
+func (d *Details) SetError(reason string) {
+	if d != nil {
+		d.err = reason
+	}
+}
@@ -0,0 +1,192 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"routing-api/internal/config"
+)
+
+// formatter appends the line for an entry, newline included, to b.
+type formatter func(b []byte, e *Entry) []byte
+
+const timeFormat = "2006-01-02T15:04:05.000Z0700"
+
+func newFormatter(format, template string) (formatter, error) {
+	switch format {
+	case config.AccessLogJSON:
+		return formatJSON, nil
+	case config.AccessLogCombined:
+		return formatCombined, nil
+	case config.AccessLogTemplate:
+		return parseTemplate(template)
+	}
+	return nil, fmt.Errorf("unknown access log format %q", format)
+}
+
+type jsonEntry struct {
+	Time              string   `json:"time"`
+	RequestID         string   `json:"request_id,omitempty"`
+	ClientIP          string   `json:"client_ip"`
+	Method            string   `json:"method"`
+	Host              string   `json:"host"`
+	URI               string   `json:"uri"`
+	Protocol          string   `json:"protocol"`
+	Status            int      `json:"status"`
+	Bytes             int64    `json:"bytes"`
+	DurationMS        float64  `json:"duration_ms"`
+	Route             string   `json:"route,omitempty"`
+	Backend           string   `json:"backend,omitempty"`
+	UpstreamLatencyMS *float64 `json:"upstream_latency_ms,omitempty"`
+	UserAgent         string   `json:"user_agent,omitempty"`
+	Referer           string   `json:"referer,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+func formatJSON(b []byte, e *Entry) []byte {
+	entry := jsonEntry{
+		Time:       e.Time.Format(timeFormat),
+		RequestID:  e.RequestID,
+		ClientIP:   e.ClientIP,
+		Method:     e.Method,
+		Host:       e.Host,
+		URI:        e.URI,
+		Protocol:   e.Protocol,
+		Status:     e.Status,
+		Bytes:      e.Bytes,
+		DurationMS: milliseconds(e.Duration),
+		Route:      e.Route,
+		Backend:    e.Backend,
+		UserAgent:  e.UserAgent,
+		Referer:    e.Referer,
+		Error:      e.Error,
+	}
+	if e.Backend != "" && e.UpstreamLatency > 0 {
+		latency := milliseconds(e.UpstreamLatency)
+		entry.UpstreamLatencyMS = &latency
+	}
+	// Every field is a string or a number, so encoding cannot fail.
+	line, _ := json.Marshal(entry)
+	b = append(b, line...)
+	return append(b, '\n')
+}
+
+// formatCombined writes the Apache and NGINX combined format:
+//
+//	client - - [time] "request line" status bytes "referer" "user agent"
+func formatCombined(b []byte, e *Entry) []byte {
+	b = append(b, e.ClientIP...)
+	b = append(b, " - - ["...)
+	b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, "] \""...)
+	b = appendEscaped(b, e.Method+" "+e.URI+" "+e.Protocol)
+	b = append(b, "\" "...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	b = append(b, dash(bytesValue(e))...)
+	b = append(b, " \""...)
+	b = appendEscaped(b, dash(e.Referer))
+	b = append(b, "\" \""...)
+	b = appendEscaped(b, dash(e.UserAgent))
+	return append(b, "\"\n"...)
+}
+
+// templateFields are the values of config.AccessLogFields.
+var templateFields = map[string]func(e *Entry) string{
+	"time":                func(e *Entry) string { return e.Time.Format(timeFormat) },
+	"request_id":          func(e *Entry) string { return e.RequestID },
+	"client_ip":           func(e *Entry) string { return e.ClientIP },
+	"method":              func(e *Entry) string { return e.Method },
+	"host":                func(e *Entry) string { return e.Host },
+	"uri":                 func(e *Entry) string { return e.URI },
+	"protocol":            func(e *Entry) string { return e.Protocol },
+	"status":              func(e *Entry) string { return strconv.Itoa(e.Status) },
+	"bytes":               func(e *Entry) string { return strconv.FormatInt(e.Bytes, 10) },
+	"duration_ms":         func(e *Entry) string { return formatMilliseconds(e.Duration) },
+	"route":               func(e *Entry) string { return e.Route },
+	"backend":             func(e *Entry) string { return e.Backend },
+	"upstream_latency_ms": upstreamLatency,
+	"user_agent":          func(e *Entry) string { return e.UserAgent },
+	"referer":             func(e *Entry) string { return e.Referer },
+	"error":               func(e *Entry) string { return e.Error },
+}
+
+// parseTemplate compiles a template such as
+// "{client_ip} {method} {uri} {status} {duration_ms}ms". Empty values are
+// written as -.
+func parseTemplate(template string) (formatter, error) {
+	var parts []func(b []byte, e *Entry) []byte
+	err := config.ParseAccessLogTemplate(template,
+		func(text string) {
+			parts = append(parts, literal(text))
+		},
+		func(name string) {
+			field := templateFields[name]
+			parts = append(parts, func(b []byte, e *Entry) []byte {
+				return appendEscaped(b, dash(field(e)))
+			})
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(b []byte, e *Entry) []byte {
+		for _, part := range parts {
+			b = part(b, e)
+		}
+		return append(b, '\n')
+	}, nil
+}
+
+func literal(text string) func(b []byte, e *Entry) []byte {
+	return func(b []byte, e *Entry) []byte { return append(b, text...) }
+}
+
+func upstreamLatency(e *Entry) string {
+	if e.Backend == "" || e.UpstreamLatency <= 0 {
+		return ""
+	}
+	return formatMilliseconds(e.UpstreamLatency)
+}
+
+func bytesValue(e *Entry) string {
+	if e.Bytes == 0 {
+		return ""
+	}
+	return strconv.FormatInt(e.Bytes, 10)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(milliseconds(d), 'f', 3, 64)
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// appendEscaped writes value with quotes, backslashes and bytes outside
+// printable ASCII escaped, so that a client cannot forge or break up lines.
+func appendEscaped(b []byte, value string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20 || c >= 0x7f:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
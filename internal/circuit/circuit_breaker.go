@@ -3,8 +3,16 @@ package circuit
 import (
 	"sync"
 	"time"
+
+	"routing-api/internal/logger"
+	"routing-api/internal/metrics"
+
+	"go.uber.org/zap"
 )
 
+var circuitTransitions = metrics.Default.NewCounterVec("routing_api_circuit_transitions_total",
+	"Circuit breaker state changes, by backend and the states changed from and to.", "backend", "from", "to")
+
 type CircuitBreakerState int
 
 const (
@@ -13,6 +21,19 @@ const (
 	StateHalfOpen
 )
 
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
 type CircuitBreaker struct {
 	state         CircuitBreakerState
 	failureCount  int
@@ -22,7 +43,12 @@ type CircuitBreaker struct {
 	slowThreshold time.Duration
 	slowCount     int
 	maxSlowCount  int
-	mutex         sync.RWMutex
+	// forced holds the breaker in its current state, ignoring results and the
+	// reset timeout, until Release is called.
+	forced bool
+	// backend names the breaker's backend in metrics and logs.
+	backend string
+	mutex   sync.RWMutex
 }
 
 func NewCircuitBreaker(maxFailures int, resetTimeout time.Duration) *CircuitBreaker {
@@ -50,25 +76,45 @@ func NewCircuitBreakerWithSlowThreshold(maxFailures int, resetTimeout time.Durat
 }
 
 func (cb *CircuitBreaker) Execute(operation func() error) error {
+	if err := cb.allow(); err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	err := operation()
+	responseTime := time.Since(startTime)
+
+	return cb.record(err, responseTime)
+}
+
+func (cb *CircuitBreaker) allow() error {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
 	switch cb.state {
 	case StateClosed:
 	case StateOpen:
-		if time.Since(cb.lastIssueTime) >= cb.resetTimeout {
-			cb.state = StateHalfOpen
+		if !cb.forced && time.Since(cb.lastIssueTime) >= cb.resetTimeout {
+			cb.setState(StateHalfOpen)
 		} else {
-			return &CircuitBreakerError{Message: "circuit breaker is open"}
+			return &CircuitBreakerError{Message: "circuit breaker is open", Open: true}
 		}
 	case StateHalfOpen:
 	}
+	return nil
+}
 
-	startTime := time.Now()
-	err := operation()
-	responseTime := time.Since(startTime)
+func (cb *CircuitBreaker) record(err error, responseTime time.Duration) error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
 
 	isSlow := responseTime > cb.slowThreshold
+	if cb.forced {
+		if isSlow && err == nil {
+			return &CircuitBreakerError{Message: "response too slow"}
+		}
+		return err
+	}
 
 	if err != nil || isSlow {
 		cb.lastIssueTime = time.Now()
@@ -81,7 +127,7 @@ func (cb *CircuitBreaker) Execute(operation func() error) error {
 		}
 
 		if cb.state == StateHalfOpen || cb.failureCount >= cb.maxFailures || cb.slowCount >= cb.maxSlowCount {
-			cb.state = StateOpen
+			cb.setState(StateOpen)
 		}
 
 		if isSlow && err == nil {
@@ -92,10 +138,73 @@ func (cb *CircuitBreaker) Execute(operation func() error) error {
 
 	cb.failureCount = 0
 	cb.slowCount = 0
-	cb.state = StateClosed
+	cb.setState(StateClosed)
 	return nil
 }
 
+// setState moves the breaker to state and counts the change. The caller holds
+// the mutex.
+func (cb *CircuitBreaker) setState(state CircuitBreakerState) {
+	if cb.state == state {
+		return
+	}
+	circuitTransitions.WithLabelValues(cb.backend, cb.state.String(), state.String()).Inc()
+	log := logger.Component(logger.Global(), logger.ComponentCircuit)
+	fields := []zap.Field{zap.String("backend", cb.backend), zap.String("from", cb.state.String()), zap.String("to", state.String())}
+	if state == StateOpen {
+		log.Warn("Circuit opened", fields...)
+	} else {
+		log.Info("Circuit state changed", fields...)
+	}
+	cb.state = state
+}
+
+// Force holds the breaker open or closed until Release is called, for taking a
+// backend out of service or keeping it in service by hand.
+func (cb *CircuitBreaker) Force(state CircuitBreakerState) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.setState(state)
+	cb.forced = true
+	cb.failureCount = 0
+	cb.slowCount = 0
+	if state == StateOpen {
+		cb.lastIssueTime = time.Now()
+	}
+}
+
+// Release ends a forced state and closes the breaker.
+func (cb *CircuitBreaker) Release() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.forced = false
+	cb.reset()
+}
+
+func (cb *CircuitBreaker) IsForced() bool {
+	cb.mutex.RLock()
+	defer cb.mutex.RUnlock()
+	return cb.forced
+}
+
+// Reset closes the breaker and clears its counters, unless it is forced.
+func (cb *CircuitBreaker) Reset() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if !cb.forced {
+		cb.reset()
+	}
+}
+
+func (cb *CircuitBreaker) reset() {
+	cb.failureCount = 0
+	cb.slowCount = 0
+	cb.setState(StateClosed)
+}
+
 func (cb *CircuitBreaker) IsOpen() bool {
 	cb.mutex.RLock()
 	defer cb.mutex.RUnlock()
@@ -123,6 +232,8 @@ func (cb *CircuitBreaker) GetFailureCount() int {
 
 type CircuitBreakerError struct {
 	Message string
+	// Open is set when the request was refused without reaching the backend.
+	Open bool
 }
 
 func (e *CircuitBreakerError) Error() string {
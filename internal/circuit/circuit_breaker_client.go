@@ -8,8 +8,10 @@ import (
 )
 
 type CircuitBreakerConfig struct {
-	MaxFailures  int
-	ResetTimeout time.Duration
+	MaxFailures   int
+	ResetTimeout  time.Duration
+	SlowThreshold time.Duration
+	MaxSlowCount  int
 }
 
 type CircuitBreakerClient struct {
@@ -19,6 +21,10 @@ type CircuitBreakerClient struct {
 
 func NewCircuitBreakerClient(client health.HTTPClient, circuitConfig CircuitBreakerConfig) *CircuitBreakerClient {
 	circuitBreaker := NewCircuitBreaker(circuitConfig.MaxFailures, circuitConfig.ResetTimeout)
+	if circuitConfig.SlowThreshold > 0 && circuitConfig.MaxSlowCount > 0 {
+		circuitBreaker = NewCircuitBreakerWithSlowThreshold(circuitConfig.MaxFailures, circuitConfig.ResetTimeout, circuitConfig.SlowThreshold, circuitConfig.MaxSlowCount)
+	}
+	circuitBreaker.backend = client.GetBaseURL()
 
 	return &CircuitBreakerClient{
 		client:         client,
@@ -27,6 +33,10 @@ func NewCircuitBreakerClient(client health.HTTPClient, circuitConfig CircuitBrea
 }
 
 func (cbc *CircuitBreakerClient) Do(req *http.Request) (*http.Response, error) {
+	if isGRPCRequest(req) {
+		return cbc.doGRPC(req)
+	}
+
 	var resp *http.Response
 	err := cbc.circuitBreaker.Execute(func() error {
 		var execErr error
@@ -34,6 +44,17 @@ func (cbc *CircuitBreakerClient) Do(req *http.Request) (*http.Response, error) {
 		return execErr
 	})
 
+	return discardOnError(resp, err)
+}
+
+// discardOnError closes the body of a response that is returned with an error,
+// such as one that came back too slowly, so that callers, like those of
+// http.Client, only close bodies of successful responses.
+func discardOnError(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil && resp != nil {
+		resp.Body.Close()
+		return nil, err
+	}
 	return resp, err
 }
 
@@ -45,14 +66,50 @@ func (cbc *CircuitBreakerClient) SetUp(isUp bool) {
 	cbc.client.SetUp(isUp)
 
 	if isUp {
-		cbc.circuitBreaker.mutex.Lock()
-		cbc.circuitBreaker.failureCount = 0
-		cbc.circuitBreaker.slowCount = 0
-		cbc.circuitBreaker.state = StateClosed
-		cbc.circuitBreaker.mutex.Unlock()
+		cbc.circuitBreaker.Reset()
 	}
 }
 
+func (cbc *CircuitBreakerClient) State() health.BackendState {
+	return cbc.client.State()
+}
+
+func (cbc *CircuitBreakerClient) SetDraining(draining bool) {
+	cbc.client.SetDraining(draining)
+}
+
+// ForceCircuit holds the circuit open or closed until ReleaseCircuit is called.
+func (cbc *CircuitBreakerClient) ForceCircuit(state CircuitBreakerState) {
+	cbc.circuitBreaker.Force(state)
+}
+
+func (cbc *CircuitBreakerClient) ReleaseCircuit() {
+	cbc.circuitBreaker.Release()
+}
+
 func (cbc *CircuitBreakerClient) GetBaseURL() string {
 	return cbc.client.GetBaseURL()
 }
+
+func (cbc *CircuitBreakerClient) SetLastCheck(at time.Time) {
+	cbc.client.SetLastCheck(at)
+}
+
+func (cbc *CircuitBreakerClient) InFlight() int64 {
+	return cbc.client.InFlight()
+}
+
+func (cbc *CircuitBreakerClient) CloseIdleConnections() {
+	if closer, ok := cbc.client.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (cbc *CircuitBreakerClient) Status() health.BackendStatus {
+	status := cbc.client.Status()
+	status.CircuitState = cbc.circuitBreaker.GetState().String()
+	status.FailureCount = cbc.circuitBreaker.GetFailureCount()
+	status.SlowCount = cbc.circuitBreaker.GetSlowCount()
+	status.CircuitForced = cbc.circuitBreaker.IsForced()
+	return status
+}
@@ -1,6 +1,7 @@
 package circuit
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -60,6 +61,33 @@ func TestCircuitBreakerClient_HTTPError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 }
 
+func TestCircuitBreakerClient_SlowResponseIsClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("late"))
+	}))
+	defer server.Close()
+
+	baseClient := health.NewDefaultHTTPClient(server.URL, 5*time.Second, time.Second)
+	circuitBreakerClient := NewCircuitBreakerClient(baseClient, CircuitBreakerConfig{
+		MaxFailures:   5,
+		ResetTimeout:  60 * time.Second,
+		SlowThreshold: time.Millisecond,
+		MaxSlowCount:  5,
+	})
+
+	for _, contentType := range []string{"", "application/grpc"} {
+		req, err := http.NewRequest("POST", "/test", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := circuitBreakerClient.Do(req)
+		assert.ErrorContains(t, err, "response too slow")
+		assert.Nil(t, resp)
+		assert.Equal(t, int64(0), circuitBreakerClient.InFlight())
+	}
+}
+
 func TestCircuitBreakerClient_CircuitBreakerOpen(t *testing.T) {
 	baseClient := &health.DefaultHTTPClient{
 		Client:  &http.Client{Timeout: 5 * time.Second},
@@ -154,3 +182,50 @@ func TestCircuitBreakerClient_SetUp(t *testing.T) {
 		circuitBreakerClient.SetUp(false)
 	})
 }
+
+func TestCircuitBreakerClient_GRPCStatusFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("payload"))
+		w.Header().Set("Grpc-Status", r.URL.Query().Get("status"))
+	}))
+	defer server.Close()
+
+	baseClient := &health.DefaultHTTPClient{
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		BaseURL: server.URL,
+		Up:      true,
+	}
+
+	circuitConfig := CircuitBreakerConfig{
+		MaxFailures:  2,
+		ResetTimeout: 60 * time.Second,
+	}
+
+	circuitBreakerClient := NewCircuitBreakerClient(baseClient, circuitConfig)
+
+	call := func(status string) error {
+		req, _ := http.NewRequest("POST", "/svc/Method?status="+status, nil)
+		req.Header.Set("Content-Type", "application/grpc")
+		resp, err := circuitBreakerClient.Do(req)
+		if err != nil {
+			return err
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil
+	}
+
+	assert.NoError(t, call("5"))
+	assert.Equal(t, StateClosed, circuitBreakerClient.circuitBreaker.GetState())
+
+	assert.NoError(t, call("14"))
+	assert.NoError(t, call("14"))
+	assert.Equal(t, StateOpen, circuitBreakerClient.circuitBreaker.GetState())
+
+	err := call("0")
+	assert.Error(t, err)
+	assert.IsType(t, &CircuitBreakerError{}, err)
+}
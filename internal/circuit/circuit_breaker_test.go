@@ -98,3 +98,46 @@ func TestCircuitBreaker_SlowResponseWithError(t *testing.T) {
 	assert.Equal(t, 1, cb.GetFailureCount())
 	assert.Equal(t, 1, cb.GetSlowCount())
 }
+
+func TestCircuitBreaker_Force(t *testing.T) {
+	cb := NewCircuitBreaker(1, 20*time.Millisecond)
+
+	cb.Force(StateOpen)
+	time.Sleep(30 * time.Millisecond)
+	assert.Error(t, cb.Execute(func() error { return nil }))
+	assert.Equal(t, StateOpen, cb.GetState())
+
+	cb.Reset()
+	assert.Equal(t, StateOpen, cb.GetState(), "a forced state survives health check recovery")
+
+	cb.Force(StateClosed)
+	for i := 0; i < 3; i++ {
+		cb.Execute(func() error { return errors.New("test error") })
+	}
+	assert.Equal(t, StateClosed, cb.GetState())
+
+	cb.Release()
+	assert.False(t, cb.IsForced())
+	cb.Execute(func() error { return errors.New("test error") })
+	assert.Equal(t, StateOpen, cb.GetState())
+}
+
+func TestCircuitBreakerCountsTransitions(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	cb.backend = "http://transitions"
+	transitions := func(from, to CircuitBreakerState) float64 {
+		return circuitTransitions.WithLabelValues(cb.backend, from.String(), to.String()).Value()
+	}
+
+	err := cb.Execute(func() error { return errors.New("test error") })
+	assert.Error(t, err)
+	err = cb.Execute(func() error { return nil })
+	assert.True(t, err.(*CircuitBreakerError).Open)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, cb.Execute(func() error { return nil }))
+
+	assert.Equal(t, float64(1), transitions(StateClosed, StateOpen))
+	assert.Equal(t, float64(1), transitions(StateOpen, StateHalfOpen))
+	assert.Equal(t, float64(1), transitions(StateHalfOpen, StateClosed))
+}
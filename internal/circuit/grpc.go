@@ -0,0 +1,107 @@
+package circuit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gRPC status codes that indicate the backend, rather than the caller, is at fault.
+var grpcFailureCodes = map[int]bool{
+	4:  true, // DEADLINE_EXCEEDED
+	8:  true, // RESOURCE_EXHAUSTED
+	13: true, // INTERNAL
+	14: true, // UNAVAILABLE
+	15: true, // DATA_LOSS
+}
+
+type GRPCStatusError struct {
+	Code int
+}
+
+func (e *GRPCStatusError) Error() string {
+	return fmt.Sprintf("grpc status %d", e.Code)
+}
+
+func isGRPCRequest(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc")
+}
+
+func isGRPCResponse(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "application/grpc")
+}
+
+func grpcStatusErr(header http.Header) (error, bool) {
+	value := header.Get("Grpc-Status")
+	if value == "" {
+		return nil, false
+	}
+
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid grpc-status %q", value), true
+	}
+	if grpcFailureCodes[code] {
+		return &GRPCStatusError{Code: code}, true
+	}
+	return nil, true
+}
+
+type grpcStatusBody struct {
+	io.ReadCloser
+	resp   *http.Response
+	record func(err error)
+	once   sync.Once
+}
+
+func (b *grpcStatusBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *grpcStatusBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *grpcStatusBody) finish() {
+	b.once.Do(func() {
+		statusErr, _ := grpcStatusErr(b.resp.Trailer)
+		b.record(statusErr)
+	})
+}
+
+func (cbc *CircuitBreakerClient) doGRPC(req *http.Request) (*http.Response, error) {
+	if err := cbc.circuitBreaker.allow(); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	resp, err := cbc.client.Do(req)
+	responseTime := time.Since(startTime)
+
+	if err != nil || !isGRPCResponse(resp) {
+		return discardOnError(resp, cbc.circuitBreaker.record(err, responseTime))
+	}
+
+	if statusErr, ok := grpcStatusErr(resp.Header); ok {
+		cbc.circuitBreaker.record(statusErr, responseTime)
+		return resp, nil
+	}
+
+	resp.Body = &grpcStatusBody{
+		ReadCloser: resp.Body,
+		resp:       resp,
+		record: func(statusErr error) {
+			cbc.circuitBreaker.record(statusErr, responseTime)
+		},
+	}
+	return resp, nil
+}
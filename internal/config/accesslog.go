@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Access log formats.
+const (
+	AccessLogJSON     = "json"
+	AccessLogCombined = "combined"
+	AccessLogTemplate = "template"
+	AccessLogOff      = "off"
+)
+
+var accessLogFormats = []string{AccessLogJSON, AccessLogCombined, AccessLogTemplate, AccessLogOff}
+
+// AccessLogFields are the placeholders an access log template may use.
+var AccessLogFields = []string{
+	"time", "request_id", "client_ip", "method", "host", "uri", "protocol", "status", "bytes",
+	"duration_ms", "route", "backend", "upstream_latency_ms", "user_agent", "referer", "error",
+}
+
+// ParseAccessLogTemplate splits a template such as
+// "{client_ip} {method} {uri} {status} {duration_ms}ms" into its literal text
+// and fields, passing each to literal or field in order. A { without a closing
+// } is literal text.
+func ParseAccessLogTemplate(template string, literal, field func(string)) error {
+	for rest := template; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		end := -1
+		if start >= 0 {
+			end = strings.IndexByte(rest[start:], '}')
+		}
+		if end < 0 {
+			literal(rest)
+			break
+		}
+		end += start
+		if start > 0 {
+			literal(rest[:start])
+		}
+		name := rest[start+1 : end]
+		if !slices.Contains(AccessLogFields, name) {
+			return fmt.Errorf("unknown access log field {%s}", name)
+		}
+		field(name)
+		rest = rest[end+1:]
+	}
+	return nil
+}
+
+// AccessLogConfig describes the line written for every request. Lines go to
+// File, or to standard output when it is empty.
+//
+// SampleRatio is the share of requests that are logged; server errors are
+// always logged. Requests for ExcludePaths are never logged; a path ending in
+// /* excludes everything below it.
+type AccessLogConfig struct {
+	Format       string
+	Template     string
+	File         string
+	SampleRatio  float64
+	ExcludePaths []string
+}
+
+func (a AccessLogConfig) Enabled() bool {
+	return a.Format != AccessLogOff
+}
+
+func accessLogFromEnv(file FileAccessLog, errs *problems) AccessLogConfig {
+	accessLog := AccessLogConfig{
+		Format:       getEnv("ACCESS_LOG_FORMAT", file.Format),
+		Template:     getEnv("ACCESS_LOG_TEMPLATE", file.Template),
+		File:         getEnv("ACCESS_LOG_FILE", file.File),
+		SampleRatio:  getEnvFloat("ACCESS_LOG_SAMPLE_RATIO", file.SampleRatio, errs),
+		ExcludePaths: file.ExcludePaths,
+	}
+	if value := getEnvRaw("ACCESS_LOG_EXCLUDE_PATHS"); value != "" {
+		accessLog.ExcludePaths = splitList(value)
+	}
+	return accessLog
+}
+
+func (a AccessLogConfig) validate(errs *problems) {
+	checkOneOf(errs, "ACCESS_LOG_FORMAT", a.Format, accessLogFormats)
+	if a.Format == AccessLogTemplate {
+		if strings.TrimSpace(a.Template) == "" {
+			errs.addf("ACCESS_LOG_TEMPLATE", "required when ACCESS_LOG_FORMAT is %s", AccessLogTemplate)
+		} else if err := ParseAccessLogTemplate(a.Template, func(string) {}, func(string) {}); err != nil {
+			errs.addf("ACCESS_LOG_TEMPLATE", "%w", err)
+		}
+	}
+	if a.SampleRatio < 0 || a.SampleRatio > 1 {
+		errs.addf("ACCESS_LOG_SAMPLE_RATIO", "must be between 0 and 1, got %g", a.SampleRatio)
+	}
+	for _, path := range a.ExcludePaths {
+		checkPath(errs, "ACCESS_LOG_EXCLUDE_PATHS", path)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLoad_AccessLog(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.AccessLog.Enabled())
+	assert.Equal(t, AccessLogConfig{Format: AccessLogJSON, SampleRatio: 1}, cfg.AccessLog)
+
+	os.Setenv("ACCESS_LOG_FORMAT", "template")
+	os.Setenv("ACCESS_LOG_TEMPLATE", "{method} {uri} {status}")
+	os.Setenv("ACCESS_LOG_FILE", "/var/log/routing-api/access.log")
+	os.Setenv("ACCESS_LOG_SAMPLE_RATIO", "0.5")
+	os.Setenv("ACCESS_LOG_EXCLUDE_PATHS", "/health, /livez,/static/*")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, AccessLogConfig{
+		Format:       AccessLogTemplate,
+		Template:     "{method} {uri} {status}",
+		File:         "/var/log/routing-api/access.log",
+		SampleRatio:  0.5,
+		ExcludePaths: []string{"/health", "/livez", "/static/*"},
+	}, cfg.AccessLog)
+
+	os.Setenv("ACCESS_LOG_FORMAT", "off")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.AccessLog.Enabled())
+
+	os.Setenv("ACCESS_LOG_FORMAT", "template")
+	os.Setenv("ACCESS_LOG_TEMPLATE", " ")
+	os.Setenv("ACCESS_LOG_SAMPLE_RATIO", "-1")
+	os.Setenv("ACCESS_LOG_EXCLUDE_PATHS", "health")
+	_, err = Load()
+	assert.ErrorContains(t, err, "ACCESS_LOG_TEMPLATE: required when ACCESS_LOG_FORMAT is template")
+	assert.ErrorContains(t, err, "ACCESS_LOG_SAMPLE_RATIO: must be between 0 and 1, got -1")
+	assert.ErrorContains(t, err, `ACCESS_LOG_EXCLUDE_PATHS: path "health" must start with /`)
+
+	os.Setenv("ACCESS_LOG_TEMPLATE", "{method} {nope}")
+	os.Setenv("ACCESS_LOG_SAMPLE_RATIO", "1")
+	os.Setenv("ACCESS_LOG_EXCLUDE_PATHS", "/health")
+	_, err = Load()
+	assert.ErrorContains(t, err, "ACCESS_LOG_TEMPLATE: unknown access log field {nope}")
+
+	os.Setenv("ACCESS_LOG_FORMAT", "apache")
+	_, err = Load()
+	assert.ErrorContains(t, err, `ACCESS_LOG_FORMAT: unknown value "apache", expected one of json, combined, template, off`)
+}
+
+func TestLoadFile_AccessLog(t *testing.T) {
+	os.Clearenv()
+	path := writeConfigFile(t, "config.yaml", `
+listeners: [{address: ":3000"}]
+circuit_breaker: {max_failures: 5}
+pools: [{name: default, backends: ["http://localhost:8080"]}]
+access_log:
+  format: combined
+  file: /var/log/routing-api/access.log
+  exclude_paths: [/health, /metrics]
+`)
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, AccessLogConfig{
+		Format:       AccessLogCombined,
+		File:         "/var/log/routing-api/access.log",
+		SampleRatio:  1,
+		ExcludePaths: []string{"/health", "/metrics"},
+	}, cfg.AccessLog)
+
+	// Every request is logged unless the access log is turned off.
+	cfg, err = LoadFile(writeConfigFile(t, "minimal.yaml", `
+listeners: [{address: ":3000"}]
+circuit_breaker: {max_failures: 5}
+pools: [{name: default, backends: ["http://localhost:8080"]}]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, AccessLogConfig{Format: AccessLogJSON, SampleRatio: 1}, cfg.AccessLog)
+}
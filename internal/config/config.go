@@ -4,20 +4,33 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"routing-api/internal/logger"
 )
 
 type Config struct {
-	Port            string
-	Environment     string
-	LogLevel        string
+	Port        string
+	Listeners   []ListenerConfig
+	Environment string
+	LogLevel    string
+	LogFormat   string
+	// LogLevels are levels for single components, which otherwise follow
+	// LogLevel.
+	LogLevels       map[string]string
 	ApplicationAPIs []string
 	BalancerType    string
+	BackendProtocol string
+
+	TLSCertFile string
+	TLSKeyFile  string
 
 	HealthCheckInterval time.Duration
+	HealthCheckPath     string
 
 	MaxFailures    int
 	CircuitTimeout time.Duration
@@ -28,61 +41,184 @@ type Config struct {
 	RequestTimeout  time.Duration
 	ConnectTimeout  time.Duration
 	ResponseTimeout time.Duration
+	FlushInterval   time.Duration
+
+	UpgradeIdleTimeout time.Duration
+	UpgradeMaxLifetime time.Duration
+
+	ShutdownDelay time.Duration
+
+	Pools       []PoolConfig
+	Routes      []RouteConfig
+	DefaultPool string
+
+	VirtualHosts        []VirtualHostConfig
+	UnmatchedHostStatus int
+
+	HTTPSRedirect bool
+
+	// AdminAddress is where the admin API listens, or empty when it is disabled.
+	AdminAddress string
+	AdminToken   string
+
+	// MetricsAddress is where /metrics is served, or empty when it is disabled.
+	MetricsAddress string
+
+	Tracing   TracingConfig
+	AccessLog AccessLogConfig
+}
+
+type ListenerConfig struct {
+	Address     string
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// Overrides are settings given on the command line. They take precedence over
+// both the environment and the config file.
+type Overrides struct {
+	// ListenAddress replaces the address of the first listener.
+	ListenAddress string
+	LogLevel      string
 }
 
 func Load() (*Config, error) {
+	return LoadFile("")
+}
+
+// LoadFile loads the configuration from a YAML or JSON file, if path is not empty,
+// with environment variables taking precedence over values from the file.
+func LoadFile(path string) (*Config, error) {
+	return LoadFileWithOverrides(path, Overrides{})
+}
+
+func LoadFileWithOverrides(path string, overrides Overrides) (*Config, error) {
 	loadEnvFile()
-	config, err := loadConfig()
+	file, err := readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	config, err := loadConfig(file, overrides)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 	return config, nil
 }
 
-func loadConfig() (*Config, error) {
-	port := getEnvRaw("PORT")
-	maxFailures, err := getEnvIntRaw("MAX_FAILURES")
-	if err != nil {
-		return nil, fmt.Errorf("invalid MAX_FAILURES: %w", err)
+func loadConfig(file *File, overrides Overrides) (*Config, error) {
+	var errs problems
+	listeners := []FileListener{{}}
+	if len(file.Listeners) > 0 {
+		listeners = append([]FileListener(nil), file.Listeners...)
+	}
+	_, filePort, _ := net.SplitHostPort(listeners[0].Address)
+	port := getEnv("PORT", filePort)
+	if overrides.ListenAddress != "" {
+		listeners[0].Address = overrides.ListenAddress
+		var err error
+		if _, port, err = net.SplitHostPort(overrides.ListenAddress); err != nil {
+			errs.addf("-listen", "invalid address %q: %w", overrides.ListenAddress, unwrapAddrError(err))
+		}
 	}
 
 	config := &Config{
 		Port:            port,
-		Environment:     getEnv("ENVIRONMENT", "development"),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		Environment:     getEnv("ENVIRONMENT", file.Environment),
+		LogLevel:        getEnv("LOG_LEVEL", file.LogLevel),
+		LogFormat:       getEnv("LOG_FORMAT", file.LogFormat),
+		LogLevels:       getLogLevels(file.LogLevels, &errs),
 		ApplicationAPIs: getApplicationAPIs(),
-		BalancerType:    getEnv("BALANCER_TYPE", "round-robin"),
+		BalancerType:    getEnv("BALANCER_TYPE", file.BalancerType),
+		BackendProtocol: getEnv("BACKEND_PROTOCOL", file.BackendProtocol),
+
+		TLSCertFile: getEnv("TLS_CERT_FILE", listeners[0].TLS.CertFile),
+		TLSKeyFile:  getEnv("TLS_KEY_FILE", listeners[0].TLS.KeyFile),
+
+		HealthCheckInterval: getEnvDuration("HEALTH_CHECK_INTERVAL", file.HealthCheck.Interval.String(), &errs),
+		HealthCheckPath:     getEnv("HEALTH_CHECK_PATH", file.HealthCheck.Path),
+
+		MaxFailures:    getEnvInt("MAX_FAILURES", file.CircuitBreaker.MaxFailures, &errs),
+		CircuitTimeout: getEnvDuration("CIRCUIT_TIMEOUT", "30s", &errs),
+		ResetTimeout:   getEnvDuration("RESET_TIMEOUT", file.CircuitBreaker.ResetTimeout.String(), &errs),
+		SlowThreshold:  getEnvDuration("SLOW_THRESHOLD", file.CircuitBreaker.SlowThreshold.String(), &errs),
+		MaxSlowCount:   getEnvInt("MAX_SLOW_COUNT", file.CircuitBreaker.MaxSlowCount, &errs),
+
+		RequestTimeout:  getEnvDuration("REQUEST_TIMEOUT", file.Timeouts.Request.String(), &errs),
+		ConnectTimeout:  getEnvDuration("CONNECT_TIMEOUT", file.Timeouts.Connect.String(), &errs),
+		ResponseTimeout: getEnvDuration("RESPONSE_TIMEOUT", file.Timeouts.Response.String(), &errs),
+		FlushInterval:   getEnvDuration("FLUSH_INTERVAL", file.Timeouts.FlushInterval.String(), &errs),
+
+		UpgradeIdleTimeout: getEnvDuration("UPGRADE_IDLE_TIMEOUT", file.Timeouts.UpgradeIdle.String(), &errs),
+		UpgradeMaxLifetime: getEnvDuration("UPGRADE_MAX_LIFETIME", file.Timeouts.UpgradeMaxLifetime.String(), &errs),
 
-		HealthCheckInterval: getEnvDuration("HEALTH_CHECK_INTERVAL", "5s"),
+		ShutdownDelay: getEnvDuration("SHUTDOWN_DELAY", file.Timeouts.ShutdownDelay.String(), &errs),
 
-		MaxFailures:    maxFailures,
-		CircuitTimeout: getEnvDuration("CIRCUIT_TIMEOUT", "30s"),
-		ResetTimeout:   getEnvDuration("RESET_TIMEOUT", "60s"),
-		SlowThreshold:  getEnvDuration("SLOW_THRESHOLD", "5s"),
-		MaxSlowCount:   getEnvInt("MAX_SLOW_COUNT", 3),
+		Routes:      getRoutes(file, &errs),
+		DefaultPool: getEnv("DEFAULT_POOL", file.DefaultPool),
 
-		RequestTimeout:  getEnvDuration("REQUEST_TIMEOUT", "30s"),
-		ConnectTimeout:  getEnvDuration("CONNECT_TIMEOUT", "5s"),
-		ResponseTimeout: getEnvDuration("RESPONSE_TIMEOUT", "25s"),
+		UnmatchedHostStatus: getEnvInt("UNMATCHED_HOST_STATUS", file.UnmatchedHostStatus, &errs),
+
+		HTTPSRedirect: getEnvBool("HTTPS_REDIRECT", file.HTTPSRedirect, &errs),
+
+		AdminAddress: getEnv("ADMIN_ADDRESS", file.Admin.Address),
+		AdminToken:   getEnv("ADMIN_TOKEN", file.Admin.Token),
+
+		MetricsAddress: getEnv("METRICS_ADDRESS", file.Metrics.Address),
+
+		Tracing:   tracingFromEnv(file.Tracing, &errs),
+		AccessLog: accessLogFromEnv(file.AccessLog, &errs),
+	}
+	if config.AdminAddress == "off" {
+		config.AdminAddress = ""
+	}
+	if config.MetricsAddress == "off" {
+		config.MetricsAddress = ""
+	}
+	setString(&config.LogLevel, overrides.LogLevel)
+	// LOG_LEVEL=development is the older way of asking for debug logs in the
+	// console format.
+	if config.LogLevel == "development" {
+		config.LogLevel = "debug"
+		config.LogFormat = logger.FormatConsole
 	}
+	config.Listeners = getListeners(config, listeners)
+	config.VirtualHosts = getVirtualHosts(config.DefaultPool, file)
+	config.Pools = getPools(config.defaultPoolConfig(), file, &errs)
+	if len(config.ApplicationAPIs) == 0 {
+		if pool, ok := config.Pool(config.DefaultPool); ok {
+			config.ApplicationAPIs = pool.APIs
+		}
+	}
+	checkPoolEnv(config.Pools, &errs)
 
-	if err := config.Validate(); err != nil {
+	config.validate(&errs)
+	if err := errs.err(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 
 	return config, nil
 }
 
-func (c *Config) Validate() error {
-	if c.Port == "" {
-		return errors.New("port cannot be empty")
+// getListeners applies PORT and TLS_* to the first listener of the file.
+func getListeners(config *Config, fileListeners []FileListener) []ListenerConfig {
+	if config.Port == "" {
+		return nil
 	}
 
-	if len(c.ApplicationAPIs) == 0 {
-		return errors.New("at least one application API must be configured")
+	host, _, _ := net.SplitHostPort(fileListeners[0].Address)
+	listeners := []ListenerConfig{{
+		Address:     net.JoinHostPort(host, config.Port),
+		TLSCertFile: config.TLSCertFile,
+		TLSKeyFile:  config.TLSKeyFile,
+	}}
+	for _, listener := range fileListeners[1:] {
+		listeners = append(listeners, ListenerConfig{
+			Address:     listener.Address,
+			TLSCertFile: listener.TLS.CertFile,
+			TLSKeyFile:  listener.TLS.KeyFile,
+		})
 	}
-
-	return nil
+	return listeners
 }
 
 func getEnv(key, defaultValue string) string {
@@ -97,18 +233,11 @@ func getEnvRaw(key string) string {
 }
 
 func getApplicationAPIs() []string {
-	var apis []string
-
 	if apisEnv := os.Getenv("APPLICATION_APIS"); apisEnv != "" {
-		parts := strings.Split(apisEnv, ",")
-		for _, part := range parts {
-			if trimmed := strings.TrimSpace(part); trimmed != "" {
-				apis = append(apis, trimmed)
-			}
-		}
-		return apis
+		return splitList(apisEnv)
 	}
 
+	var apis []string
 	for i := 1; i <= 10; i++ {
 		if api := os.Getenv("API_" + strconv.Itoa(i)); api != "" {
 			apis = append(apis, api)
@@ -118,27 +247,54 @@ func getApplicationAPIs() []string {
 	return apis
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			return intVal
-		}
+// getEnvInt, getEnvBool and getEnvDuration record a problem and return the
+// default when the variable is set to something that does not parse.
+func getEnvInt(key string, defaultValue int, errs *problems) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
 	}
-	return defaultValue
+	intVal, err := strconv.Atoi(value)
+	if err != nil {
+		errs.invalid(key, value, err)
+		return defaultValue
+	}
+	return intVal
 }
 
-func getEnvIntRaw(key string) (int, error) {
-	if value := os.Getenv(key); value != "" {
-		return strconv.Atoi(value)
+func getEnvFloat(key string, defaultValue float64, errs *problems) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	floatVal, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		errs.invalid(key, value, err)
+		return defaultValue
+	}
+	return floatVal
+}
+
+func getEnvBool(key string, defaultValue bool, errs *problems) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	boolVal, err := strconv.ParseBool(value)
+	if err != nil {
+		errs.invalid(key, value, err)
+		return defaultValue
 	}
-	return 0, errors.New("environment variable not set")
+	return boolVal
 }
 
-func getEnvDuration(key string, defaultValue string) time.Duration {
+func getEnvDuration(key string, defaultValue string, errs *problems) time.Duration {
 	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
+		duration, err := time.ParseDuration(value)
+		if err == nil {
 			return duration
 		}
+		errs.invalid(key, value, errors.New("not a duration, use a value like 30s or 500ms"))
 	}
 	if duration, err := time.ParseDuration(defaultValue); err == nil {
 		return duration
@@ -95,3 +95,291 @@ func TestConfigValidationErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigLoad_PoolsAndRoutes(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "orders,user-service")
+	os.Setenv("POOL_ORDERS_APIS", "http://localhost:9090,http://localhost:9091")
+	os.Setenv("POOL_ORDERS_BALANCER_TYPE", "least-connections")
+	os.Setenv("POOL_USER_SERVICE_APIS", "http://localhost:9190")
+	os.Setenv("POOL_USER_SERVICE_REQUIRED", "false")
+	os.Setenv("POOL_USER_SERVICE_MAX_FAILURES", "2")
+	os.Setenv("ROUTES", "/orders/*=orders, /users/{id}=user-service")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+
+	assert.Len(t, cfg.Pools, 3)
+	assert.Equal(t, DefaultPoolName, cfg.DefaultPool)
+
+	orders, ok := cfg.Pool("orders")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"http://localhost:9090", "http://localhost:9091"}, orders.APIs)
+	assert.Equal(t, "least-connections", orders.BalancerType)
+	assert.True(t, orders.Required)
+	assert.Equal(t, 5, orders.MaxFailures)
+
+	users, ok := cfg.Pool("user-service")
+	assert.True(t, ok)
+	assert.False(t, users.Required)
+	assert.Equal(t, 2, users.MaxFailures)
+
+	assert.Equal(t, []RouteConfig{
+		{Path: "/orders/*", Pool: "orders"},
+		{Path: "/users/{id}", Pool: "user-service"},
+	}, cfg.Routes)
+}
+
+func TestConfigLoad_RouteValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+	}{
+		{
+			name:    "route to unknown pool",
+			envVars: map[string]string{"ROUTES": "/orders/*=orders"},
+		},
+		{
+			name:    "wildcard in the middle of a path",
+			envVars: map[string]string{"ROUTES": "/orders/*/items=default"},
+		},
+		{
+			name:    "pool without APIs",
+			envVars: map[string]string{"POOLS": "orders"},
+		},
+		{
+			name:    "unknown default pool",
+			envVars: map[string]string{"DEFAULT_POOL": "orders"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("PORT", "3000")
+			os.Setenv("MAX_FAILURES", "5")
+			os.Setenv("API_1", "http://localhost:8080")
+			for key, value := range tt.envVars {
+				os.Setenv(key, value)
+			}
+
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfigLoad_VirtualHosts(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "tenants")
+	os.Setenv("POOL_TENANTS_APIS", "http://localhost:9090")
+	os.Setenv("HOSTS", "api.example.com,*.api.example.com=tenants")
+	os.Setenv("ROUTES", "API.example.com/orders/*=tenants,/shared/*=default")
+	os.Setenv("UNMATCHED_HOST_STATUS", "421")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []VirtualHostConfig{
+		{Host: "api.example.com", DefaultPool: DefaultPoolName},
+		{Host: "*.api.example.com", DefaultPool: "tenants"},
+	}, cfg.VirtualHosts)
+	assert.Equal(t, []RouteConfig{
+		{Host: "api.example.com", Path: "/orders/*", Pool: "tenants"},
+		{Path: "/shared/*", Pool: DefaultPoolName},
+	}, cfg.Routes)
+	assert.Equal(t, 421, cfg.UnmatchedHostStatus)
+
+	os.Setenv("UNMATCHED_HOST_STATUS", "400")
+	_, err = Load()
+	assert.Error(t, err)
+
+	os.Setenv("UNMATCHED_HOST_STATUS", "404")
+	os.Setenv("ROUTES", "other.example.com/orders/*=tenants")
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestParseRoute(t *testing.T) {
+	route, err := parseRoute("api.example.com/v2/*=v2;method:get|POST;header:X-Api-Version=2;header:X-Debug;query:tag~^beta;cidr:10.0.0.0/8|192.168.0.0/16")
+	assert.NoError(t, err)
+	assert.Equal(t, RouteConfig{
+		Host:    "api.example.com",
+		Path:    "/v2/*",
+		Pool:    "v2",
+		Methods: []string{"GET", "POST"},
+		Headers: []ValueMatch{
+			{Name: "X-Api-Version", Exact: "2"},
+			{Name: "X-Debug"},
+		},
+		Queries:     []ValueMatch{{Name: "tag", Regex: "^beta"}},
+		SourceCIDRs: []string{"10.0.0.0/8", "192.168.0.0/16"},
+	}, route)
+	assert.Equal(t, 5, route.Conditions())
+
+	_, err = parseRoute("/v2/*=v2;cookie:session")
+	assert.Error(t, err)
+}
+
+func TestConfigLoad_InvalidRouteConditions(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes string
+	}{
+		{name: "invalid regex", routes: "/*=default;header:X-Api-Version~(2"},
+		{name: "invalid cidr", routes: "/*=default;cidr:10.0.0.0/33"},
+		{name: "unknown condition", routes: "/*=default;cookie:session"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("PORT", "3000")
+			os.Setenv("MAX_FAILURES", "5")
+			os.Setenv("API_1", "http://localhost:8080")
+			os.Setenv("ROUTES", tt.routes)
+
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfigLoad_WeightedSplits(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "canary")
+	os.Setenv("POOL_CANARY_APIS", "http://localhost:9090")
+	os.Setenv("ROUTES", "/api/*=default:95|canary:5;name:api;sticky-header:X-User-ID;sticky-cookie:user;pool-header")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []RouteConfig{{
+		Name:         "api",
+		Path:         "/api/*",
+		Splits:       []WeightedPool{{Pool: "default", Weight: 95}, {Pool: "canary", Weight: 5}},
+		StickyHeader: "X-User-ID",
+		StickyCookie: "user",
+		PoolHeader:   true,
+	}}, cfg.Routes)
+
+	invalid := []string{
+		"/api/*=default:95|missing:5",
+		"/api/*=default:95|canary:-5",
+		"/api/*=default:0|canary:0",
+		"/api/*=default:x|canary:5",
+		"/api/*=default:50|default:50",
+		"/a/*=default:1|canary:1;name:x,/b/*=default:1|canary:1;name:x",
+	}
+	for _, routes := range invalid {
+		os.Setenv("ROUTES", routes)
+		_, err = Load()
+		assert.Error(t, err, routes)
+	}
+}
+
+func TestConfigLoad_Mirror(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "shadow")
+	os.Setenv("POOL_SHADOW_APIS", "http://localhost:9090")
+	os.Setenv("ROUTES", "/orders/*=default;mirror:shadow:12.5,/users/*=default;mirror:shadow")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "shadow", cfg.Routes[0].MirrorPool)
+	assert.Equal(t, 12.5, cfg.Routes[0].MirrorPercent)
+	assert.Equal(t, 100.0, cfg.Routes[1].MirrorPercent)
+
+	for _, routes := range []string{"/*=default;mirror:missing", "/*=default;mirror:shadow:150", "/*=default;mirror:shadow:x"} {
+		os.Setenv("ROUTES", routes)
+		_, err = Load()
+		assert.Error(t, err, routes)
+	}
+}
+
+func TestParseRoute_Rewrite(t *testing.T) {
+	route, err := parseRoute(`/api/*=default;strip-prefix;set-header:X-Client={client_ip};add-header:X-Tag=a=b;remove-header:Cookie;set-response-header:Server=edge;remove-response-header:X-Powered-By`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api", route.StripPrefix)
+	assert.Equal(t, HeaderRules{
+		Set:    []HeaderValue{{Name: "X-Client", Value: "{client_ip}"}},
+		Add:    []HeaderValue{{Name: "X-Tag", Value: "a=b"}},
+		Remove: []string{"Cookie"},
+	}, route.RequestHeaders)
+	assert.Equal(t, HeaderRules{
+		Set:    []HeaderValue{{Name: "Server", Value: "edge"}},
+		Remove: []string{"X-Powered-By"},
+	}, route.ResponseHeaders)
+
+	route, err = parseRoute(`/users/{id}/*=default;prefix-rewrite:/v2`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users", route.StripPrefix)
+	assert.Equal(t, "/v2", route.PrefixRewrite)
+
+	route, err = parseRoute(`/users/*=default;regex-rewrite:^/users/(\d+)$=/v2/users/$1/full=true`)
+	assert.NoError(t, err)
+	assert.Equal(t, `^/users/(\d+)$`, route.RegexRewrite)
+	assert.Equal(t, "/v2/users/$1/full=true", route.RegexReplacement)
+}
+
+func TestConfigLoad_InvalidRewrite(t *testing.T) {
+	for _, routes := range []string{
+		"/*=default;regex-rewrite:(=/x",
+		"/*=default;strip-prefix:api",
+		"/*=default;prefix-rewrite:v2",
+		"/*=default;set-header:=value",
+	} {
+		os.Clearenv()
+		os.Setenv("PORT", "3000")
+		os.Setenv("MAX_FAILURES", "5")
+		os.Setenv("API_1", "http://localhost:8080")
+		os.Setenv("ROUTES", routes)
+
+		_, err := Load()
+		assert.Error(t, err, routes)
+	}
+}
+
+func TestConfigLoad_RouteActions(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("ROUTES", "/old/*=redirect;location:/new{rest},/v1/*=respond;status:410;body:gone,/*=default;https-redirect")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []RouteConfig{
+		{Path: "/old/*", Action: ActionRedirect, Status: 302, Location: "/new{rest}"},
+		{Path: "/v1/*", Action: ActionRespond, Status: 410, Body: "gone"},
+		{Path: "/*", Pool: DefaultPoolName, HTTPSRedirect: true},
+	}, cfg.Routes)
+
+	for _, routes := range []string{
+		"/old/*=redirect",
+		"/old/*=redirect;location:/new;status:200",
+		"/v1/*=respond;status:100",
+		"/v1/*=respond;body-file:/does/not/exist",
+		"/v1/*=respond;body:a;body-file:/etc/hostname",
+		"/v1/*=respond;mirror:default",
+	} {
+		os.Setenv("ROUTES", routes)
+		_, err = Load()
+		assert.Error(t, err, routes)
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Discovery sources, as returned by DiscoveryConfig.Source.
+const (
+	DiscoveryFile   = "file"
+	DiscoveryDNS    = "dns"
+	DiscoveryConsul = "consul"
+)
+
+const (
+	DNSRecordA   = "a"
+	DNSRecordSRV = "srv"
+)
+
+var (
+	dnsRecords       = []string{DNSRecordA, DNSRecordSRV}
+	discoverySchemes = []string{"http", "https"}
+)
+
+const (
+	defaultDiscoveryInterval = 5 * time.Second
+	defaultMinRefresh        = 5 * time.Second
+	defaultMaxRefresh        = time.Minute
+	defaultConsulWait        = 5 * time.Minute
+	// maxConsulWait is the longest blocking query Consul accepts.
+	maxConsulWait = 10 * time.Minute
+)
+
+// DiscoveryConfig makes a pool take its backends from a discovery source instead
+// of a fixed list.
+//
+// File is a YAML or JSON list of backends that is watched for changes, falling
+// back to polling every Interval where the file cannot be watched.
+//
+// DNSName is resolved to backends through A and AAAA records, combined with Port,
+// or through SRV records. Lookups are repeated when the records expire, but no
+// sooner than MinRefresh and no later than MaxRefresh.
+//
+// ConsulAddress is the HTTP API of a Consul agent, or anything serving the same
+// health API, which is asked for the passing instances of ConsulService with
+// blocking queries that wait up to ConsulWait for a change. Failed queries are
+// retried after Interval.
+type DiscoveryConfig struct {
+	File     string
+	Interval time.Duration
+
+	DNSName    string
+	DNSRecord  string
+	DNSServer  string
+	Port       int
+	Scheme     string
+	MinRefresh time.Duration
+	MaxRefresh time.Duration
+
+	ConsulAddress    string
+	ConsulService    string
+	ConsulTag        string
+	ConsulDatacenter string
+	ConsulToken      string
+	ConsulWait       time.Duration
+}
+
+func (d DiscoveryConfig) Enabled() bool {
+	return d.Source() != ""
+}
+
+// Source names where the backends come from, or is empty for a fixed list of
+// backends.
+func (d DiscoveryConfig) Source() string {
+	switch {
+	case d.File != "":
+		return DiscoveryFile
+	case d.DNSName != "":
+		return DiscoveryDNS
+	case d.ConsulAddress != "":
+		return DiscoveryConsul
+	}
+	return ""
+}
+
+func discoveryFromEnv(discovery DiscoveryConfig, prefix string, errs *problems) DiscoveryConfig {
+	discovery.File = getEnv(prefix+"DISCOVERY_FILE", discovery.File)
+	discovery.Interval = getEnvDuration(prefix+"DISCOVERY_INTERVAL", discovery.Interval.String(), errs)
+	discovery.DNSName = getEnv(prefix+"DISCOVERY_DNS", discovery.DNSName)
+	discovery.DNSRecord = getEnv(prefix+"DISCOVERY_DNS_RECORD", discovery.DNSRecord)
+	discovery.DNSServer = getEnv(prefix+"DISCOVERY_DNS_SERVER", discovery.DNSServer)
+	discovery.Port = getEnvInt(prefix+"DISCOVERY_PORT", discovery.Port, errs)
+	discovery.Scheme = getEnv(prefix+"DISCOVERY_SCHEME", discovery.Scheme)
+	discovery.MinRefresh = getEnvDuration(prefix+"DISCOVERY_MIN_REFRESH", discovery.MinRefresh.String(), errs)
+	discovery.MaxRefresh = getEnvDuration(prefix+"DISCOVERY_MAX_REFRESH", discovery.MaxRefresh.String(), errs)
+	discovery.ConsulAddress = getEnv(prefix+"DISCOVERY_CONSUL", discovery.ConsulAddress)
+	discovery.ConsulService = getEnv(prefix+"DISCOVERY_CONSUL_SERVICE", discovery.ConsulService)
+	discovery.ConsulTag = getEnv(prefix+"DISCOVERY_CONSUL_TAG", discovery.ConsulTag)
+	discovery.ConsulDatacenter = getEnv(prefix+"DISCOVERY_CONSUL_DATACENTER", discovery.ConsulDatacenter)
+	discovery.ConsulToken = getEnv(prefix+"DISCOVERY_CONSUL_TOKEN", discovery.ConsulToken)
+	discovery.ConsulWait = getEnvDuration(prefix+"DISCOVERY_CONSUL_WAIT", discovery.ConsulWait.String(), errs)
+	return discovery.withDefaults()
+}
+
+func (d DiscoveryConfig) withDefaults() DiscoveryConfig {
+	if (d.File != "" || d.ConsulAddress != "") && d.Interval == 0 {
+		d.Interval = defaultDiscoveryInterval
+	}
+	if (d.DNSName != "" || d.ConsulAddress != "") && d.Scheme == "" {
+		d.Scheme = "http"
+	}
+	if d.ConsulAddress != "" && d.ConsulWait == 0 {
+		d.ConsulWait = defaultConsulWait
+	}
+	if d.DNSName != "" {
+		if d.DNSRecord == "" {
+			// Service names such as _http._tcp.example.com are looked up as SRV.
+			d.DNSRecord = DNSRecordA
+			if strings.HasPrefix(d.DNSName, "_") {
+				d.DNSRecord = DNSRecordSRV
+			}
+		}
+		if d.MinRefresh == 0 {
+			d.MinRefresh = defaultMinRefresh
+		}
+		if d.MaxRefresh == 0 {
+			d.MaxRefresh = defaultMaxRefresh
+		}
+	}
+	return d
+}
+
+func (d DiscoveryConfig) validate(errs *problems, prefix string) {
+	sources := 0
+	for _, value := range []string{d.File, d.DNSName, d.ConsulAddress} {
+		if value != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		errs.addf(prefix+"DISCOVERY_FILE", "use only one of DISCOVERY_FILE, DISCOVERY_DNS and DISCOVERY_CONSUL")
+	}
+	if d.File != "" || d.ConsulAddress != "" {
+		checkPositive(errs, prefix+"DISCOVERY_INTERVAL", d.Interval)
+	}
+	if d.DNSName != "" || d.ConsulAddress != "" {
+		checkOneOf(errs, prefix+"DISCOVERY_SCHEME", d.Scheme, discoverySchemes)
+	}
+	if d.ConsulAddress != "" {
+		d.validateConsul(errs, prefix)
+	}
+	if d.DNSName == "" {
+		return
+	}
+
+	checkOneOf(errs, prefix+"DISCOVERY_DNS_RECORD", d.DNSRecord, dnsRecords)
+	if d.DNSRecord == DNSRecordA && (d.Port < 1 || d.Port > 65535) {
+		errs.addf(prefix+"DISCOVERY_PORT", "A and AAAA records need a port between 1 and 65535, got %d", d.Port)
+	}
+	if d.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(d.DNSServer); err != nil {
+			errs.addf(prefix+"DISCOVERY_DNS_SERVER", "invalid address %q: %w", d.DNSServer, unwrapAddrError(err))
+		}
+	}
+	checkPositive(errs, prefix+"DISCOVERY_MIN_REFRESH", d.MinRefresh)
+	if d.MaxRefresh < d.MinRefresh {
+		errs.addf(prefix+"DISCOVERY_MAX_REFRESH", "%s must not be shorter than DISCOVERY_MIN_REFRESH (%s)", d.MaxRefresh, d.MinRefresh)
+	}
+}
+
+func (d DiscoveryConfig) validateConsul(errs *problems, prefix string) {
+	if u, err := url.Parse(d.ConsulAddress); err != nil {
+		errs.addf(prefix+"DISCOVERY_CONSUL", "invalid URL %q: %w", d.ConsulAddress, unwrapURLError(err))
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.addf(prefix+"DISCOVERY_CONSUL", "%q needs an http or https scheme and a host", d.ConsulAddress)
+	}
+	if d.ConsulService == "" {
+		errs.addf(prefix+"DISCOVERY_CONSUL_SERVICE", "required when DISCOVERY_CONSUL is set")
+	}
+	checkPositive(errs, prefix+"DISCOVERY_CONSUL_WAIT", d.ConsulWait)
+	if d.ConsulWait > maxConsulWait {
+		errs.addf(prefix+"DISCOVERY_CONSUL_WAIT", "cannot be longer than %s, got %s", maxConsulWait, d.ConsulWait)
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLoad_DiscoveryFile(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "orders")
+	os.Setenv("POOL_ORDERS_DISCOVERY_FILE", "/etc/routing-api/orders.yaml")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	orders, ok := cfg.Pool("orders")
+	require.True(t, ok)
+	assert.Empty(t, orders.APIs)
+	assert.Equal(t, DiscoveryConfig{File: "/etc/routing-api/orders.yaml", Interval: 5 * time.Second}, orders.Discovery)
+
+	os.Setenv("POOL_ORDERS_APIS", "http://localhost:9090")
+	os.Setenv("POOL_ORDERS_DISCOVERY_INTERVAL", "-1s")
+	_, err = Load()
+	assert.ErrorContains(t, err, `pool "orders": cannot list backends and use discovery`)
+	assert.ErrorContains(t, err, "POOL_ORDERS_DISCOVERY_INTERVAL: must be greater than zero")
+}
+
+func TestConfigLoad_DiscoveryDNS(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "orders")
+	os.Setenv("POOL_ORDERS_DISCOVERY_DNS", "_orders._tcp.service.internal")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	orders, ok := cfg.Pool("orders")
+	require.True(t, ok)
+	assert.Equal(t, DiscoveryConfig{
+		DNSName:    "_orders._tcp.service.internal",
+		DNSRecord:  DNSRecordSRV,
+		Scheme:     "http",
+		MinRefresh: 5 * time.Second,
+		MaxRefresh: time.Minute,
+	}, orders.Discovery)
+
+	os.Setenv("POOL_ORDERS_DISCOVERY_DNS", "orders.service.internal")
+	os.Setenv("POOL_ORDERS_DISCOVERY_DNS_SERVER", "10.0.0.53")
+	os.Setenv("POOL_ORDERS_DISCOVERY_SCHEME", "grpc")
+	os.Setenv("POOL_ORDERS_DISCOVERY_MIN_REFRESH", "30s")
+	os.Setenv("POOL_ORDERS_DISCOVERY_MAX_REFRESH", "10s")
+	_, err = Load()
+	assert.ErrorContains(t, err, "POOL_ORDERS_DISCOVERY_PORT: A and AAAA records need a port between 1 and 65535, got 0")
+	assert.ErrorContains(t, err, `POOL_ORDERS_DISCOVERY_DNS_SERVER: invalid address "10.0.0.53": missing port in address`)
+	assert.ErrorContains(t, err, `POOL_ORDERS_DISCOVERY_SCHEME: unknown value "grpc"`)
+	assert.ErrorContains(t, err, "POOL_ORDERS_DISCOVERY_MAX_REFRESH: 10s must not be shorter than DISCOVERY_MIN_REFRESH (30s)")
+
+	os.Setenv("POOL_ORDERS_DISCOVERY_FILE", "/etc/routing-api/orders.yaml")
+	_, err = Load()
+	assert.ErrorContains(t, err, "POOL_ORDERS_DISCOVERY_FILE: use only one of DISCOVERY_FILE, DISCOVERY_DNS and DISCOVERY_CONSUL")
+}
+
+func TestConfigLoad_DiscoveryConsul(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+	os.Setenv("POOLS", "orders")
+	os.Setenv("POOL_ORDERS_DISCOVERY_CONSUL", "http://127.0.0.1:8500")
+	os.Setenv("POOL_ORDERS_DISCOVERY_CONSUL_SERVICE", "orders")
+	os.Setenv("POOL_ORDERS_DISCOVERY_CONSUL_TOKEN", "s3cr3t")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	orders, ok := cfg.Pool("orders")
+	require.True(t, ok)
+	assert.Equal(t, DiscoveryConsul, orders.Discovery.Source())
+	assert.Equal(t, DiscoveryConfig{
+		Interval:      5 * time.Second,
+		Scheme:        "http",
+		ConsulAddress: "http://127.0.0.1:8500",
+		ConsulService: "orders",
+		ConsulToken:   "s3cr3t",
+		ConsulWait:    5 * time.Minute,
+	}, orders.Discovery)
+
+	redactedOrders, _ := cfg.Redacted().Pool("orders")
+	assert.Equal(t, "REDACTED", redactedOrders.Discovery.ConsulToken)
+
+	os.Setenv("POOL_ORDERS_DISCOVERY_CONSUL", "127.0.0.1:8500")
+	os.Setenv("POOL_ORDERS_DISCOVERY_CONSUL_SERVICE", "")
+	os.Setenv("POOL_ORDERS_DISCOVERY_CONSUL_WAIT", "15m")
+	_, err = Load()
+	assert.ErrorContains(t, err, `POOL_ORDERS_DISCOVERY_CONSUL: invalid URL "127.0.0.1:8500": first path segment in URL cannot contain colon`)
+	assert.ErrorContains(t, err, "POOL_ORDERS_DISCOVERY_CONSUL_SERVICE: required when DISCOVERY_CONSUL is set")
+	assert.ErrorContains(t, err, "POOL_ORDERS_DISCOVERY_CONSUL_WAIT: cannot be longer than 10m0s, got 15m0s")
+}
@@ -0,0 +1,612 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"routing-api/internal/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of a YAML configuration file. JSON files are accepted too,
+// since JSON is valid YAML. Keys left out of the file keep their defaults, and
+// environment variables override values from the file.
+type File struct {
+	Environment     string            `yaml:"environment"`
+	LogLevel        string            `yaml:"log_level"`
+	LogFormat       string            `yaml:"log_format"`
+	LogLevels       map[string]string `yaml:"log_levels,omitempty"`
+	Listeners       []FileListener    `yaml:"listeners"`
+	BalancerType    string            `yaml:"balancer_type"`
+	BackendProtocol string            `yaml:"backend_protocol"`
+
+	HealthCheck    FileHealthCheck    `yaml:"health_check"`
+	CircuitBreaker FileCircuitBreaker `yaml:"circuit_breaker"`
+	Timeouts       FileTimeouts       `yaml:"timeouts"`
+
+	DefaultPool         string      `yaml:"default_pool"`
+	UnmatchedHostStatus int         `yaml:"unmatched_host_status"`
+	HTTPSRedirect       bool        `yaml:"https_redirect"`
+	Hosts               []FileHost  `yaml:"hosts,omitempty"`
+	Pools               []FilePool  `yaml:"pools,omitempty"`
+	Routes              []FileRoute `yaml:"routes,omitempty"`
+
+	Admin     FileAdmin     `yaml:"admin"`
+	Metrics   FileMetrics   `yaml:"metrics"`
+	Tracing   FileTracing   `yaml:"tracing"`
+	AccessLog FileAccessLog `yaml:"access_log"`
+}
+
+type FileListener struct {
+	Address string  `yaml:"address"`
+	TLS     FileTLS `yaml:"tls,omitempty"`
+}
+
+type FileTLS struct {
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+}
+
+type FileHealthCheck struct {
+	Interval time.Duration `yaml:"interval"`
+	Path     string        `yaml:"path"`
+}
+
+type FileCircuitBreaker struct {
+	MaxFailures   int           `yaml:"max_failures"`
+	ResetTimeout  time.Duration `yaml:"reset_timeout"`
+	SlowThreshold time.Duration `yaml:"slow_threshold"`
+	MaxSlowCount  int           `yaml:"max_slow_count"`
+}
+
+type FileTimeouts struct {
+	Request            time.Duration `yaml:"request"`
+	Connect            time.Duration `yaml:"connect"`
+	Response           time.Duration `yaml:"response"`
+	FlushInterval      time.Duration `yaml:"flush_interval"`
+	UpgradeIdle        time.Duration `yaml:"upgrade_idle"`
+	UpgradeMaxLifetime time.Duration `yaml:"upgrade_max_lifetime"`
+	ShutdownDelay      time.Duration `yaml:"shutdown_delay"`
+}
+
+// FileAdmin configures the admin API. An address of "off" disables it.
+type FileAdmin struct {
+	Address string `yaml:"address"`
+	Token   string `yaml:"token,omitempty"`
+}
+
+// FileMetrics configures the listener for /metrics. An address of "off"
+// disables it.
+type FileMetrics struct {
+	Address string `yaml:"address"`
+}
+
+type FileTracing struct {
+	Endpoint    string  `yaml:"endpoint,omitempty"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+	ServiceName string  `yaml:"service_name"`
+}
+
+type FileAccessLog struct {
+	Format       string   `yaml:"format"`
+	Template     string   `yaml:"template,omitempty"`
+	File         string   `yaml:"file,omitempty"`
+	SampleRatio  float64  `yaml:"sample_ratio"`
+	ExcludePaths []string `yaml:"exclude_paths,omitempty"`
+}
+
+type FileHost struct {
+	Host        string `yaml:"host"`
+	DefaultPool string `yaml:"default_pool"`
+}
+
+// FilePool settings left empty inherit the global settings.
+type FilePool struct {
+	Name            string             `yaml:"name"`
+	Backends        []FileBackend      `yaml:"backends,omitempty"`
+	BalancerType    string             `yaml:"balancer_type"`
+	BackendProtocol string             `yaml:"backend_protocol"`
+	Required        *bool              `yaml:"required"`
+	HealthCheck     FileHealthCheck    `yaml:"health_check"`
+	CircuitBreaker  FileCircuitBreaker `yaml:"circuit_breaker"`
+	Timeouts        FilePoolTimeouts   `yaml:"timeouts"`
+	Discovery       FileDiscovery      `yaml:"discovery,omitempty"`
+}
+
+type FilePoolTimeouts struct {
+	Connect  time.Duration `yaml:"connect"`
+	Response time.Duration `yaml:"response"`
+}
+
+type FileDiscovery struct {
+	File     string        `yaml:"file,omitempty"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	DNS        string        `yaml:"dns,omitempty"`
+	Record     string        `yaml:"record,omitempty"`
+	Server     string        `yaml:"server,omitempty"`
+	Port       int           `yaml:"port,omitempty"`
+	Scheme     string        `yaml:"scheme,omitempty"`
+	MinRefresh time.Duration `yaml:"min_refresh,omitempty"`
+	MaxRefresh time.Duration `yaml:"max_refresh,omitempty"`
+
+	Consul     string        `yaml:"consul,omitempty"`
+	Service    string        `yaml:"service,omitempty"`
+	Tag        string        `yaml:"tag,omitempty"`
+	Datacenter string        `yaml:"datacenter,omitempty"`
+	Token      string        `yaml:"token,omitempty"`
+	Wait       time.Duration `yaml:"wait,omitempty"`
+}
+
+// FileBackend is written either as a URL or as a mapping with a url and options.
+type FileBackend struct {
+	URL    string `yaml:"url"`
+	Weight *int   `yaml:"weight,omitempty"`
+}
+
+func (b *FileBackend) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&b.URL)
+	}
+	type plain FileBackend
+	return node.Decode((*plain)(b))
+}
+
+func (b FileBackend) MarshalYAML() (any, error) {
+	if b.Weight == nil {
+		return b.URL, nil
+	}
+	type plain FileBackend
+	return plain(b), nil
+}
+
+type FileRoute struct {
+	Name          string          `yaml:"name,omitempty"`
+	Host          string          `yaml:"host,omitempty"`
+	Path          string          `yaml:"path,omitempty"`
+	Pool          string          `yaml:"pool,omitempty"`
+	Splits        []FileSplit     `yaml:"splits,omitempty"`
+	StickyHeader  string          `yaml:"sticky_header,omitempty"`
+	StickyCookie  string          `yaml:"sticky_cookie,omitempty"`
+	PoolHeader    bool            `yaml:"pool_header,omitempty"`
+	Mirror        *FileMirror     `yaml:"mirror,omitempty"`
+	FlushInterval time.Duration   `yaml:"flush_interval,omitempty"`
+	HTTPSRedirect bool            `yaml:"https_redirect,omitempty"`
+	Redirect      *FileRedirect   `yaml:"redirect,omitempty"`
+	Respond       *FileRespond    `yaml:"respond,omitempty"`
+	Rewrite       FileRewrite     `yaml:"rewrite,omitempty"`
+	Match         FileMatch       `yaml:"match,omitempty"`
+	Request       FileHeaderRules `yaml:"request_headers,omitempty"`
+	Response      FileHeaderRules `yaml:"response_headers,omitempty"`
+}
+
+type FileSplit struct {
+	Pool   string `yaml:"pool"`
+	Weight int    `yaml:"weight"`
+}
+
+type FileMirror struct {
+	Pool    string   `yaml:"pool"`
+	Percent *float64 `yaml:"percent"`
+}
+
+type FileRedirect struct {
+	Location string `yaml:"location"`
+	Status   int    `yaml:"status"`
+}
+
+type FileRespond struct {
+	Status   int    `yaml:"status"`
+	Body     string `yaml:"body,omitempty"`
+	BodyFile string `yaml:"body_file,omitempty"`
+}
+
+type FileRewrite struct {
+	StripPrefix      string `yaml:"strip_prefix,omitempty"`
+	PrefixRewrite    string `yaml:"prefix_rewrite,omitempty"`
+	Regex            string `yaml:"regex,omitempty"`
+	RegexReplacement string `yaml:"replacement,omitempty"`
+}
+
+type FileMatch struct {
+	Methods     []string         `yaml:"methods,omitempty"`
+	Headers     []FileValueMatch `yaml:"headers,omitempty"`
+	Queries     []FileValueMatch `yaml:"queries,omitempty"`
+	SourceCIDRs []string         `yaml:"source_cidrs,omitempty"`
+}
+
+type FileValueMatch struct {
+	Name  string `yaml:"name"`
+	Exact string `yaml:"exact,omitempty"`
+	Regex string `yaml:"regex,omitempty"`
+}
+
+type FileHeaderRules struct {
+	Set    map[string]string `yaml:"set,omitempty"`
+	Add    map[string]string `yaml:"add,omitempty"`
+	Remove []string          `yaml:"remove,omitempty"`
+}
+
+func defaultFile() *File {
+	return &File{
+		Environment:     "development",
+		LogLevel:        "info",
+		LogFormat:       logger.FormatJSON,
+		BalancerType:    "round-robin",
+		BackendProtocol: "http1",
+		HealthCheck: FileHealthCheck{
+			Interval: 5 * time.Second,
+			Path:     "/health",
+		},
+		CircuitBreaker: FileCircuitBreaker{
+			ResetTimeout:  60 * time.Second,
+			SlowThreshold: 5 * time.Second,
+			MaxSlowCount:  3,
+		},
+		Timeouts: FileTimeouts{
+			Request:     30 * time.Second,
+			Connect:     5 * time.Second,
+			Response:    25 * time.Second,
+			UpgradeIdle: 60 * time.Second,
+		},
+		DefaultPool:         DefaultPoolName,
+		UnmatchedHostStatus: 404,
+		Admin: FileAdmin{
+			Address: "off",
+		},
+		Metrics: FileMetrics{
+			Address: ":9090",
+		},
+		Tracing: FileTracing{
+			SampleRatio: 1,
+			ServiceName: "routing-api",
+		},
+		AccessLog: FileAccessLog{
+			Format:      AccessLogJSON,
+			SampleRatio: 1,
+		},
+	}
+}
+
+// readFile decodes the file at path over the defaults, rejecting unknown keys.
+func readFile(path string) (*File, error) {
+	file := defaultFile()
+	if path == "" {
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// poolConfig resolves a pool from the file, filling unset values from defaults.
+func (p FilePool) poolConfig(defaults PoolConfig) PoolConfig {
+	pool := defaults
+	pool.Name = p.Name
+	pool.APIs = nil
+	for _, backend := range p.Backends {
+		pool.APIs = append(pool.APIs, backend.URL)
+		if backend.Weight != nil {
+			if pool.Weights == nil {
+				pool.Weights = make(map[string]int)
+			}
+			pool.Weights[backend.URL] = *backend.Weight
+		}
+	}
+
+	setString(&pool.BalancerType, p.BalancerType)
+	setString(&pool.BackendProtocol, p.BackendProtocol)
+	if p.Required != nil {
+		pool.Required = *p.Required
+	}
+	setDuration(&pool.HealthCheckInterval, p.HealthCheck.Interval)
+	setString(&pool.HealthCheckPath, p.HealthCheck.Path)
+	setInt(&pool.MaxFailures, p.CircuitBreaker.MaxFailures)
+	setDuration(&pool.ResetTimeout, p.CircuitBreaker.ResetTimeout)
+	setDuration(&pool.SlowThreshold, p.CircuitBreaker.SlowThreshold)
+	setInt(&pool.MaxSlowCount, p.CircuitBreaker.MaxSlowCount)
+	setDuration(&pool.ConnectTimeout, p.Timeouts.Connect)
+	setDuration(&pool.ResponseTimeout, p.Timeouts.Response)
+	pool.Discovery = DiscoveryConfig{
+		File:       p.Discovery.File,
+		Interval:   p.Discovery.Interval,
+		DNSName:    p.Discovery.DNS,
+		DNSRecord:  p.Discovery.Record,
+		DNSServer:  p.Discovery.Server,
+		Port:       p.Discovery.Port,
+		Scheme:     p.Discovery.Scheme,
+		MinRefresh: p.Discovery.MinRefresh,
+		MaxRefresh: p.Discovery.MaxRefresh,
+
+		ConsulAddress:    p.Discovery.Consul,
+		ConsulService:    p.Discovery.Service,
+		ConsulTag:        p.Discovery.Tag,
+		ConsulDatacenter: p.Discovery.Datacenter,
+		ConsulToken:      p.Discovery.Token,
+		ConsulWait:       p.Discovery.Wait,
+	}
+	return pool
+}
+
+func (r FileRoute) routeConfig() RouteConfig {
+	route := RouteConfig{
+		Name:          r.Name,
+		Host:          r.Host,
+		Path:          r.Path,
+		Pool:          r.Pool,
+		FlushInterval: r.FlushInterval,
+		StickyHeader:  r.StickyHeader,
+		StickyCookie:  r.StickyCookie,
+		PoolHeader:    r.PoolHeader,
+		HTTPSRedirect: r.HTTPSRedirect,
+
+		StripPrefix:      r.Rewrite.StripPrefix,
+		PrefixRewrite:    r.Rewrite.PrefixRewrite,
+		RegexRewrite:     r.Rewrite.Regex,
+		RegexReplacement: r.Rewrite.RegexReplacement,
+		RequestHeaders:   r.Request.headerRules(),
+		ResponseHeaders:  r.Response.headerRules(),
+
+		Methods:     r.Match.Methods,
+		SourceCIDRs: r.Match.SourceCIDRs,
+	}
+
+	for _, split := range r.Splits {
+		route.Splits = append(route.Splits, WeightedPool{Pool: split.Pool, Weight: split.Weight})
+	}
+	for _, match := range r.Match.Headers {
+		route.Headers = append(route.Headers, ValueMatch(match))
+	}
+	for _, match := range r.Match.Queries {
+		route.Queries = append(route.Queries, ValueMatch(match))
+	}
+	if r.Mirror != nil {
+		route.MirrorPool = r.Mirror.Pool
+		route.MirrorPercent = 100
+		if r.Mirror.Percent != nil {
+			route.MirrorPercent = *r.Mirror.Percent
+		}
+	}
+	if r.Redirect != nil {
+		route.Action = ActionRedirect
+		route.Location = r.Redirect.Location
+		route.Status = r.Redirect.Status
+	}
+	if r.Respond != nil {
+		route.Action = ActionRespond
+		route.Status = r.Respond.Status
+		route.Body = r.Respond.Body
+		route.BodyFile = r.Respond.BodyFile
+	}
+	return route.withDefaults()
+}
+
+func (r FileHeaderRules) headerRules() HeaderRules {
+	return HeaderRules{
+		Set:    sortedHeaderValues(r.Set),
+		Add:    sortedHeaderValues(r.Add),
+		Remove: r.Remove,
+	}
+}
+
+func sortedHeaderValues(values map[string]string) []HeaderValue {
+	var headers []HeaderValue
+	for name, value := range values {
+		headers = append(headers, HeaderValue{Name: name, Value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+func setString(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+func setInt(target *int, value int) {
+	if value != 0 {
+		*target = value
+	}
+}
+
+func setDuration(target *time.Duration, value time.Duration) {
+	if value != 0 {
+		*target = value
+	}
+}
+
+// MarshalYAML writes the configuration in the layout of a configuration file with
+// every global and pool setting spelled out, so loading the output without any
+// environment variables gives the same configuration.
+func (c *Config) MarshalYAML() (any, error) {
+	file := &File{
+		Environment:     c.Environment,
+		LogLevel:        c.LogLevel,
+		LogFormat:       c.LogFormat,
+		LogLevels:       c.LogLevels,
+		BalancerType:    c.BalancerType,
+		BackendProtocol: c.BackendProtocol,
+		HealthCheck: FileHealthCheck{
+			Interval: c.HealthCheckInterval,
+			Path:     c.HealthCheckPath,
+		},
+		CircuitBreaker: FileCircuitBreaker{
+			MaxFailures:   c.MaxFailures,
+			ResetTimeout:  c.ResetTimeout,
+			SlowThreshold: c.SlowThreshold,
+			MaxSlowCount:  c.MaxSlowCount,
+		},
+		Timeouts: FileTimeouts{
+			Request:            c.RequestTimeout,
+			Connect:            c.ConnectTimeout,
+			Response:           c.ResponseTimeout,
+			FlushInterval:      c.FlushInterval,
+			UpgradeIdle:        c.UpgradeIdleTimeout,
+			UpgradeMaxLifetime: c.UpgradeMaxLifetime,
+			ShutdownDelay:      c.ShutdownDelay,
+		},
+		DefaultPool:         c.DefaultPool,
+		UnmatchedHostStatus: c.UnmatchedHostStatus,
+		HTTPSRedirect:       c.HTTPSRedirect,
+		Admin: FileAdmin{
+			Address: c.AdminAddress,
+			Token:   c.AdminToken,
+		},
+		Metrics:   FileMetrics{Address: c.MetricsAddress},
+		Tracing:   FileTracing(c.Tracing),
+		AccessLog: FileAccessLog(c.AccessLog),
+	}
+	if c.AdminAddress == "" {
+		file.Admin.Address = "off"
+	}
+	if c.MetricsAddress == "" {
+		file.Metrics.Address = "off"
+	}
+
+	for _, listener := range c.Listeners {
+		file.Listeners = append(file.Listeners, FileListener{
+			Address: listener.Address,
+			TLS:     FileTLS{CertFile: listener.TLSCertFile, KeyFile: listener.TLSKeyFile},
+		})
+	}
+	for _, host := range c.VirtualHosts {
+		file.Hosts = append(file.Hosts, FileHost(host))
+	}
+	for _, pool := range c.Pools {
+		file.Pools = append(file.Pools, pool.filePool())
+	}
+	for _, route := range c.Routes {
+		file.Routes = append(file.Routes, route.fileRoute())
+	}
+	return file, nil
+}
+
+func (p PoolConfig) filePool() FilePool {
+	pool := FilePool{
+		Name:            p.Name,
+		BalancerType:    p.BalancerType,
+		BackendProtocol: p.BackendProtocol,
+		Required:        &p.Required,
+		HealthCheck: FileHealthCheck{
+			Interval: p.HealthCheckInterval,
+			Path:     p.HealthCheckPath,
+		},
+		CircuitBreaker: FileCircuitBreaker{
+			MaxFailures:   p.MaxFailures,
+			ResetTimeout:  p.ResetTimeout,
+			SlowThreshold: p.SlowThreshold,
+			MaxSlowCount:  p.MaxSlowCount,
+		},
+		Timeouts: FilePoolTimeouts{
+			Connect:  p.ConnectTimeout,
+			Response: p.ResponseTimeout,
+		},
+		Discovery: FileDiscovery{
+			File:       p.Discovery.File,
+			Interval:   p.Discovery.Interval,
+			DNS:        p.Discovery.DNSName,
+			Record:     p.Discovery.DNSRecord,
+			Server:     p.Discovery.DNSServer,
+			Port:       p.Discovery.Port,
+			Scheme:     p.Discovery.Scheme,
+			MinRefresh: p.Discovery.MinRefresh,
+			MaxRefresh: p.Discovery.MaxRefresh,
+
+			Consul:     p.Discovery.ConsulAddress,
+			Service:    p.Discovery.ConsulService,
+			Tag:        p.Discovery.ConsulTag,
+			Datacenter: p.Discovery.ConsulDatacenter,
+			Token:      p.Discovery.ConsulToken,
+			Wait:       p.Discovery.ConsulWait,
+		},
+	}
+	for _, api := range p.APIs {
+		backend := FileBackend{URL: api}
+		if weight, ok := p.Weights[api]; ok {
+			backend.Weight = &weight
+		}
+		pool.Backends = append(pool.Backends, backend)
+	}
+	return pool
+}
+
+func (r RouteConfig) fileRoute() FileRoute {
+	route := FileRoute{
+		Name:          r.Name,
+		Host:          r.Host,
+		Path:          r.Path,
+		Pool:          r.Pool,
+		StickyHeader:  r.StickyHeader,
+		StickyCookie:  r.StickyCookie,
+		PoolHeader:    r.PoolHeader,
+		FlushInterval: r.FlushInterval,
+		HTTPSRedirect: r.HTTPSRedirect,
+		Rewrite: FileRewrite{
+			StripPrefix:      r.StripPrefix,
+			PrefixRewrite:    r.PrefixRewrite,
+			Regex:            r.RegexRewrite,
+			RegexReplacement: r.RegexReplacement,
+		},
+		Match: FileMatch{
+			Methods:     r.Methods,
+			SourceCIDRs: r.SourceCIDRs,
+		},
+		Request:  r.RequestHeaders.fileHeaderRules(),
+		Response: r.ResponseHeaders.fileHeaderRules(),
+	}
+
+	for _, split := range r.Splits {
+		route.Splits = append(route.Splits, FileSplit(split))
+	}
+	for _, match := range r.Headers {
+		route.Match.Headers = append(route.Match.Headers, FileValueMatch(match))
+	}
+	for _, match := range r.Queries {
+		route.Match.Queries = append(route.Match.Queries, FileValueMatch(match))
+	}
+	if r.MirrorPool != "" {
+		route.Mirror = &FileMirror{Pool: r.MirrorPool, Percent: &r.MirrorPercent}
+	}
+	switch r.Action {
+	case ActionRedirect:
+		route.Redirect = &FileRedirect{Location: r.Location, Status: r.Status}
+	case ActionRespond:
+		route.Respond = &FileRespond{Status: r.Status, Body: r.Body, BodyFile: r.BodyFile}
+	}
+	return route
+}
+
+// fileHeaderRules keeps the last value when a header is added more than once,
+// since the file layout maps each header name to a single value.
+func (h HeaderRules) fileHeaderRules() FileHeaderRules {
+	rules := FileHeaderRules{Remove: h.Remove}
+	for _, header := range h.Set {
+		if rules.Set == nil {
+			rules.Set = make(map[string]string)
+		}
+		rules.Set[header.Name] = header.Value
+	}
+	for _, header := range h.Add {
+		if rules.Add == nil {
+			rules.Add = make(map[string]string)
+		}
+		rules.Add[header.Name] = header.Value
+	}
+	return rules
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testConfigYAML = `
+log_level: debug
+listeners:
+  - address: ":8080"
+  - address: "127.0.0.1:8443"
+    tls:
+      cert_file: {dir}/cert.pem
+      key_file: {dir}/key.pem
+circuit_breaker:
+  max_failures: 4
+timeouts:
+  request: 40s
+  upgrade_idle: 0s
+hosts:
+  - host: API.example.com
+pools:
+  - name: default
+    backends:
+      - http://localhost:8080
+      - url: http://localhost:8081
+        weight: 3
+  - name: orders
+    balancer_type: least-connections
+    required: false
+    backends: [http://localhost:9090]
+    health_check:
+      path: /ready
+    circuit_breaker:
+      max_failures: 2
+routes:
+  - path: /orders/*
+    host: api.example.com
+    pool: orders
+    match:
+      methods: [get]
+      headers:
+        - name: X-Api-Version
+          exact: "2"
+    rewrite:
+      prefix_rewrite: /v2
+    request_headers:
+      set:
+        X-Client-IP: "{client_ip}"
+  - path: /old/*
+    redirect:
+      location: /new{rest}
+      status: 301
+`
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// writeTestConfig writes testConfigYAML and the TLS files it refers to into dir.
+func writeTestConfig(t *testing.T, dir string) string {
+	t.Helper()
+	for _, name := range []string{"cert.pem", "key.pem"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(strings.ReplaceAll(testConfigYAML, "{dir}", dir)), 0o644))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	os.Clearenv()
+	dir := t.TempDir()
+	path := writeTestConfig(t, dir)
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "8080", cfg.Port)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, []ListenerConfig{
+		{Address: ":8080"},
+		{Address: "127.0.0.1:8443", TLSCertFile: dir + "/cert.pem", TLSKeyFile: dir + "/key.pem"},
+	}, cfg.Listeners)
+	assert.Equal(t, 4, cfg.MaxFailures)
+	assert.Equal(t, 40*time.Second, cfg.RequestTimeout)
+	assert.Equal(t, time.Duration(0), cfg.UpgradeIdleTimeout)
+	assert.Equal(t, 25*time.Second, cfg.ResponseTimeout)
+	assert.Equal(t, []string{"http://localhost:8080", "http://localhost:8081"}, cfg.ApplicationAPIs)
+	assert.Equal(t, []VirtualHostConfig{{Host: "api.example.com", DefaultPool: DefaultPoolName}}, cfg.VirtualHosts)
+
+	require.Len(t, cfg.Pools, 2)
+	assert.Equal(t, map[string]int{"http://localhost:8081": 3}, cfg.Pools[0].Weights)
+	assert.True(t, cfg.Pools[0].Required)
+	assert.Equal(t, PoolConfig{
+		Name:                "orders",
+		APIs:                []string{"http://localhost:9090"},
+		BalancerType:        "least-connections",
+		BackendProtocol:     "http1",
+		Required:            false,
+		HealthCheckInterval: 5 * time.Second,
+		HealthCheckPath:     "/ready",
+		MaxFailures:         2,
+		ResetTimeout:        60 * time.Second,
+		SlowThreshold:       5 * time.Second,
+		MaxSlowCount:        3,
+		ConnectTimeout:      5 * time.Second,
+		ResponseTimeout:     25 * time.Second,
+	}, cfg.Pools[1])
+
+	require.Len(t, cfg.Routes, 2)
+	assert.Equal(t, RouteConfig{
+		Host:          "api.example.com",
+		Path:          "/orders/*",
+		Pool:          "orders",
+		Methods:       []string{"GET"},
+		Headers:       []ValueMatch{{Name: "X-Api-Version", Exact: "2"}},
+		StripPrefix:   "/orders",
+		PrefixRewrite: "/v2",
+		RequestHeaders: HeaderRules{
+			Set: []HeaderValue{{Name: "X-Client-IP", Value: "{client_ip}"}},
+		},
+	}, cfg.Routes[0])
+	assert.Equal(t, RouteConfig{Path: "/old/*", Action: ActionRedirect, Location: "/new{rest}", Status: 301}, cfg.Routes[1])
+}
+
+func TestLoadFile_EnvironmentOverrides(t *testing.T) {
+	os.Clearenv()
+	path := writeTestConfig(t, t.TempDir())
+
+	os.Setenv("PORT", "9000")
+	os.Setenv("LOG_LEVEL", "warn")
+	os.Setenv("MAX_FAILURES", "7")
+	os.Setenv("POOL_ORDERS_MAX_FAILURES", "9")
+	os.Setenv("POOL_ORDERS_APIS", "http://orders:9090")
+	os.Setenv("API_1", "http://localhost:7070")
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9000", cfg.Port)
+	assert.Equal(t, ":9000", cfg.Listeners[0].Address)
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, 7, cfg.MaxFailures)
+	assert.Equal(t, []string{"http://localhost:7070"}, cfg.Pools[0].APIs)
+	assert.Equal(t, 7, cfg.Pools[0].MaxFailures)
+	assert.Equal(t, []string{"http://orders:9090"}, cfg.Pools[1].APIs)
+	assert.Equal(t, 9, cfg.Pools[1].MaxFailures)
+	assert.Len(t, cfg.Routes, 2)
+
+	os.Setenv("ROUTES", "/orders/*=orders")
+	cfg, err = LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []RouteConfig{{Path: "/orders/*", Pool: "orders"}}, cfg.Routes)
+}
+
+func TestLoadFile_JSON(t *testing.T) {
+	os.Clearenv()
+	path := writeConfigFile(t, "config.json", `{
+		"listeners": [{"address": ":3000"}],
+		"circuit_breaker": {"max_failures": 5},
+		"pools": [{"name": "default", "backends": ["http://localhost:8080"]}]
+	}`)
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "3000", cfg.Port)
+	assert.Equal(t, []string{"http://localhost:8080"}, cfg.Pools[0].APIs)
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	os.Clearenv()
+
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+
+	_, err = LoadFile(writeConfigFile(t, "unknown.yaml", "listeners: [{address: ':3000'}]\nlog_levle: debug\n"))
+	assert.ErrorContains(t, err, "log_levle")
+
+	_, err = LoadFile(writeConfigFile(t, "invalid.yaml", "timeouts:\n  request: soon\n"))
+	assert.Error(t, err)
+
+	_, err = LoadFile(writeConfigFile(t, "weights.yaml", `
+listeners: [{address: ":3000"}]
+circuit_breaker: {max_failures: 5}
+pools: [{name: default, backends: [{url: "http://a:8080", weight: 1000000007}, {url: "http://b:8080", weight: 1000}]}]
+`))
+	assert.ErrorContains(t, err, `pool "default": weight for backend "http://a:8080" cannot be more than 1000`)
+}
+
+func TestConfig_MarshalYAMLRoundTrip(t *testing.T) {
+	os.Clearenv()
+	dir := t.TempDir()
+	cfg, err := LoadFile(writeTestConfig(t, dir))
+	require.NoError(t, err)
+
+	data, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+
+	reloaded, err := LoadFile(writeConfigFile(t, "printed.yaml", string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, cfg, reloaded)
+}
+
+func TestLoadFileWithOverrides(t *testing.T) {
+	os.Clearenv()
+	path := writeTestConfig(t, t.TempDir())
+	os.Setenv("PORT", "9000")
+	os.Setenv("LOG_LEVEL", "warn")
+
+	cfg, err := LoadFileWithOverrides(path, Overrides{ListenAddress: "127.0.0.1:7000", LogLevel: "error"})
+	require.NoError(t, err)
+	assert.Equal(t, "7000", cfg.Port)
+	assert.Equal(t, "127.0.0.1:7000", cfg.Listeners[0].Address)
+	assert.Equal(t, "127.0.0.1:8443", cfg.Listeners[1].Address)
+	assert.Equal(t, "error", cfg.LogLevel)
+
+	_, err = LoadFileWithOverrides(path, Overrides{ListenAddress: "7000"})
+	assert.ErrorContains(t, err, "-listen")
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"routing-api/internal/logger"
+)
+
+// getLogLevels reads LOG_LEVELS, such as "proxy=debug,health=warn", which
+// replaces log_levels from the file.
+func getLogLevels(file map[string]string, errs *problems) map[string]string {
+	value := getEnvRaw("LOG_LEVELS")
+	if value == "" {
+		return file
+	}
+	levels := make(map[string]string)
+	for _, entry := range splitList(value) {
+		component, level, ok := strings.Cut(entry, "=")
+		if !ok {
+			errs.invalid("LOG_LEVELS", entry, errors.New("expected component=level"))
+			continue
+		}
+		levels[strings.TrimSpace(component)] = strings.TrimSpace(level)
+	}
+	return levels
+}
+
+func (c *Config) validateLogging(errs *problems) {
+	checkOneOf(errs, "LOG_LEVEL", c.LogLevel, logLevels)
+	checkOneOf(errs, "LOG_FORMAT", c.LogFormat, logFormats)
+	for _, component := range slices.Sorted(maps.Keys(c.LogLevels)) {
+		if !slices.Contains(logger.Components, component) {
+			errs.addf("LOG_LEVELS", "unknown component %q, expected one of %s", component, strings.Join(logger.Components, ", "))
+			continue
+		}
+		if level := c.LogLevels[component]; !slices.Contains(logLevels, level) {
+			errs.addf("LOG_LEVELS", "unknown level %q for %s, expected one of %s", level, component, strings.Join(logLevels, ", "))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLoad_Logging(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "3000")
+	os.Setenv("MAX_FAILURES", "5")
+	os.Setenv("API_1", "http://localhost:8080")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.Equal(t, "json", cfg.LogFormat)
+	assert.Empty(t, cfg.LogLevels)
+
+	os.Setenv("LOG_LEVEL", "warn")
+	os.Setenv("LOG_FORMAT", "console")
+	os.Setenv("LOG_LEVELS", "proxy=debug, health = error")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, "console", cfg.LogFormat)
+	assert.Equal(t, map[string]string{"proxy": "debug", "health": "error"}, cfg.LogLevels)
+
+	os.Setenv("LOG_LEVEL", "development")
+	os.Setenv("LOG_FORMAT", "json")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, "console", cfg.LogFormat)
+
+	os.Setenv("LOG_LEVEL", "verbose")
+	os.Setenv("LOG_FORMAT", "text")
+	os.Setenv("LOG_LEVELS", "proxy=trace,router=debug")
+	_, err = Load()
+	assert.ErrorContains(t, err, `LOG_LEVEL: unknown value "verbose", expected one of debug, info, warn, error`)
+	assert.ErrorContains(t, err, `LOG_FORMAT: unknown value "text", expected one of json, console`)
+	assert.ErrorContains(t, err, `LOG_LEVELS: unknown level "trace" for proxy`)
+	assert.ErrorContains(t, err, `LOG_LEVELS: unknown component "router", expected one of proxy, health, circuit, balancer`)
+
+	os.Setenv("LOG_LEVEL", "info")
+	os.Setenv("LOG_FORMAT", "json")
+	os.Setenv("LOG_LEVELS", "proxy")
+	_, err = Load()
+	assert.ErrorContains(t, err, `LOG_LEVELS: invalid value "proxy": expected component=level`)
+}
+
+func TestLoadFile_Logging(t *testing.T) {
+	os.Clearenv()
+	path := writeConfigFile(t, "config.yaml", `
+listeners: [{address: ":3000"}]
+circuit_breaker: {max_failures: 5}
+pools: [{name: default, backends: ["http://localhost:8080"]}]
+log_level: error
+log_format: console
+log_levels:
+  circuit: info
+  balancer: debug
+`)
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "error", cfg.LogLevel)
+	assert.Equal(t, "console", cfg.LogFormat)
+	assert.Equal(t, map[string]string{"circuit": "info", "balancer": "debug"}, cfg.LogLevels)
+
+	os.Setenv("LOG_LEVELS", "health=warn")
+	cfg, err = LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"health": "warn"}, cfg.LogLevels)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveNames are fragments of header and query parameter names whose values
+// are treated as credentials.
+var sensitiveNames = []string{"auth", "token", "secret", "password", "key", "cookie", "session", "credential"}
+
+// Redacted returns a copy of the configuration that is safe to print. The admin
+// and Consul tokens, credentials in backend URLs and the values of credential headers and
+// query parameters are replaced with REDACTED.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.ApplicationAPIs = redactURLs(c.ApplicationAPIs)
+	if c.AdminToken != "" {
+		out.AdminToken = redacted
+	}
+	out.Tracing.Endpoint = redactURL(c.Tracing.Endpoint)
+
+	out.Pools = make([]PoolConfig, len(c.Pools))
+	for i, pool := range c.Pools {
+		pool.APIs = redactURLs(pool.APIs)
+		if pool.Weights != nil {
+			weights := make(map[string]int, len(pool.Weights))
+			for api, weight := range pool.Weights {
+				weights[redactURL(api)] = weight
+			}
+			pool.Weights = weights
+		}
+		if pool.Discovery.ConsulToken != "" {
+			pool.Discovery.ConsulToken = redacted
+		}
+		pool.Discovery.ConsulAddress = redactURL(pool.Discovery.ConsulAddress)
+		out.Pools[i] = pool
+	}
+
+	out.Routes = make([]RouteConfig, len(c.Routes))
+	for i, route := range c.Routes {
+		route.Location = redactURL(route.Location)
+		route.Headers = redactMatches(route.Headers)
+		route.Queries = redactMatches(route.Queries)
+		route.RequestHeaders = route.RequestHeaders.redacted()
+		route.ResponseHeaders = route.ResponseHeaders.redacted()
+		out.Routes[i] = route
+	}
+	return &out
+}
+
+func (h HeaderRules) redacted() HeaderRules {
+	return HeaderRules{
+		Set:    redactHeaderValues(h.Set),
+		Add:    redactHeaderValues(h.Add),
+		Remove: h.Remove,
+	}
+}
+
+func redactHeaderValues(headers []HeaderValue) []HeaderValue {
+	var out []HeaderValue
+	for _, header := range headers {
+		if isSensitive(header.Name) {
+			header.Value = redacted
+		}
+		out = append(out, header)
+	}
+	return out
+}
+
+func redactMatches(matches []ValueMatch) []ValueMatch {
+	var out []ValueMatch
+	for _, match := range matches {
+		if isSensitive(match.Name) {
+			if match.Exact != "" {
+				match.Exact = redacted
+			}
+			if match.Regex != "" {
+				match.Regex = redacted
+			}
+		}
+		out = append(out, match)
+	}
+	return out
+}
+
+func redactURLs(urls []string) []string {
+	var out []string
+	for _, u := range urls {
+		out = append(out, redactURL(u))
+	}
+	return out
+}
+
+// redactURL hides the password of a URL, or its user name when there is no
+// password since that is then usually a token.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	} else {
+		u.User = url.User(redacted)
+	}
+	return u.String()
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, fragment := range sensitiveNames {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
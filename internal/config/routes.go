@@ -0,0 +1,660 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ActionRedirect = "redirect"
+	ActionRespond  = "respond"
+)
+
+const DefaultPoolName = "default"
+
+// MaxWeight is the largest weight a backend can have. Balancers lay out a cycle
+// with a slot for every unit of weight, so weights are kept small.
+const MaxWeight = 1000
+
+type PoolConfig struct {
+	Name            string
+	APIs            []string
+	Weights         map[string]int
+	BalancerType    string
+	BackendProtocol string
+	Required        bool
+
+	HealthCheckInterval time.Duration
+	HealthCheckPath     string
+
+	MaxFailures   int
+	ResetTimeout  time.Duration
+	SlowThreshold time.Duration
+	MaxSlowCount  int
+
+	ConnectTimeout  time.Duration
+	ResponseTimeout time.Duration
+
+	Discovery DiscoveryConfig
+}
+
+type RouteConfig struct {
+	Name          string
+	Host          string
+	Path          string
+	Pool          string
+	FlushInterval time.Duration
+
+	Splits       []WeightedPool
+	StickyHeader string
+	StickyCookie string
+	// PoolHeader makes a split route name the pool that served each response.
+	PoolHeader bool
+
+	MirrorPool    string
+	MirrorPercent float64
+
+	// Action is empty for routes that proxy to a pool, or ActionRedirect or
+	// ActionRespond for routes answered by the router itself.
+	Action        string
+	Status        int
+	Location      string
+	Body          string
+	BodyFile      string
+	HTTPSRedirect bool
+
+	StripPrefix      string
+	PrefixRewrite    string
+	RegexRewrite     string
+	RegexReplacement string
+	RequestHeaders   HeaderRules
+	ResponseHeaders  HeaderRules
+
+	Methods     []string
+	Headers     []ValueMatch
+	Queries     []ValueMatch
+	SourceCIDRs []string
+}
+
+// ValueMatch matches a header or query parameter by exact value, by regular
+// expression, or by presence when both Exact and Regex are empty.
+type ValueMatch struct {
+	Name  string
+	Exact string
+	Regex string
+}
+
+// HeaderRules removes, sets and adds headers. Values may use the placeholders
+// {client_ip}, {request_id}, {host}, {method}, {path} and {scheme}.
+type HeaderRules struct {
+	Set    []HeaderValue
+	Add    []HeaderValue
+	Remove []string
+}
+
+type HeaderValue struct {
+	Name  string
+	Value string
+}
+
+type WeightedPool struct {
+	Pool   string
+	Weight int
+}
+
+func (r RouteConfig) RouteName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Host + r.Path
+}
+
+func (r RouteConfig) Conditions() int {
+	conditions := len(r.Headers) + len(r.Queries)
+	if len(r.Methods) > 0 {
+		conditions++
+	}
+	if len(r.SourceCIDRs) > 0 {
+		conditions++
+	}
+	return conditions
+}
+
+type VirtualHostConfig struct {
+	Host        string
+	DefaultPool string
+}
+
+func (c *Config) Pool(name string) (PoolConfig, bool) {
+	for _, pool := range c.Pools {
+		if pool.Name == name {
+			return pool, true
+		}
+	}
+	return PoolConfig{}, false
+}
+
+func (c *Config) defaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		Name:                DefaultPoolName,
+		APIs:                c.ApplicationAPIs,
+		BalancerType:        c.BalancerType,
+		BackendProtocol:     c.BackendProtocol,
+		Required:            true,
+		HealthCheckInterval: c.HealthCheckInterval,
+		HealthCheckPath:     c.HealthCheckPath,
+		MaxFailures:         c.MaxFailures,
+		ResetTimeout:        c.ResetTimeout,
+		SlowThreshold:       c.SlowThreshold,
+		MaxSlowCount:        c.MaxSlowCount,
+		ConnectTimeout:      c.ConnectTimeout,
+		ResponseTimeout:     c.ResponseTimeout,
+	}
+}
+
+// getPools builds the pools declared in the config file and in POOLS. The default
+// pool comes from the file or from API_1..API_10, and POOL_<NAME>_* variables
+// override the settings of any pool.
+func getPools(defaults PoolConfig, file *File, errs *problems) []PoolConfig {
+	var pools []PoolConfig
+	defined := make(map[string]bool)
+	add := func(pool PoolConfig) {
+		pools = append(pools, poolFromEnv(pool, errs))
+		defined[pool.Name] = true
+	}
+
+	defaultIndex := -1
+	for i, filePool := range file.Pools {
+		if filePool.Name == DefaultPoolName {
+			defaultIndex = i
+			break
+		}
+	}
+
+	if defaultIndex != -1 {
+		pool := file.Pools[defaultIndex].poolConfig(defaults)
+		if len(defaults.APIs) > 0 {
+			pool.APIs = defaults.APIs
+			pool.Weights = nil
+		}
+		add(pool)
+	} else if len(defaults.APIs) > 0 {
+		add(defaults)
+	}
+
+	for i, filePool := range file.Pools {
+		if i != defaultIndex {
+			add(filePool.poolConfig(defaults))
+		}
+	}
+
+	for _, name := range splitList(os.Getenv("POOLS")) {
+		if !defined[name] {
+			pool := defaults
+			pool.Name = name
+			pool.APIs = nil
+			pool.Weights = nil
+			pool.Required = true
+			add(pool)
+		}
+	}
+
+	return pools
+}
+
+func poolFromEnv(pool PoolConfig, errs *problems) PoolConfig {
+	prefix := "POOL_" + envName(pool.Name) + "_"
+	if apis := splitList(os.Getenv(prefix + "APIS")); len(apis) > 0 {
+		pool.APIs = apis
+	}
+	pool.BalancerType = getEnv(prefix+"BALANCER_TYPE", pool.BalancerType)
+	pool.BackendProtocol = getEnv(prefix+"BACKEND_PROTOCOL", pool.BackendProtocol)
+	pool.Required = getEnvBool(prefix+"REQUIRED", pool.Required, errs)
+	pool.HealthCheckInterval = getEnvDuration(prefix+"HEALTH_CHECK_INTERVAL", pool.HealthCheckInterval.String(), errs)
+	pool.HealthCheckPath = getEnv(prefix+"HEALTH_CHECK_PATH", pool.HealthCheckPath)
+	pool.MaxFailures = getEnvInt(prefix+"MAX_FAILURES", pool.MaxFailures, errs)
+	pool.ResetTimeout = getEnvDuration(prefix+"RESET_TIMEOUT", pool.ResetTimeout.String(), errs)
+	pool.SlowThreshold = getEnvDuration(prefix+"SLOW_THRESHOLD", pool.SlowThreshold.String(), errs)
+	pool.MaxSlowCount = getEnvInt(prefix+"MAX_SLOW_COUNT", pool.MaxSlowCount, errs)
+	pool.ConnectTimeout = getEnvDuration(prefix+"CONNECT_TIMEOUT", pool.ConnectTimeout.String(), errs)
+	pool.ResponseTimeout = getEnvDuration(prefix+"RESPONSE_TIMEOUT", pool.ResponseTimeout.String(), errs)
+	pool.Discovery = discoveryFromEnv(pool.Discovery, prefix, errs)
+	return pool
+}
+
+// getRoutes parses ROUTES, which replaces the routes of the config file when set.
+func getRoutes(file *File, errs *problems) []RouteConfig {
+	var routes []RouteConfig
+	if os.Getenv("ROUTES") == "" {
+		for _, route := range file.Routes {
+			routes = append(routes, route.routeConfig())
+		}
+		return routes
+	}
+
+	for _, entry := range splitList(os.Getenv("ROUTES")) {
+		route, err := parseRoute(entry)
+		if err != nil {
+			errs.addf("ROUTES", "invalid entry %q: %w", entry, err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// parseRoute parses "[host]/path=target[;option...]" where target is a pool name,
+// weighted pools like "v1:95|v2:5", or one of the actions redirect and respond
+// configured with status:, location:, body: and body-file:. Each option is one of the conditions
+// method:GET|POST, header:Name[=value|~regex], query:name[=value|~regex], cidr:10.0.0.0/8|...
+// or the settings name:route-name, sticky-header:Name, sticky-cookie:name,
+// pool-header, mirror:pool[:percent], strip-prefix[:/prefix], prefix-rewrite:/new,
+// regex-rewrite:pattern=replacement, and set-header:, add-header:, remove-header:
+// (with -response-header variants) taking Name=value or Name.
+func parseRoute(entry string) (RouteConfig, error) {
+	parts := strings.Split(entry, ";")
+	pattern, target, _ := strings.Cut(parts[0], "=")
+	pattern = strings.TrimSpace(pattern)
+	target = strings.TrimSpace(target)
+
+	route := RouteConfig{Path: pattern, Pool: target}
+	if target == ActionRedirect || target == ActionRespond {
+		route.Pool = ""
+		route.Action = target
+	} else if strings.Contains(target, ":") {
+		splits, err := parseSplits(target)
+		if err != nil {
+			return RouteConfig{}, err
+		}
+		route.Pool = ""
+		route.Splits = splits
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		route.Host = strings.ToLower(pattern[:i])
+		route.Path = pattern[i:]
+	}
+
+	for _, condition := range parts[1:] {
+		kind, value, _ := strings.Cut(strings.TrimSpace(condition), ":")
+		switch kind {
+		case "method":
+			for _, method := range strings.Split(value, "|") {
+				route.Methods = append(route.Methods, strings.ToUpper(strings.TrimSpace(method)))
+			}
+		case "header":
+			route.Headers = append(route.Headers, parseValueMatch(value))
+		case "query":
+			route.Queries = append(route.Queries, parseValueMatch(value))
+		case "cidr":
+			for _, cidr := range strings.Split(value, "|") {
+				route.SourceCIDRs = append(route.SourceCIDRs, strings.TrimSpace(cidr))
+			}
+		case "name":
+			route.Name = strings.TrimSpace(value)
+		case "sticky-header":
+			route.StickyHeader = strings.TrimSpace(value)
+		case "sticky-cookie":
+			route.StickyCookie = strings.TrimSpace(value)
+		case "pool-header":
+			route.PoolHeader = true
+		case "mirror":
+			pool, percent, found := strings.Cut(value, ":")
+			route.MirrorPool = strings.TrimSpace(pool)
+			route.MirrorPercent = 100
+			if found {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+				if err != nil {
+					return RouteConfig{}, fmt.Errorf("invalid mirror percentage %q: %w", percent, err)
+				}
+				route.MirrorPercent = parsed
+			}
+		case "status":
+			status, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return RouteConfig{}, fmt.Errorf("invalid status %q: %w", value, err)
+			}
+			route.Status = status
+		case "location":
+			route.Location = strings.TrimSpace(value)
+		case "body":
+			route.Body = value
+		case "body-file":
+			route.BodyFile = strings.TrimSpace(value)
+		case "https-redirect":
+			route.HTTPSRedirect = true
+		case "strip-prefix":
+			route.StripPrefix = strings.TrimSpace(value)
+			if route.StripPrefix == "" {
+				route.StripPrefix = route.Prefix()
+			}
+		case "prefix-rewrite":
+			route.PrefixRewrite = strings.TrimSpace(value)
+		case "regex-rewrite":
+			route.RegexRewrite, route.RegexReplacement, _ = strings.Cut(value, "=")
+		case "set-header":
+			route.RequestHeaders.Set = append(route.RequestHeaders.Set, parseHeaderValue(value))
+		case "add-header":
+			route.RequestHeaders.Add = append(route.RequestHeaders.Add, parseHeaderValue(value))
+		case "remove-header":
+			route.RequestHeaders.Remove = append(route.RequestHeaders.Remove, strings.TrimSpace(value))
+		case "set-response-header":
+			route.ResponseHeaders.Set = append(route.ResponseHeaders.Set, parseHeaderValue(value))
+		case "add-response-header":
+			route.ResponseHeaders.Add = append(route.ResponseHeaders.Add, parseHeaderValue(value))
+		case "remove-response-header":
+			route.ResponseHeaders.Remove = append(route.ResponseHeaders.Remove, strings.TrimSpace(value))
+		default:
+			return RouteConfig{}, fmt.Errorf("unknown condition %q", condition)
+		}
+	}
+
+	return route.withDefaults(), nil
+}
+
+func (r RouteConfig) withDefaults() RouteConfig {
+	r.Host = strings.ToLower(r.Host)
+	for i, method := range r.Methods {
+		r.Methods[i] = strings.ToUpper(method)
+	}
+	if r.PrefixRewrite != "" && r.StripPrefix == "" {
+		r.StripPrefix = r.Prefix()
+	}
+	if r.Status == 0 {
+		switch r.Action {
+		case ActionRedirect:
+			r.Status = http.StatusFound
+		case ActionRespond:
+			r.Status = http.StatusOK
+		}
+	}
+	return r
+}
+
+// Prefix is the literal part of the route path before any wildcard or variable.
+func (r RouteConfig) Prefix() string {
+	path := r.Path
+	if i := strings.IndexAny(path, "*{"); i != -1 {
+		path = path[:i]
+	}
+	if trimmed := strings.TrimSuffix(path, "/"); trimmed != "" {
+		return trimmed
+	}
+	return path
+}
+
+func parseHeaderValue(value string) HeaderValue {
+	name, headerValue, _ := strings.Cut(value, "=")
+	return HeaderValue{Name: strings.TrimSpace(name), Value: headerValue}
+}
+
+func parseSplits(target string) ([]WeightedPool, error) {
+	var splits []WeightedPool
+	for _, part := range strings.Split(target, "|") {
+		pool, weightValue, _ := strings.Cut(part, ":")
+		weight, err := strconv.Atoi(strings.TrimSpace(weightValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for pool %q: %w", pool, err)
+		}
+		splits = append(splits, WeightedPool{Pool: strings.TrimSpace(pool), Weight: weight})
+	}
+	return splits, nil
+}
+
+func parseValueMatch(value string) ValueMatch {
+	if i := strings.IndexAny(value, "=~"); i != -1 {
+		match := ValueMatch{Name: strings.TrimSpace(value[:i])}
+		if value[i] == '=' {
+			match.Exact = value[i+1:]
+		} else {
+			match.Regex = value[i+1:]
+		}
+		return match
+	}
+	return ValueMatch{Name: strings.TrimSpace(value)}
+}
+
+// getVirtualHosts parses HOSTS, which replaces the hosts of the config file when set.
+func getVirtualHosts(defaultPool string, file *File) []VirtualHostConfig {
+	var hosts []VirtualHostConfig
+	if os.Getenv("HOSTS") == "" {
+		for _, host := range file.Hosts {
+			if host.DefaultPool == "" {
+				host.DefaultPool = defaultPool
+			}
+			hosts = append(hosts, VirtualHostConfig{Host: strings.ToLower(host.Host), DefaultPool: host.DefaultPool})
+		}
+		return hosts
+	}
+
+	for _, entry := range splitList(os.Getenv("HOSTS")) {
+		host, pool, found := strings.Cut(entry, "=")
+		if !found {
+			pool = defaultPool
+		}
+		hosts = append(hosts, VirtualHostConfig{
+			Host:        strings.ToLower(strings.TrimSpace(host)),
+			DefaultPool: strings.TrimSpace(pool),
+		})
+	}
+	return hosts
+}
+
+func (c *Config) validateRoutes(errs *problems) {
+	pools := make(map[string]bool)
+	for _, pool := range c.Pools {
+		key := fmt.Sprintf("pool %q", pool.Name)
+		if pools[pool.Name] {
+			errs.addf(key, "defined more than once")
+		}
+		pools[pool.Name] = true
+
+		switch {
+		case pool.Discovery.Enabled() && len(pool.APIs) > 0:
+			errs.addf(key, "cannot list backends and use discovery")
+		case !pool.Discovery.Enabled() && len(pool.APIs) == 0:
+			errs.addf(key, "has no application APIs")
+		}
+	}
+
+	if len(c.Pools) > 0 && !pools[c.DefaultPool] {
+		errs.addf("DEFAULT_POOL", "default pool %q is not defined", c.DefaultPool)
+	}
+
+	hosts := make(map[string]bool)
+	for _, host := range c.VirtualHosts {
+		key := fmt.Sprintf("virtual host %q", host.Host)
+		if hosts[host.Host] {
+			errs.addf(key, "defined more than once")
+		}
+		hosts[host.Host] = true
+
+		if host.Host == "" || strings.Contains(strings.TrimPrefix(host.Host, "*."), "*") {
+			errs.addf(key, "must be a host name or *.domain wildcard")
+		}
+		if !pools[host.DefaultPool] {
+			errs.addf(key, "references unknown pool %q", host.DefaultPool)
+		}
+	}
+
+	if len(c.VirtualHosts) > 0 && c.UnmatchedHostStatus != 404 && c.UnmatchedHostStatus != 421 {
+		errs.addf("UNMATCHED_HOST_STATUS", "unmatched host status must be 404 or 421, got %d", c.UnmatchedHostStatus)
+	}
+
+	splitNames := make(map[string]bool)
+	for _, route := range c.Routes {
+		key := fmt.Sprintf("route %q", route.RouteName())
+		if route.Host != "" && !hosts[route.Host] {
+			errs.addf(key, "references undeclared virtual host %q", route.Host)
+		}
+		if !strings.HasPrefix(route.Path, "/") {
+			errs.addf(key, "path %q must start with /", route.Path)
+		}
+		if i := strings.Index(route.Path, "*"); i != -1 && i != len(route.Path)-1 {
+			errs.addf(key, "path %q may only use * as its last character", route.Path)
+		}
+		for _, err := range []error{validateTarget(route, pools), validateConditions(route), validateRewrite(route)} {
+			if err != nil {
+				errs.addf(key, "%w", err)
+			}
+		}
+		if route.MirrorPool != "" {
+			if !pools[route.MirrorPool] {
+				errs.addf(key, "mirrors to unknown pool %q", route.MirrorPool)
+			}
+			if route.MirrorPercent <= 0 || route.MirrorPercent > 100 {
+				errs.addf(key, "mirror percentage must be between 0 and 100, got %g", route.MirrorPercent)
+			}
+		}
+		if len(route.Splits) > 0 {
+			if splitNames[route.RouteName()] {
+				errs.addf(key, "split route name is used more than once")
+			}
+			splitNames[route.RouteName()] = true
+		}
+	}
+}
+
+func validateTarget(route RouteConfig, pools map[string]bool) error {
+	if route.Action != "" {
+		return validateAction(route)
+	}
+
+	if len(route.Splits) == 0 {
+		if !pools[route.Pool] {
+			return fmt.Errorf("unknown pool %q", route.Pool)
+		}
+		return nil
+	}
+
+	if route.Pool != "" {
+		return errors.New("a route cannot have both a pool and weighted splits")
+	}
+	return ValidateSplitWeights(route.Splits, pools)
+}
+
+func validateAction(route RouteConfig) error {
+	if route.Pool != "" || len(route.Splits) > 0 || route.MirrorPool != "" {
+		return fmt.Errorf("a %s route cannot proxy to a pool", route.Action)
+	}
+
+	switch route.Action {
+	case ActionRedirect:
+		switch route.Status {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return fmt.Errorf("redirect status must be 301, 302, 307 or 308, got %d", route.Status)
+		}
+		if route.Location == "" {
+			return errors.New("redirect needs a location")
+		}
+	case ActionRespond:
+		if route.Status < 200 || route.Status > 599 {
+			return fmt.Errorf("response status must be between 200 and 599, got %d", route.Status)
+		}
+		if route.Body != "" && route.BodyFile != "" {
+			return errors.New("a response cannot have both a body and a body file")
+		}
+		if route.BodyFile != "" {
+			if _, err := os.Stat(route.BodyFile); err != nil {
+				return fmt.Errorf("response body file: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown action %q", route.Action)
+	}
+	return nil
+}
+
+func ValidateSplitWeights(splits []WeightedPool, pools map[string]bool) error {
+	total := 0
+	used := make(map[string]bool)
+	for _, split := range splits {
+		if !pools[split.Pool] {
+			return fmt.Errorf("unknown pool %q", split.Pool)
+		}
+		if used[split.Pool] {
+			return fmt.Errorf("pool %q appears more than once in the split", split.Pool)
+		}
+		used[split.Pool] = true
+		if split.Weight < 0 {
+			return fmt.Errorf("weight for pool %q cannot be negative", split.Pool)
+		}
+		total += split.Weight
+	}
+	if total == 0 {
+		return errors.New("split weights must add up to more than zero")
+	}
+	return nil
+}
+
+func validateRewrite(route RouteConfig) error {
+	if route.StripPrefix != "" && !strings.HasPrefix(route.StripPrefix, "/") {
+		return fmt.Errorf("strip prefix %q must start with /", route.StripPrefix)
+	}
+	if route.PrefixRewrite != "" && !strings.HasPrefix(route.PrefixRewrite, "/") {
+		return fmt.Errorf("prefix rewrite %q must start with /", route.PrefixRewrite)
+	}
+	if route.RegexRewrite != "" {
+		if _, err := regexp.Compile(route.RegexRewrite); err != nil {
+			return fmt.Errorf("invalid rewrite regex: %w", err)
+		}
+	}
+	for _, rules := range []HeaderRules{route.RequestHeaders, route.ResponseHeaders} {
+		for _, header := range append(rules.Set, rules.Add...) {
+			if header.Name == "" {
+				return errors.New("header rules need a header name")
+			}
+		}
+		for _, name := range rules.Remove {
+			if name == "" {
+				return errors.New("header rules need a header name")
+			}
+		}
+	}
+	return nil
+}
+
+func validateConditions(route RouteConfig) error {
+	for _, method := range route.Methods {
+		if method == "" {
+			return errors.New("empty method")
+		}
+	}
+	for _, match := range append(append([]ValueMatch(nil), route.Headers...), route.Queries...) {
+		if match.Name == "" {
+			return errors.New("header and query conditions need a name")
+		}
+		if match.Regex != "" {
+			if _, err := regexp.Compile(match.Regex); err != nil {
+				return fmt.Errorf("invalid regular expression for %q: %w", match.Name, err)
+			}
+		}
+	}
+	for _, cidr := range route.SourceCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// tracesPath is where an OTLP/HTTP collector receives spans.
+const tracesPath = "/v1/traces"
+
+// TracingConfig sends spans of proxied requests to an OpenTelemetry collector
+// over OTLP/HTTP. Tracing is off when Endpoint is empty.
+//
+// SampleRatio is the share of new traces that are recorded. Requests that carry
+// a traceparent follow the sampling decision of their caller.
+type TracingConfig struct {
+	Endpoint    string
+	SampleRatio float64
+	ServiceName string
+}
+
+func (t TracingConfig) Enabled() bool {
+	return t.Endpoint != ""
+}
+
+func tracingFromEnv(file FileTracing, errs *problems) TracingConfig {
+	tracing := TracingConfig{
+		Endpoint:    getEnv("TRACING_ENDPOINT", file.Endpoint),
+		SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", file.SampleRatio, errs),
+		ServiceName: getEnv("TRACING_SERVICE_NAME", file.ServiceName),
+	}
+	// A collector address such as http://collector:4318 gets the standard path.
+	if u, err := url.Parse(tracing.Endpoint); err == nil && tracing.Endpoint != "" && strings.Trim(u.Path, "/") == "" {
+		u.Path = tracesPath
+		tracing.Endpoint = u.String()
+	}
+	return tracing
+}
+
+func (t TracingConfig) validate(errs *problems) {
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs.addf("TRACING_SAMPLE_RATIO", "must be between 0 and 1, got %g", t.SampleRatio)
+	}
+	if !t.Enabled() {
+		return
+	}
+	if u, err := url.Parse(t.Endpoint); err != nil {
+		errs.addf("TRACING_ENDPOINT", "invalid URL %q: %w", t.Endpoint, unwrapURLError(err))
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.addf("TRACING_ENDPOINT", "%q needs an http or https scheme and a host", t.Endpoint)
+	}
+	if t.ServiceName == "" {
+		errs.addf("TRACING_SERVICE_NAME", "cannot be empty")
+	}
+}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"routing-api/internal/logger"
+)
+
+var (
+	balancerTypes    = []string{"round-robin", "least-connections"}
+	backendProtocols = []string{"http1", "http2"}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+	logFormats       = []string{logger.FormatJSON, logger.FormatConsole}
+)
+
+// poolEnvSettings are the suffixes accepted after POOL_<NAME>_.
+var poolEnvSettings = []string{
+	"APIS", "BALANCER_TYPE", "BACKEND_PROTOCOL", "REQUIRED", "HEALTH_CHECK_INTERVAL",
+	"HEALTH_CHECK_PATH", "MAX_FAILURES", "RESET_TIMEOUT", "SLOW_THRESHOLD",
+	"MAX_SLOW_COUNT", "CONNECT_TIMEOUT", "RESPONSE_TIMEOUT", "DISCOVERY_FILE",
+	"DISCOVERY_INTERVAL", "DISCOVERY_DNS", "DISCOVERY_DNS_RECORD", "DISCOVERY_DNS_SERVER",
+	"DISCOVERY_PORT", "DISCOVERY_SCHEME", "DISCOVERY_MIN_REFRESH", "DISCOVERY_MAX_REFRESH",
+	"DISCOVERY_CONSUL", "DISCOVERY_CONSUL_SERVICE", "DISCOVERY_CONSUL_TAG", "DISCOVERY_CONSUL_DATACENTER",
+	"DISCOVERY_CONSUL_TOKEN", "DISCOVERY_CONSUL_WAIT",
+}
+
+// ValidationError lists every problem found in a configuration. Each problem
+// starts with the environment variable or the route, pool or host it concerns.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problems:", len(e.Problems))
+	for _, problem := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(problem.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// problems collects configuration errors so they can be reported together.
+// Keys whose value could not be parsed are not checked again.
+type problems struct {
+	errs     []error
+	unparsed map[string]bool
+}
+
+func (p *problems) addf(key, format string, args ...any) {
+	if p.unparsed[key] {
+		return
+	}
+	p.errs = append(p.errs, fmt.Errorf("%s: %w", key, fmt.Errorf(format, args...)))
+}
+
+func (p *problems) invalid(key, value string, err error) {
+	if p.unparsed == nil {
+		p.unparsed = make(map[string]bool)
+	}
+	p.errs = append(p.errs, fmt.Errorf("%s: invalid value %q: %w", key, value, unwrapNumError(err)))
+	p.unparsed[key] = true
+}
+
+func (p *problems) err() error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p.errs}
+}
+
+func unwrapNumError(err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return numErr.Err
+	}
+	return err
+}
+
+func (c *Config) Validate() error {
+	var errs problems
+	c.validate(&errs)
+	return errs.err()
+}
+
+func (c *Config) validate(errs *problems) {
+	if c.Port == "" {
+		errs.addf("PORT", "port cannot be empty")
+	} else if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs.addf("PORT", "%q is not a port number", c.Port)
+	}
+	c.validateLogging(errs)
+
+	if len(c.Pools) == 0 {
+		errs.addf("APPLICATION_APIS", "at least one application API must be configured")
+	}
+
+	checkOneOf(errs, "BALANCER_TYPE", c.BalancerType, balancerTypes)
+	checkOneOf(errs, "BACKEND_PROTOCOL", c.BackendProtocol, backendProtocols)
+	checkPositive(errs, "HEALTH_CHECK_INTERVAL", c.HealthCheckInterval)
+	checkPath(errs, "HEALTH_CHECK_PATH", c.HealthCheckPath)
+
+	if c.MaxFailures <= 0 {
+		errs.addf("MAX_FAILURES", "must be greater than zero, got %d", c.MaxFailures)
+	}
+	checkPositive(errs, "CIRCUIT_TIMEOUT", c.CircuitTimeout)
+	checkPositive(errs, "RESET_TIMEOUT", c.ResetTimeout)
+	checkNotNegative(errs, "SLOW_THRESHOLD", c.SlowThreshold)
+	if c.MaxSlowCount < 0 {
+		errs.addf("MAX_SLOW_COUNT", "cannot be negative, got %d", c.MaxSlowCount)
+	}
+
+	checkPositive(errs, "REQUEST_TIMEOUT", c.RequestTimeout)
+	c.checkTimeouts(errs, "", c.ConnectTimeout, c.ResponseTimeout)
+	checkNotNegative(errs, "UPGRADE_IDLE_TIMEOUT", c.UpgradeIdleTimeout)
+	checkNotNegative(errs, "UPGRADE_MAX_LIFETIME", c.UpgradeMaxLifetime)
+	checkNotNegative(errs, "SHUTDOWN_DELAY", c.ShutdownDelay)
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs.addf("TLS_CERT_FILE", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	c.validateListeners(errs)
+	c.validateAdminAndMetrics(errs)
+	c.Tracing.validate(errs)
+	c.AccessLog.validate(errs)
+	c.validatePools(errs)
+	c.validateRoutes(errs)
+}
+
+func (c *Config) validateListeners(errs *problems) {
+	addresses := make(map[string]bool)
+	for _, listener := range c.Listeners {
+		key := fmt.Sprintf("listener %q", listener.Address)
+		if _, _, err := net.SplitHostPort(listener.Address); err != nil {
+			errs.addf(key, "invalid address: %w", unwrapAddrError(err))
+		}
+		if addresses[listener.Address] {
+			errs.addf(key, "address is used by more than one listener")
+		}
+		addresses[listener.Address] = true
+
+		if (listener.TLSCertFile == "") != (listener.TLSKeyFile == "") {
+			errs.addf(key, "needs both a TLS certificate and key")
+			continue
+		}
+		for _, file := range []string{listener.TLSCertFile, listener.TLSKeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				errs.addf(key, "TLS file: %w", err)
+			}
+		}
+	}
+}
+
+func (c *Config) validateAdminAndMetrics(errs *problems) {
+	c.validateSideListener(errs, "ADMIN_ADDRESS", c.AdminAddress)
+	c.validateSideListener(errs, "METRICS_ADDRESS", c.MetricsAddress)
+	if c.AdminAddress != "" && overlaps(c.AdminAddress, c.MetricsAddress) {
+		errs.addf("METRICS_ADDRESS", "%s overlaps ADMIN_ADDRESS %s", c.MetricsAddress, c.AdminAddress)
+	}
+}
+
+// validateSideListener checks the address of a listener that serves something
+// other than proxied requests.
+func (c *Config) validateSideListener(errs *problems, key, address string) {
+	if address == "" {
+		return
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		errs.addf(key, "invalid address %q: %w", address, unwrapAddrError(err))
+		return
+	}
+	for _, listener := range c.Listeners {
+		if overlaps(listener.Address, address) {
+			errs.addf(key, "%s overlaps listener %s", address, listener.Address)
+		}
+	}
+}
+
+// overlaps reports whether two listen addresses would bind the same port, taking
+// an empty or unspecified host to mean every interface.
+func overlaps(a, b string) bool {
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil || portA != portB {
+		return false
+	}
+	return hostA == hostB || isWildcardHost(hostA) || isWildcardHost(hostB)
+}
+
+func isWildcardHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// validatePools checks each pool. Settings a pool inherits unchanged from the
+// global ones were already checked under their global key.
+func (c *Config) validatePools(errs *problems) {
+	for _, pool := range c.Pools {
+		prefix := "POOL_" + envName(pool.Name) + "_"
+		key := fmt.Sprintf("pool %q", pool.Name)
+		if pool.Name == "" {
+			errs.addf("POOLS", "pool names cannot be empty")
+		}
+
+		apis := make(map[string]bool)
+		for _, api := range pool.APIs {
+			if err := checkBackendURL(api); err != nil {
+				errs.addf(key, "backend %q: %w", api, err)
+			}
+			if apis[api] {
+				errs.addf(key, "backend %q is listed more than once", api)
+			}
+			apis[api] = true
+		}
+		for api, weight := range pool.Weights {
+			if !apis[api] {
+				errs.addf(key, "weight given for unknown backend %q", api)
+			}
+			if weight < 0 {
+				errs.addf(key, "weight for backend %q cannot be negative", api)
+			}
+			if weight > MaxWeight {
+				errs.addf(key, "weight for backend %q cannot be more than %d", api, MaxWeight)
+			}
+		}
+
+		if pool.BalancerType != c.BalancerType {
+			checkOneOf(errs, prefix+"BALANCER_TYPE", pool.BalancerType, balancerTypes)
+		}
+		if pool.BackendProtocol != c.BackendProtocol {
+			checkOneOf(errs, prefix+"BACKEND_PROTOCOL", pool.BackendProtocol, backendProtocols)
+		}
+		if pool.HealthCheckInterval != c.HealthCheckInterval {
+			checkPositive(errs, prefix+"HEALTH_CHECK_INTERVAL", pool.HealthCheckInterval)
+		}
+		if pool.HealthCheckPath != c.HealthCheckPath {
+			checkPath(errs, prefix+"HEALTH_CHECK_PATH", pool.HealthCheckPath)
+		}
+		if pool.MaxFailures != c.MaxFailures && pool.MaxFailures <= 0 {
+			errs.addf(prefix+"MAX_FAILURES", "must be greater than zero, got %d", pool.MaxFailures)
+		}
+		if pool.ResetTimeout != c.ResetTimeout {
+			checkPositive(errs, prefix+"RESET_TIMEOUT", pool.ResetTimeout)
+		}
+		if pool.SlowThreshold != c.SlowThreshold {
+			checkNotNegative(errs, prefix+"SLOW_THRESHOLD", pool.SlowThreshold)
+		}
+		if pool.MaxSlowCount != c.MaxSlowCount && pool.MaxSlowCount < 0 {
+			errs.addf(prefix+"MAX_SLOW_COUNT", "cannot be negative, got %d", pool.MaxSlowCount)
+		}
+		if pool.ConnectTimeout != c.ConnectTimeout || pool.ResponseTimeout != c.ResponseTimeout {
+			c.checkTimeouts(errs, prefix, pool.ConnectTimeout, pool.ResponseTimeout)
+		}
+		pool.Discovery.validate(errs, prefix)
+	}
+}
+
+// checkTimeouts checks that connecting and waiting for a response both end
+// before the request as a whole times out.
+func (c *Config) checkTimeouts(errs *problems, prefix string, connect, response time.Duration) {
+	checkPositive(errs, prefix+"CONNECT_TIMEOUT", connect)
+	checkPositive(errs, prefix+"RESPONSE_TIMEOUT", response)
+	if c.RequestTimeout <= 0 {
+		return
+	}
+	if connect >= c.RequestTimeout {
+		errs.addf(prefix+"CONNECT_TIMEOUT", "%s must be shorter than REQUEST_TIMEOUT (%s)", connect, c.RequestTimeout)
+	}
+	if response >= c.RequestTimeout {
+		errs.addf(prefix+"RESPONSE_TIMEOUT", "%s must be shorter than REQUEST_TIMEOUT (%s)", response, c.RequestTimeout)
+	}
+}
+
+// checkPoolEnv reports POOL_* variables that name no defined pool or setting,
+// which would otherwise be ignored.
+func checkPoolEnv(pools []PoolConfig, errs *problems) {
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, "POOL_") || knownPoolKey(key, pools) {
+			continue
+		}
+		errs.addf(key, "unknown variable: no pool or pool setting has this name")
+	}
+}
+
+func knownPoolKey(key string, pools []PoolConfig) bool {
+	for _, pool := range pools {
+		setting, found := strings.CutPrefix(key, "POOL_"+envName(pool.Name)+"_")
+		if !found {
+			continue
+		}
+		for _, known := range poolEnvSettings {
+			if setting == known {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkBackendURL(api string) error {
+	u, err := url.Parse(api)
+	if err != nil {
+		return unwrapURLError(err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("missing host")
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("cannot have a query or fragment")
+	}
+	return nil
+}
+
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+func unwrapAddrError(err error) error {
+	if addrErr, ok := err.(*net.AddrError); ok {
+		return fmt.Errorf("%s", addrErr.Err)
+	}
+	return err
+}
+
+func checkOneOf(errs *problems, key, value string, allowed []string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	errs.addf(key, "unknown value %q, expected one of %s", value, strings.Join(allowed, ", "))
+}
+
+func checkPositive(errs *problems, key string, value time.Duration) {
+	if value <= 0 {
+		errs.addf(key, "must be greater than zero, got %s", value)
+	}
+}
+
+func checkNotNegative(errs *problems, key string, value time.Duration) {
+	if value < 0 {
+		errs.addf(key, "cannot be negative, got %s", value)
+	}
+}
+
+func checkPath(errs *problems, key, path string) {
+	if !strings.HasPrefix(path, "/") {
+		errs.addf(key, "path %q must start with /", path)
+	}
+}
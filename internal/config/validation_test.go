@@ -3,8 +3,10 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_ValidationErrors(t *testing.T) {
@@ -58,6 +60,16 @@ func TestConfig_ValidationErrors(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "unknown BACKEND_PROTOCOL",
+			envVars: map[string]string{
+				"PORT":             "8080",
+				"APPLICATION_APIS": "http://localhost:8081",
+				"MAX_FAILURES":     "5",
+				"BACKEND_PROTOCOL": "spdy",
+			},
+			expectError: true,
+		},
 		{
 			name: "invalid MAX_FAILURES",
 			envVars: map[string]string{
@@ -103,12 +115,50 @@ func TestConfig_DefaultValues(t *testing.T) {
 	assert.Equal(t, "1m0s", cfg.ResetTimeout.String())
 }
 
+func TestConfig_ReportsAllProblems(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "8080")
+	os.Setenv("MAX_FAILURES", "five")
+	os.Setenv("API_1", "localhost:8081")
+	os.Setenv("API_2", "http://localhost:8082?debug=1")
+	os.Setenv("BALANCER_TYPE", "random")
+	os.Setenv("HEALTH_CHECK_INTERVAL", "5")
+	os.Setenv("REQUEST_TIMEOUT", "10s")
+	os.Setenv("RESPONSE_TIMEOUT", "20s")
+	os.Setenv("POOLS", "orders")
+	os.Setenv("POOL_ORDERS_APIS", "http://orders")
+	os.Setenv("POOL_ORDERS_MAX_SLOW_COUNT", "-1")
+	os.Setenv("POOL_ORDRES_APIS", "http://orders")
+	os.Setenv("HTTPS_REDIRECT", "yes")
+
+	_, err := Load()
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	var messages []string
+	for _, problem := range validationErr.Problems {
+		messages = append(messages, problem.Error())
+	}
+	assert.ElementsMatch(t, []string{
+		`MAX_FAILURES: invalid value "five": invalid syntax`,
+		`HEALTH_CHECK_INTERVAL: invalid value "5": not a duration, use a value like 30s or 500ms`,
+		`HTTPS_REDIRECT: invalid value "yes": invalid syntax`,
+		`POOL_ORDRES_APIS: unknown variable: no pool or pool setting has this name`,
+		`BALANCER_TYPE: unknown value "random", expected one of round-robin, least-connections`,
+		`RESPONSE_TIMEOUT: 20s must be shorter than REQUEST_TIMEOUT (10s)`,
+		`pool "default": backend "localhost:8081": scheme must be http or https`,
+		`pool "default": backend "http://localhost:8082?debug=1": cannot have a query or fragment`,
+		`POOL_ORDERS_MAX_SLOW_COUNT: cannot be negative, got -1`,
+	}, messages)
+}
+
 func TestGetEnvInt_EdgeCases(t *testing.T) {
 	tests := []struct {
 		name         string
 		envValue     string
 		defaultValue int
 		expected     int
+		expectError  bool
 	}{
 		{
 			name:         "valid integer",
@@ -121,6 +171,7 @@ func TestGetEnvInt_EdgeCases(t *testing.T) {
 			envValue:     "not-a-number",
 			defaultValue: 10,
 			expected:     10,
+			expectError:  true,
 		},
 		{
 			name:         "empty value",
@@ -143,8 +194,14 @@ func TestGetEnvInt_EdgeCases(t *testing.T) {
 				os.Setenv("TEST_INT", tt.envValue)
 			}
 
-			result := getEnvInt("TEST_INT", tt.defaultValue)
+			var errs problems
+			result := getEnvInt("TEST_INT", tt.defaultValue, &errs)
 			assert.Equal(t, tt.expected, result)
+			if tt.expectError {
+				assert.ErrorContains(t, errs.err(), "TEST_INT")
+			} else {
+				assert.NoError(t, errs.err())
+			}
 		})
 	}
 }
@@ -154,25 +211,27 @@ func TestGetEnvDuration_EdgeCases(t *testing.T) {
 		name         string
 		envValue     string
 		defaultValue string
+		expected     time.Duration
 		expectError  bool
 	}{
 		{
 			name:         "valid duration",
 			envValue:     "30s",
 			defaultValue: "10s",
-			expectError:  false,
+			expected:     30 * time.Second,
 		},
 		{
 			name:         "invalid duration",
 			envValue:     "invalid-duration",
 			defaultValue: "5s",
-			expectError:  false, // Should fall back to default
+			expected:     5 * time.Second,
+			expectError:  true,
 		},
 		{
 			name:         "empty value",
 			envValue:     "",
 			defaultValue: "1m",
-			expectError:  false,
+			expected:     time.Minute,
 		},
 	}
 
@@ -183,8 +242,90 @@ func TestGetEnvDuration_EdgeCases(t *testing.T) {
 				os.Setenv("TEST_DURATION", tt.envValue)
 			}
 
-			result := getEnvDuration("TEST_DURATION", tt.defaultValue)
-			assert.NotNil(t, result)
+			var errs problems
+			result := getEnvDuration("TEST_DURATION", tt.defaultValue, &errs)
+			assert.Equal(t, tt.expected, result)
+			if tt.expectError {
+				assert.ErrorContains(t, errs.err(), "TEST_DURATION")
+			} else {
+				assert.NoError(t, errs.err())
+			}
+		})
+	}
+}
+
+func TestConfig_AdminAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		expected string
+		problem  string
+	}{
+		{name: "default", expected: ""},
+		{name: "local", address: "127.0.0.1:9901", expected: "127.0.0.1:9901"},
+		{name: "disabled", address: "off", expected: ""},
+		{name: "custom", address: ":9000", expected: ":9000"},
+		{name: "invalid", address: "9000", problem: `ADMIN_ADDRESS: invalid address "9000": missing port in address`},
+		{name: "same port as listener", address: "127.0.0.1:8080", problem: "ADMIN_ADDRESS: 127.0.0.1:8080 overlaps listener :8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("PORT", "8080")
+			os.Setenv("MAX_FAILURES", "5")
+			os.Setenv("APPLICATION_APIS", "http://localhost:8081")
+			if tt.address != "" {
+				os.Setenv("ADMIN_ADDRESS", tt.address)
+			}
+
+			cfg, err := Load()
+			if tt.problem != "" {
+				assert.ErrorContains(t, err, tt.problem)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.AdminAddress)
+		})
+	}
+}
+
+func TestConfig_MetricsAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		admin    string
+		expected string
+		problem  string
+	}{
+		{name: "default", expected: ":9090"},
+		{name: "disabled", address: "off", expected: ""},
+		{name: "custom", address: "10.0.0.1:9100", expected: "10.0.0.1:9100"},
+		{name: "invalid", address: "9090", problem: `METRICS_ADDRESS: invalid address "9090": missing port in address`},
+		{name: "same port as listener", address: "0.0.0.0:8080", problem: "METRICS_ADDRESS: 0.0.0.0:8080 overlaps listener :8080"},
+		{name: "same port as admin API", address: ":9901", admin: "127.0.0.1:9901", problem: "METRICS_ADDRESS: :9901 overlaps ADMIN_ADDRESS 127.0.0.1:9901"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("PORT", "8080")
+			os.Setenv("MAX_FAILURES", "5")
+			os.Setenv("APPLICATION_APIS", "http://localhost:8081")
+			if tt.address != "" {
+				os.Setenv("METRICS_ADDRESS", tt.address)
+			}
+			if tt.admin != "" {
+				os.Setenv("ADMIN_ADDRESS", tt.admin)
+			}
+
+			cfg, err := Load()
+			if tt.problem != "" {
+				assert.ErrorContains(t, err, tt.problem)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.MetricsAddress)
 		})
 	}
 }
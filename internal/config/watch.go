@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WatchFile polls the file at path and sends on the returned channel whenever its
+// modification time or size changes. The channel is nil when path is empty.
+func WatchFile(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	if path == "" {
+		return nil
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last, _ := os.Stat(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := os.Stat(path)
+			if err != nil || !changed(last, current) {
+				continue
+			}
+			last = current
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes
+}
+
+func changed(last, current os.FileInfo) bool {
+	if last == nil {
+		return true
+	}
+	return !last.ModTime().Equal(current.ModTime()) || last.Size() != current.Size()
+}
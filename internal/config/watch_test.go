@@ -0,0 +1,36 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchFile(t *testing.T) {
+	assert.Nil(t, WatchFile(context.Background(), "", time.Millisecond))
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("log_level: info\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := WatchFile(ctx, path, 10*time.Millisecond)
+
+	select {
+	case <-changes:
+		t.Fatal("unchanged file reported as changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("log_level: debug\n"), 0o644))
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("change was not reported")
+	}
+}
@@ -0,0 +1,201 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// consulIndexHeader carries the index a blocking query waits on.
+	consulIndexHeader = "X-Consul-Index"
+	// consulMinInterval spaces out queries that return straight away, such as
+	// those to a registry that does not support blocking.
+	consulMinInterval = time.Second
+	// consulTimeoutSlack is added to the wait of a blocking query to allow for the
+	// jitter Consul adds and for the response to arrive.
+	consulTimeoutSlack = 10 * time.Second
+)
+
+// ConsulProvider makes a backend of every passing instance of a service in the
+// health API of Consul, or of any registry serving the same API. It uses
+// blocking queries, so a change is seen as soon as the registry has it rather
+// than at the next poll. Failed queries keep the last backends that were found.
+type ConsulProvider struct {
+	endpoint    string
+	token       string
+	scheme      string
+	wait        time.Duration
+	retry       time.Duration
+	minInterval time.Duration
+	client      *http.Client
+	logger      logger.Logger
+}
+
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Weights struct {
+			Passing int
+		}
+	}
+}
+
+func NewConsulProvider(discovery config.DiscoveryConfig, logger logger.Logger) *ConsulProvider {
+	query := url.Values{"passing": {"true"}}
+	if discovery.ConsulTag != "" {
+		query.Set("tag", discovery.ConsulTag)
+	}
+	if discovery.ConsulDatacenter != "" {
+		query.Set("dc", discovery.ConsulDatacenter)
+	}
+	endpoint := strings.TrimSuffix(discovery.ConsulAddress, "/") +
+		"/v1/health/service/" + url.PathEscape(discovery.ConsulService) + "?" + query.Encode()
+
+	return &ConsulProvider{
+		endpoint:    endpoint,
+		token:       discovery.ConsulToken,
+		scheme:      discovery.Scheme,
+		wait:        discovery.ConsulWait,
+		retry:       discovery.Interval,
+		minInterval: consulMinInterval,
+		client:      &http.Client{},
+		logger:      logger.With(zap.String("discovery_consul_service", discovery.ConsulService)),
+	}
+}
+
+func (p *ConsulProvider) Targets(ctx context.Context) ([]Target, error) {
+	targets, _, err := p.query(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("service has no passing instances")
+	}
+	return targets, nil
+}
+
+func (p *ConsulProvider) Watch(ctx context.Context, update func([]Target)) {
+	var index uint64
+	var last []Target
+	for {
+		started := time.Now()
+		delay := p.minInterval
+		targets, next, err := p.query(ctx, index)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			p.logger.Warn("Consul query failed, keeping the current backends", zap.Error(err))
+			delay = p.retry
+		default:
+			// An index that goes backwards means the registry's state was reset,
+			// so the next query must not wait on the old index.
+			if next < index {
+				next = 0
+			}
+			index = next
+			if len(targets) == 0 {
+				p.logger.Warn("Service has no passing instances, keeping the current backends")
+			} else if !slices.Equal(targets, last) {
+				last = targets
+				update(targets)
+			}
+		}
+
+		timer := time.NewTimer(delay - time.Since(started))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// query asks for the passing instances of the service, sorted by URL. With a
+// non-zero index it blocks until the instances change or the wait runs out, and
+// it returns the index to wait on next.
+func (p *ConsulProvider) query(ctx context.Context, index uint64) ([]Target, uint64, error) {
+	endpoint := p.endpoint
+	if index > 0 {
+		endpoint += "&index=" + strconv.FormatUint(index, 10) + "&wait=" + strconv.FormatInt(p.wait.Milliseconds(), 10) + "ms"
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.wait+p.wait/16+consulTimeoutSlack)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.token != "" {
+		req.Header.Set("X-Consul-Token", p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	next, err := strconv.ParseUint(resp.Header.Get(consulIndexHeader), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s header %q", consulIndexHeader, resp.Header.Get(consulIndexHeader))
+	}
+
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("cannot decode response: %w", err)
+	}
+	return p.targets(entries), next, nil
+}
+
+// targets turns service instances into backends. An instance without an address
+// of its own is reached at its node's address.
+func (p *ConsulProvider) targets(entries []consulEntry) []Target {
+	targets := make([]Target, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		if address == "" || entry.Service.Port <= 0 {
+			continue
+		}
+
+		backendURL := p.scheme + "://" + net.JoinHostPort(address, strconv.Itoa(entry.Service.Port))
+		if seen[backendURL] {
+			continue
+		}
+		seen[backendURL] = true
+
+		weight := entry.Service.Weights.Passing
+		if weight <= 0 {
+			weight = 1
+		}
+		targets = append(targets, Target{URL: backendURL, Weight: weight})
+	}
+	slices.SortFunc(targets, func(a, b Target) int { return strings.Compare(a.URL, b.URL) })
+	return scaleWeights(targets)
+}
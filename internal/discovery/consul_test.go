@@ -0,0 +1,188 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consulServer stands in for the health API of a Consul agent, including its
+// blocking queries.
+type consulServer struct {
+	mutex   sync.Mutex
+	index   uint64
+	entries []map[string]any
+	status  int
+	changed chan struct{}
+	queries []*http.Request
+}
+
+func newConsulServer(t *testing.T) (*consulServer, *httptest.Server) {
+	consul := &consulServer{index: 1, changed: make(chan struct{})}
+	server := httptest.NewServer(http.HandlerFunc(consul.serveHTTP))
+	t.Cleanup(server.Close)
+	return consul, server
+}
+
+func (c *consulServer) set(status int, entries ...map[string]any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.index++
+	c.status = status
+	c.entries = entries
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *consulServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	c.queries = append(c.queries, r)
+	index, changed := c.index, c.changed
+	c.mutex.Unlock()
+
+	if waitIndex, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); waitIndex == index {
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.status != 0 && c.status != http.StatusOK {
+		http.Error(w, "rpc error", c.status)
+		return
+	}
+	w.Header().Set(consulIndexHeader, strconv.FormatUint(c.index, 10))
+	json.NewEncoder(w).Encode(append([]map[string]any{}, c.entries...))
+}
+
+func (c *consulServer) lastQuery() *http.Request {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.queries[len(c.queries)-1]
+}
+
+func instance(nodeAddress, address string, port, weight int) map[string]any {
+	return map[string]any{
+		"Node":    map[string]any{"Address": nodeAddress},
+		"Service": map[string]any{"Address": address, "Port": port, "Weights": map[string]any{"Passing": weight}},
+	}
+}
+
+func newTestConsulProvider(address string) *ConsulProvider {
+	provider := NewConsulProvider(config.DiscoveryConfig{
+		ConsulAddress:    address,
+		ConsulService:    "orders api",
+		ConsulTag:        "v2",
+		ConsulDatacenter: "eu1",
+		ConsulToken:      "secret",
+		ConsulWait:       time.Second,
+		Interval:         20 * time.Millisecond,
+		Scheme:           "http",
+	}, &testLogger{})
+	provider.minInterval = 0
+	return provider
+}
+
+func TestConsulProvider_Targets(t *testing.T) {
+	consul, server := newConsulServer(t)
+	consul.set(http.StatusOK,
+		instance("10.0.0.1", "", 8080, 0),
+		instance("10.0.0.9", "10.0.0.2", 8080, 3),
+		instance("10.0.0.9", "10.0.0.2", 8080, 3),
+		instance("10.0.0.3", "", 0, 1),
+	)
+	provider := newTestConsulProvider(server.URL + "/")
+
+	targets, err := provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{
+		{URL: "http://10.0.0.1:8080", Weight: 1},
+		{URL: "http://10.0.0.2:8080", Weight: 3},
+	}, targets)
+
+	query := consul.lastQuery()
+	assert.Equal(t, "/v1/health/service/orders%20api", query.URL.EscapedPath())
+	assert.Equal(t, "true", query.URL.Query().Get("passing"))
+	assert.Equal(t, "v2", query.URL.Query().Get("tag"))
+	assert.Equal(t, "eu1", query.URL.Query().Get("dc"))
+	assert.Equal(t, "secret", query.Header.Get("X-Consul-Token"))
+
+	consul.set(http.StatusOK)
+	_, err = provider.Targets(context.Background())
+	assert.ErrorContains(t, err, "no passing instances")
+
+	consul.set(http.StatusForbidden)
+	_, err = provider.Targets(context.Background())
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestConsulProvider_Watch(t *testing.T) {
+	consul, server := newConsulServer(t)
+	consul.set(http.StatusOK, instance("10.0.0.1", "", 8080, 1))
+	provider := newTestConsulProvider(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan []Target, 10)
+	go provider.Watch(ctx, func(targets []Target) { updates <- targets })
+
+	next := func() []Target {
+		select {
+		case targets := <-updates:
+			return targets
+		case <-time.After(2 * time.Second):
+			t.Fatal("no update")
+			return nil
+		}
+	}
+	assert.Equal(t, []Target{{URL: "http://10.0.0.1:8080", Weight: 1}}, next())
+
+	// The next query blocks on the index of the last answer.
+	assert.Eventually(t, func() bool {
+		return consul.lastQuery().URL.Query().Get("index") == "2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "1000ms", consul.lastQuery().URL.Query().Get("wait"))
+
+	consul.set(http.StatusOK, instance("10.0.0.1", "", 8080, 1), instance("10.0.0.2", "", 8080, 1))
+	assert.Equal(t, []Target{
+		{URL: "http://10.0.0.1:8080", Weight: 1},
+		{URL: "http://10.0.0.2:8080", Weight: 1},
+	}, next())
+
+	consul.set(http.StatusInternalServerError)
+	time.Sleep(50 * time.Millisecond)
+	consul.set(http.StatusOK)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, updates)
+
+	consul.set(http.StatusOK, instance("10.0.0.3", "", 9090, 2))
+	assert.Equal(t, []Target{{URL: "http://10.0.0.3:9090", Weight: 2}}, next())
+}
+
+func TestNew_Registry(t *testing.T) {
+	assert.Nil(t, New(config.DiscoveryConfig{}, &testLogger{}))
+	assert.IsType(t, &ConsulProvider{}, New(config.DiscoveryConfig{ConsulAddress: "http://127.0.0.1:8500"}, &testLogger{}))
+
+	custom := NewFileProvider("custom.yaml", time.Second, &testLogger{})
+	Register(config.DiscoveryFile, func(config.DiscoveryConfig, logger.Logger) Provider { return custom })
+	defer Register(config.DiscoveryFile, func(discovery config.DiscoveryConfig, logger logger.Logger) Provider {
+		return NewFileProvider(discovery.File, discovery.Interval, logger)
+	})
+	assert.Same(t, custom, New(config.DiscoveryConfig{File: "backends.yaml"}, &testLogger{}))
+}
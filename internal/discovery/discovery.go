@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Target is a backend found by discovery.
+type Target struct {
+	URL    string
+	Weight int
+}
+
+// Provider finds the backends of a pool.
+type Provider interface {
+	// Targets returns the backends known now.
+	Targets(ctx context.Context) ([]Target, error)
+	// Watch calls update with the backends whenever they change, until ctx is
+	// done. Lookups that fail are logged and the last known backends are kept.
+	Watch(ctx context.Context, update func([]Target))
+}
+
+// Factory builds a provider from a pool's discovery settings.
+type Factory func(discovery config.DiscoveryConfig, logger logger.Logger) Provider
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{
+		config.DiscoveryFile: func(discovery config.DiscoveryConfig, logger logger.Logger) Provider {
+			return NewFileProvider(discovery.File, discovery.Interval, logger)
+		},
+		config.DiscoveryDNS: func(discovery config.DiscoveryConfig, logger logger.Logger) Provider {
+			return NewDNSProvider(discovery, logger)
+		},
+		config.DiscoveryConsul: func(discovery config.DiscoveryConfig, logger logger.Logger) Provider {
+			return NewConsulProvider(discovery, logger)
+		},
+	}
+)
+
+// Register makes New use factory for the pools whose discovery settings name
+// source, replacing any factory registered for it before.
+func Register(source string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+	factories[source] = factory
+}
+
+// New returns the provider for a pool's discovery settings, or nil when the pool
+// has a fixed list of backends.
+func New(discovery config.DiscoveryConfig, logger logger.Logger) Provider {
+	source := discovery.Source()
+	if source == "" {
+		return nil
+	}
+
+	factoriesMutex.RLock()
+	factory, ok := factories[source]
+	factoriesMutex.RUnlock()
+	if !ok {
+		logger.Error("No discovery provider is registered for source", zap.String("source", source))
+		return nil
+	}
+	return factory(discovery, logger)
+}
+
+// target is a backend as written in a discovery document: a URL, or a mapping
+// with a url and a weight.
+type target struct {
+	URL    string `yaml:"url"`
+	Weight *int   `yaml:"weight"`
+}
+
+func (t *target) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&t.URL)
+	}
+	type plain target
+	return node.Decode((*plain)(t))
+}
+
+// ParseTargets reads a YAML or JSON list of backends, given either at the top
+// level or under a "backends" key.
+func ParseTargets(data []byte) ([]Target, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	var entries []target
+	if len(document.Content) > 0 && document.Content[0].Kind == yaml.MappingNode {
+		var wrapped struct {
+			Backends []target `yaml:"backends"`
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&wrapped); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		entries = wrapped.Backends
+	} else if err := document.Decode(&entries); err != nil && len(document.Content) > 0 {
+		return nil, err
+	}
+
+	targets := make([]Target, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if err := checkURL(entry.URL); err != nil {
+			return nil, fmt.Errorf("backend %q: %w", entry.URL, err)
+		}
+		if seen[entry.URL] {
+			return nil, fmt.Errorf("backend %q is listed more than once", entry.URL)
+		}
+		seen[entry.URL] = true
+
+		weight := 1
+		if entry.Weight != nil {
+			weight = *entry.Weight
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("backend %q: weight cannot be negative", entry.URL)
+		}
+		if weight > config.MaxWeight {
+			return nil, fmt.Errorf("backend %q: weight cannot be more than %d", entry.URL, config.MaxWeight)
+		}
+		targets = append(targets, Target{URL: entry.URL, Weight: weight})
+	}
+	return targets, nil
+}
+
+// scaleWeights brings the weights of targets down to config.MaxWeight at most,
+// keeping their proportions. Backends that had a weight keep one of at least 1.
+func scaleWeights(targets []Target) []Target {
+	heaviest := 0
+	for _, target := range targets {
+		heaviest = max(heaviest, target.Weight)
+	}
+	if heaviest <= config.MaxWeight {
+		return targets
+	}
+	for i, target := range targets {
+		if target.Weight > 0 {
+			targets[i].Weight = max(1, target.Weight*config.MaxWeight/heaviest)
+		}
+	}
+	return targets
+}
+
+func checkURL(backendURL string) error {
+	u, err := url.Parse(backendURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("needs an http or https scheme and a host")
+	}
+	return nil
+}
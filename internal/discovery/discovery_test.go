@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"testing"
+
+	"routing-api/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testLogger struct{}
+
+func (l *testLogger) Debug(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Info(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Warn(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Error(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Fatal(msg string, fields ...zap.Field)  {}
+func (l *testLogger) With(fields ...zap.Field) logger.Logger { return l }
+func (l *testLogger) Sync() error                            { return nil }
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []Target
+		err      string
+	}{
+		{
+			name:     "YAML list",
+			data:     "- http://10.0.0.1:8080\n- url: http://10.0.0.2:8080\n  weight: 3\n",
+			expected: []Target{{URL: "http://10.0.0.1:8080", Weight: 1}, {URL: "http://10.0.0.2:8080", Weight: 3}},
+		},
+		{
+			name:     "JSON under backends",
+			data:     `{"backends": ["http://10.0.0.1:8080", {"url": "https://10.0.0.2", "weight": 0}]}`,
+			expected: []Target{{URL: "http://10.0.0.1:8080", Weight: 1}, {URL: "https://10.0.0.2", Weight: 0}},
+		},
+		{
+			name:     "empty",
+			data:     "",
+			expected: []Target{},
+		},
+		{
+			name: "unknown key",
+			data: `{"servers": ["http://10.0.0.1:8080"]}`,
+			err:  "field servers not found",
+		},
+		{
+			name: "invalid URL",
+			data: `["10.0.0.1:8080"]`,
+			err:  `backend "10.0.0.1:8080"`,
+		},
+		{
+			name: "duplicate",
+			data: `["http://10.0.0.1", "http://10.0.0.1"]`,
+			err:  "listed more than once",
+		},
+		{
+			name: "negative weight",
+			data: `[{"url": "http://10.0.0.1", "weight": -1}]`,
+			err:  "weight cannot be negative",
+		},
+		{
+			name: "weight too large",
+			data: `[{"url": "http://10.0.0.1", "weight": 1001}]`,
+			err:  "weight cannot be more than 1000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := ParseTargets([]byte(tt.data))
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, targets)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package discovery
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// DNSProvider makes a backend of every address a DNS name resolves to. A and
+// AAAA records are combined with a fixed port; SRV records carry their own port
+// and weight, and only the records with the lowest priority whose targets
+// resolve are used, so those with a higher priority take over once the lower
+// ones are withdrawn or gone.
+//
+// Without a configured server, the name servers of /etc/resolv.conf are tried
+// in turn, and its search domains apply to relative names.
+//
+// Names are looked up again when their records expire, within the configured
+// bounds. Failed lookups keep the last backends that were found.
+type DNSProvider struct {
+	name       string
+	names      []string
+	record     string
+	scheme     string
+	port       int
+	servers    []string
+	minRefresh time.Duration
+	maxRefresh time.Duration
+	logger     logger.Logger
+	lookup     func(ctx context.Context, server, name string, qtype uint16) ([]dnsRecord, error)
+}
+
+const resolvConfPath = "/etc/resolv.conf"
+
+func NewDNSProvider(discovery config.DiscoveryConfig, logger logger.Logger) *DNSProvider {
+	conf := readResolvConf(resolvConfPath)
+	if discovery.DNSServer != "" {
+		conf.servers = []string{discovery.DNSServer}
+	}
+	return &DNSProvider{
+		name:       discovery.DNSName,
+		names:      conf.names(discovery.DNSName),
+		record:     discovery.DNSRecord,
+		scheme:     discovery.Scheme,
+		port:       discovery.Port,
+		servers:    conf.servers,
+		minRefresh: discovery.MinRefresh,
+		maxRefresh: discovery.MaxRefresh,
+		logger:     logger.With(zap.String("discovery_dns", discovery.DNSName)),
+		lookup:     lookup,
+	}
+}
+
+func (p *DNSProvider) Targets(ctx context.Context) ([]Target, error) {
+	targets, _, err := p.resolve(ctx)
+	return targets, err
+}
+
+func (p *DNSProvider) Watch(ctx context.Context, update func([]Target)) {
+	var last []Target
+	for {
+		delay := p.minRefresh
+		targets, ttl, err := p.resolve(ctx)
+		if err != nil {
+			p.logger.Warn("DNS lookup failed, keeping the current backends", zap.Error(err))
+		} else {
+			delay = min(max(ttl, p.minRefresh), p.maxRefresh)
+			if !slices.Equal(targets, last) {
+				last = targets
+				update(targets)
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// resolve returns the backends the name points to, sorted by URL, and how long
+// the answer may be cached.
+func (p *DNSProvider) resolve(ctx context.Context) ([]Target, time.Duration, error) {
+	var targets []Target
+	var ttl time.Duration
+	var err error
+	if p.record == config.DNSRecordSRV {
+		targets, ttl, err = p.resolveSRV(ctx)
+	} else {
+		targets, ttl, err = p.resolveAddresses(ctx)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(targets) == 0 {
+		return nil, 0, fmt.Errorf("%s has no usable records", p.name)
+	}
+	slices.SortFunc(targets, func(a, b Target) int { return strings.Compare(a.URL, b.URL) })
+	return targets, ttl, nil
+}
+
+func (p *DNSProvider) resolveAddresses(ctx context.Context) ([]Target, time.Duration, error) {
+	records, err := p.search(ctx, typeA, typeAAAA)
+	if err != nil && !errors.Is(err, errNoSuchHost) {
+		return nil, 0, err
+	}
+
+	port := strconv.Itoa(p.port)
+	targets := make([]Target, 0, len(records))
+	for _, record := range records {
+		targets = append(targets, Target{URL: p.scheme + "://" + net.JoinHostPort(record.IP.String(), port), Weight: 1})
+	}
+	return targets, minTTL(records), nil
+}
+
+func (p *DNSProvider) resolveSRV(ctx context.Context) ([]Target, time.Duration, error) {
+	records, err := p.search(ctx, typeSRV)
+	if errors.Is(err, errNoSuchHost) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// A target of "." means the service is not available at this name.
+	records = slices.DeleteFunc(records, func(r dnsRecord) bool { return r.SRV.Target == "" })
+	if len(records) == 0 {
+		return nil, 0, nil
+	}
+	records = p.usablePriority(ctx, records)
+
+	// Weight 0 takes a backend out of rotation here, while in DNS it only makes
+	// it unlikely to be picked; when every weight is 0 they are treated as equal.
+	allZero := !slices.ContainsFunc(records, func(r dnsRecord) bool { return r.SRV.Weight > 0 })
+	targets := make([]Target, 0, len(records))
+	for _, record := range records {
+		weight := int(record.SRV.Weight)
+		if allZero {
+			weight = 1
+		}
+		host := net.JoinHostPort(record.SRV.Target, strconv.Itoa(int(record.SRV.Port)))
+		targets = append(targets, Target{URL: p.scheme + "://" + host, Weight: weight})
+	}
+	return scaleWeights(targets), minTTL(records), nil
+}
+
+// usablePriority returns the records with the lowest priority that has a target
+// which resolves, or with the highest priority when none does. Targets whose
+// lookup fails for other reasons count as resolving, so a flaky server does not
+// move traffic to the backup backends.
+func (p *DNSProvider) usablePriority(ctx context.Context, records []dnsRecord) []dnsRecord {
+	slices.SortStableFunc(records, func(a, b dnsRecord) int { return cmp.Compare(a.SRV.Priority, b.SRV.Priority) })
+	for start := 0; start < len(records); {
+		end := start
+		for end < len(records) && records[end].SRV.Priority == records[start].SRV.Priority {
+			end++
+		}
+		group := records[start:end]
+		if end == len(records) || slices.ContainsFunc(group, func(r dnsRecord) bool { return p.resolves(ctx, r.SRV.Target) }) {
+			return group
+		}
+		start = end
+	}
+	return nil
+}
+
+func (p *DNSProvider) resolves(ctx context.Context, host string) bool {
+	var err error
+	for _, qtype := range []uint16{typeA, typeAAAA} {
+		var records []dnsRecord
+		if records, err = p.ask(ctx, host+".", qtype); len(records) > 0 {
+			return true
+		}
+		if err != nil && !errors.Is(err, errNoSuchHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// search looks up the records of the given types for the first of the
+// provider's names that has any.
+func (p *DNSProvider) search(ctx context.Context, qtypes ...uint16) ([]dnsRecord, error) {
+	lastErr := errNoSuchHost
+	for _, name := range p.names {
+		var records []dnsRecord
+		var errs []error
+		for _, qtype := range qtypes {
+			found, err := p.ask(ctx, name, qtype)
+			if err != nil && !errors.Is(err, errNoSuchHost) {
+				errs = append(errs, err)
+			}
+			records = append(records, found...)
+		}
+		if len(records) > 0 {
+			return records, nil
+		}
+		if len(errs) > 0 {
+			lastErr = errors.Join(errs...)
+		}
+	}
+	return nil, lastErr
+}
+
+// ask sends a query to each server in turn until one answers. A server saying
+// the name does not exist is an answer.
+func (p *DNSProvider) ask(ctx context.Context, name string, qtype uint16) ([]dnsRecord, error) {
+	var errs []error
+	for _, server := range p.servers {
+		records, err := p.lookup(ctx, server, name, qtype)
+		if err == nil || errors.Is(err, errNoSuchHost) {
+			return records, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func minTTL(records []dnsRecord) time.Duration {
+	if len(records) == 0 {
+		return 0
+	}
+	return slices.MinFunc(records, func(a, b dnsRecord) int { return cmp.Compare(a.TTL, b.TTL) }).TTL
+}
@@ -0,0 +1,301 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A small DNS client. The resolver in the standard library hides record TTLs,
+// which DNSProvider needs to know when to look again.
+
+const (
+	typeA    uint16 = 1
+	typeAAAA uint16 = 28
+	typeSRV  uint16 = 33
+	typeOPT  uint16 = 41
+	classIN  uint16 = 1
+
+	dnsTimeout    = 5 * time.Second
+	maxUDPPayload = 4096
+)
+
+var errNoSuchHost = errors.New("no such host")
+
+type dnsRecord struct {
+	Type uint16
+	TTL  time.Duration
+	IP   net.IP
+	SRV  srvRecord
+}
+
+type srvRecord struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// resolvConf holds the settings of /etc/resolv.conf that apply to lookups.
+type resolvConf struct {
+	servers []string
+	search  []string
+	ndots   int
+}
+
+// readResolvConf reads the name servers, search domains and ndots option of a
+// resolv.conf file, falling back to a local server when it has none.
+func readResolvConf(path string) resolvConf {
+	conf := resolvConf{ndots: 1}
+	if file, err := os.Open(path); err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "nameserver":
+				conf.servers = append(conf.servers, net.JoinHostPort(fields[1], "53"))
+			case "domain", "search":
+				// Whichever comes last wins, as with the system resolver.
+				conf.search = fields[1:]
+			case "options":
+				for _, option := range fields[1:] {
+					if value, ok := strings.CutPrefix(option, "ndots:"); ok {
+						if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+							conf.ndots = min(n, 15)
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(conf.servers) == 0 {
+		conf.servers = []string{"127.0.0.1:53"}
+	}
+	return conf
+}
+
+// names returns the names to try for name in order. A name ending in a dot is
+// used as it is; other names are also tried under each search domain, after the
+// name itself when it has at least ndots dots and before it otherwise.
+func (c resolvConf) names(name string) []string {
+	if strings.HasSuffix(name, ".") || len(c.search) == 0 {
+		return []string{name}
+	}
+	names := make([]string, 0, len(c.search)+1)
+	for _, domain := range c.search {
+		names = append(names, name+"."+strings.Trim(domain, "."))
+	}
+	if strings.Count(name, ".") >= c.ndots {
+		return append([]string{name}, names...)
+	}
+	return append(names, name)
+}
+
+// lookup asks server for the records of name with the given type, over UDP and
+// again over TCP when the answer is truncated.
+func lookup(ctx context.Context, server, name string, qtype uint16) ([]dnsRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	id := uint16(rand.UintN(1 << 16))
+	query, err := buildQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := exchange(ctx, "udp", server, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) >= 4 && response[2]&0x02 != 0 {
+		if response, err = exchange(ctx, "tcp", server, query); err != nil {
+			return nil, err
+		}
+	}
+	return parseResponse(response, id, qtype)
+}
+
+func exchange(ctx context.Context, network, server string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		response := make([]byte, maxUDPPayload)
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, err
+		}
+		return response[:n], nil
+	}
+
+	message := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(message, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// buildQuery encodes a recursive query for one question, advertising a larger
+// UDP payload with EDNS0 so answers with many records need not fall back to TCP.
+func buildQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	message := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(message[0:], id)
+	binary.BigEndian.PutUint16(message[2:], 0x0100) // recursion desired
+	binary.BigEndian.PutUint16(message[4:], 1)      // questions
+	binary.BigEndian.PutUint16(message[10:], 1)     // additional records
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid DNS name %q", name)
+		}
+		message = append(message, byte(len(label)))
+		message = append(message, label...)
+	}
+	message = append(message, 0)
+	message = binary.BigEndian.AppendUint16(message, qtype)
+	message = binary.BigEndian.AppendUint16(message, classIN)
+
+	message = append(message, 0) // root name
+	message = binary.BigEndian.AppendUint16(message, typeOPT)
+	message = binary.BigEndian.AppendUint16(message, maxUDPPayload)
+	message = binary.BigEndian.AppendUint32(message, 0)
+	message = binary.BigEndian.AppendUint16(message, 0)
+	return message, nil
+}
+
+// parseResponse returns the answers of type qtype. Other answers, such as the
+// CNAME records leading to them, are skipped.
+func parseResponse(message []byte, id, qtype uint16) ([]dnsRecord, error) {
+	if len(message) < 12 {
+		return nil, errors.New("short DNS response")
+	}
+	if binary.BigEndian.Uint16(message[0:]) != id {
+		return nil, errors.New("DNS response does not match the query")
+	}
+	flags := binary.BigEndian.Uint16(message[2:])
+	switch rcode := flags & 0x000f; rcode {
+	case 0:
+	case 3:
+		return nil, errNoSuchHost
+	default:
+		return nil, fmt.Errorf("DNS server answered with rcode %d", rcode)
+	}
+	questions := int(binary.BigEndian.Uint16(message[4:]))
+	answers := int(binary.BigEndian.Uint16(message[6:]))
+
+	offset := 12
+	var err error
+	for i := 0; i < questions; i++ {
+		if _, offset, err = readName(message, offset); err != nil {
+			return nil, err
+		}
+		offset += 4
+	}
+
+	var records []dnsRecord
+	for i := 0; i < answers; i++ {
+		if _, offset, err = readName(message, offset); err != nil {
+			return nil, err
+		}
+		if offset+10 > len(message) {
+			return nil, errors.New("truncated DNS record")
+		}
+		rtype := binary.BigEndian.Uint16(message[offset:])
+		ttl := binary.BigEndian.Uint32(message[offset+4:])
+		length := int(binary.BigEndian.Uint16(message[offset+8:]))
+		offset += 10
+		if offset+length > len(message) {
+			return nil, errors.New("truncated DNS record")
+		}
+		data := message[offset : offset+length]
+
+		record := dnsRecord{Type: rtype, TTL: time.Duration(ttl) * time.Second}
+		switch {
+		case rtype != qtype:
+			offset += length
+			continue
+		case rtype == typeA && length == net.IPv4len, rtype == typeAAAA && length == net.IPv6len:
+			record.IP = net.IP(append([]byte(nil), data...))
+		case rtype == typeSRV && length > 6:
+			record.SRV = srvRecord{
+				Priority: binary.BigEndian.Uint16(data[0:]),
+				Weight:   binary.BigEndian.Uint16(data[2:]),
+				Port:     binary.BigEndian.Uint16(data[4:]),
+			}
+			if record.SRV.Target, _, err = readName(message, offset+6); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("malformed DNS record of type %d", rtype)
+		}
+		records = append(records, record)
+		offset += length
+	}
+	return records, nil
+}
+
+// readName decodes the possibly compressed name at offset and returns it with
+// the offset just past it.
+func readName(message []byte, offset int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if offset >= len(message) {
+			return "", 0, errors.New("truncated DNS name")
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if end == -1 {
+				end = offset + 1
+			}
+			return strings.Join(labels, "."), end, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(message) || jumps > 16 {
+				return "", 0, errors.New("invalid DNS name compression")
+			}
+			if end == -1 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(message[offset:]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, errors.New("truncated DNS name")
+			}
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answer builds a response to query whose records all point back at the name in
+// the question.
+func answer(query []byte, truncated bool, records ...[]byte) []byte {
+	questionEnd := 12
+	for query[questionEnd] != 0 {
+		questionEnd += int(query[questionEnd]) + 1
+	}
+	questionEnd += 5
+
+	response := append([]byte(nil), query[:questionEnd]...)
+	flags := uint16(0x8180)
+	if truncated {
+		flags |= 0x0200
+	}
+	binary.BigEndian.PutUint16(response[2:], flags)
+	binary.BigEndian.PutUint16(response[6:], uint16(len(records)))
+	binary.BigEndian.PutUint16(response[10:], 0)
+	for _, record := range records {
+		response = append(response, record...)
+	}
+	return response
+}
+
+func resourceRecord(rtype uint16, ttl uint32, data []byte) []byte {
+	record := []byte{0xc0, 12}
+	record = binary.BigEndian.AppendUint16(record, rtype)
+	record = binary.BigEndian.AppendUint16(record, classIN)
+	record = binary.BigEndian.AppendUint32(record, ttl)
+	record = binary.BigEndian.AppendUint16(record, uint16(len(data)))
+	return append(record, data...)
+}
+
+func srvData(priority, weight, port uint16, target string) []byte {
+	data := binary.BigEndian.AppendUint16(nil, priority)
+	data = binary.BigEndian.AppendUint16(data, weight)
+	data = binary.BigEndian.AppendUint16(data, port)
+	for _, label := range []string{target, "example", "com"} {
+		data = append(data, byte(len(label)))
+		data = append(data, label...)
+	}
+	return append(data, 0)
+}
+
+// serveDNS answers over UDP with a truncated response and over TCP with the full
+// one, so lookups have to retry over TCP.
+func serveDNS(t *testing.T, records ...[]byte) string {
+	t.Helper()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { udp.Close() })
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { tcp.Close() })
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := udp.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			udp.WriteTo(answer(buffer[:n], true), addr)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			io.ReadFull(conn, length[:])
+			query := make([]byte, binary.BigEndian.Uint16(length[:]))
+			io.ReadFull(conn, query)
+			response := answer(query, false, records...)
+			conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+			conn.Close()
+		}
+	}()
+	return udp.LocalAddr().String()
+}
+
+func TestLookup(t *testing.T) {
+	server := serveDNS(t,
+		resourceRecord(5, 300, []byte{0}), // a CNAME, skipped
+		resourceRecord(typeSRV, 30, srvData(10, 5, 8080, "node1")),
+		resourceRecord(typeSRV, 60, srvData(20, 0, 8081, "node2")),
+	)
+
+	records, err := lookup(context.Background(), server, "_http._tcp.example.com", typeSRV)
+	require.NoError(t, err)
+	assert.Equal(t, []dnsRecord{
+		{Type: typeSRV, TTL: 30 * time.Second, SRV: srvRecord{Priority: 10, Weight: 5, Port: 8080, Target: "node1.example.com"}},
+		{Type: typeSRV, TTL: time.Minute, SRV: srvRecord{Priority: 20, Weight: 0, Port: 8081, Target: "node2.example.com"}},
+	}, records)
+}
+
+func TestParseResponse_Errors(t *testing.T) {
+	query, err := buildQuery(7, "missing.example.com", typeA)
+	require.NoError(t, err)
+
+	response := answer(query, false)
+	response[3] |= 3
+	_, err = parseResponse(response, 7, typeA)
+	assert.ErrorIs(t, err, errNoSuchHost)
+
+	_, err = parseResponse(answer(query, false), 8, typeA)
+	assert.ErrorContains(t, err, "does not match")
+
+	_, err = parseResponse(answer(query, false, resourceRecord(typeA, 30, []byte{10, 0})), 7, typeA)
+	assert.ErrorContains(t, err, "malformed")
+
+	_, err = buildQuery(1, "bad..name", typeA)
+	assert.Error(t, err)
+}
+
+func TestReadResolvConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	require.NoError(t, os.WriteFile(path, []byte(`# generated
+nameserver 10.96.0.10
+nameserver fd00::10
+domain example.com
+search prod.svc.cluster.local svc.cluster.local
+options ndots:5 timeout:2
+`), 0o644))
+
+	conf := readResolvConf(path)
+	assert.Equal(t, []string{"10.96.0.10:53", "[fd00::10]:53"}, conf.servers)
+	assert.Equal(t, []string{"api.prod.svc.cluster.local", "api.svc.cluster.local", "api"}, conf.names("api"))
+	assert.Equal(t, []string{"api.example.com."}, conf.names("api.example.com."))
+
+	conf.ndots = 1
+	assert.Equal(t, []string{"api.example.com", "api.example.com.prod.svc.cluster.local", "api.example.com.svc.cluster.local"}, conf.names("api.example.com"))
+
+	conf = readResolvConf(filepath.Join(t.TempDir(), "missing"))
+	assert.Equal(t, resolvConf{servers: []string{"127.0.0.1:53"}, ndots: 1}, conf)
+	assert.Equal(t, []string{"api"}, conf.names("api"))
+}
@@ -0,0 +1,219 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDNS struct {
+	mutex   sync.Mutex
+	records map[uint16][]dnsRecord
+	err     error
+	// hosts answers for particular names instead of records.
+	hosts map[string]map[uint16][]dnsRecord
+	// down lists servers that fail every query.
+	down    map[string]bool
+	queries []string
+}
+
+func (f *fakeDNS) set(records map[uint16][]dnsRecord, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.records, f.err = records, err
+}
+
+func (f *fakeDNS) lookup(ctx context.Context, server, name string, qtype uint16) ([]dnsRecord, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.queries = append(f.queries, server+" "+name)
+	if f.down[server] {
+		return nil, errors.New("i/o timeout")
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	records := f.records
+	if host, ok := f.hosts[name]; ok {
+		records = host
+	}
+	if len(records[qtype]) == 0 {
+		return nil, errNoSuchHost
+	}
+	return records[qtype], nil
+}
+
+func (f *fakeDNS) takeQueries() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	queries := f.queries
+	f.queries = nil
+	return queries
+}
+
+func newTestDNSProvider(dns *fakeDNS, discovery config.DiscoveryConfig) *DNSProvider {
+	discovery.DNSServer = "127.0.0.1:53"
+	provider := NewDNSProvider(discovery, &testLogger{})
+	provider.lookup = dns.lookup
+	return provider
+}
+
+func TestDNSProvider_Addresses(t *testing.T) {
+	dns := &fakeDNS{}
+	dns.set(map[uint16][]dnsRecord{
+		typeA:    {{TTL: 30 * time.Second, IP: net.ParseIP("10.0.0.2")}, {TTL: 10 * time.Second, IP: net.ParseIP("10.0.0.1")}},
+		typeAAAA: {{TTL: 20 * time.Second, IP: net.ParseIP("fd00::1")}},
+	}, nil)
+	provider := newTestDNSProvider(dns, config.DiscoveryConfig{
+		DNSName: "api.internal", DNSRecord: "a", Port: 8080, Scheme: "http", MinRefresh: time.Second, MaxRefresh: time.Minute,
+	})
+
+	targets, ttl, err := provider.resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{
+		{URL: "http://10.0.0.1:8080", Weight: 1},
+		{URL: "http://10.0.0.2:8080", Weight: 1},
+		{URL: "http://[fd00::1]:8080", Weight: 1},
+	}, targets)
+	assert.Equal(t, 10*time.Second, ttl)
+}
+
+func TestDNSProvider_SRV(t *testing.T) {
+	dns := &fakeDNS{}
+	dns.set(map[uint16][]dnsRecord{typeSRV: {
+		{TTL: 30 * time.Second, SRV: srvRecord{Priority: 10, Weight: 3, Port: 9000, Target: "node1.internal"}},
+		{TTL: 30 * time.Second, SRV: srvRecord{Priority: 10, Weight: 1, Port: 9001, Target: "node2.internal"}},
+		{TTL: 30 * time.Second, SRV: srvRecord{Priority: 20, Weight: 1, Port: 9000, Target: "backup.internal"}},
+	}}, nil)
+	dns.hosts = map[string]map[uint16][]dnsRecord{
+		"node1.internal.": {typeA: {{IP: net.ParseIP("10.0.0.1")}}},
+		"node2.internal.": {typeAAAA: {{IP: net.ParseIP("fd00::2")}}},
+	}
+	provider := newTestDNSProvider(dns, config.DiscoveryConfig{
+		DNSName: "_api._tcp.internal", DNSRecord: "srv", Scheme: "https", MinRefresh: time.Second, MaxRefresh: time.Minute,
+	})
+
+	targets, err := provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{
+		{URL: "https://node1.internal:9000", Weight: 3},
+		{URL: "https://node2.internal:9001", Weight: 1},
+	}, targets)
+
+	dns.set(map[uint16][]dnsRecord{typeSRV: {
+		{SRV: srvRecord{Priority: 5, Weight: 0, Port: 9000, Target: "node3.internal"}},
+		{SRV: srvRecord{Priority: 5, Weight: 0, Port: 9000, Target: ""}},
+	}}, nil)
+	targets, err = provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{{URL: "https://node3.internal:9000", Weight: 1}}, targets)
+
+	// Weights above config.MaxWeight are scaled down, keeping their proportions.
+	dns.set(map[uint16][]dnsRecord{typeSRV: {
+		{SRV: srvRecord{Priority: 10, Weight: 65535, Port: 9000, Target: "node1.internal"}},
+		{SRV: srvRecord{Priority: 10, Weight: 6554, Port: 9001, Target: "node2.internal"}},
+		{SRV: srvRecord{Priority: 10, Weight: 1, Port: 9002, Target: "node2.internal"}},
+	}}, nil)
+	targets, err = provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{
+		{URL: "https://node1.internal:9000", Weight: 1000},
+		{URL: "https://node2.internal:9001", Weight: 100},
+		{URL: "https://node2.internal:9002", Weight: 1},
+	}, targets)
+}
+
+func TestDNSProvider_SRVFailsOverToNextPriority(t *testing.T) {
+	dns := &fakeDNS{}
+	dns.set(map[uint16][]dnsRecord{typeSRV: {
+		{SRV: srvRecord{Priority: 10, Weight: 1, Port: 9000, Target: "node1.internal"}},
+		{SRV: srvRecord{Priority: 20, Weight: 1, Port: 9000, Target: "backup1.internal"}},
+		{SRV: srvRecord{Priority: 30, Weight: 1, Port: 9000, Target: "backup2.internal"}},
+	}}, nil)
+	dns.hosts = map[string]map[uint16][]dnsRecord{
+		"node1.internal.":   {},
+		"backup1.internal.": {typeA: {{IP: net.ParseIP("10.0.0.2")}}},
+		"backup2.internal.": {typeA: {{IP: net.ParseIP("10.0.0.3")}}},
+	}
+	provider := newTestDNSProvider(dns, config.DiscoveryConfig{
+		DNSName: "_api._tcp.internal", DNSRecord: "srv", Scheme: "http", MinRefresh: time.Second, MaxRefresh: time.Minute,
+	})
+
+	targets, err := provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{{URL: "http://backup1.internal:9000", Weight: 1}}, targets)
+
+	// Nothing resolves, so the last group is used as it is.
+	dns.hosts["backup1.internal."] = map[uint16][]dnsRecord{}
+	dns.hosts["backup2.internal."] = map[uint16][]dnsRecord{}
+	targets, err = provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{{URL: "http://backup2.internal:9000", Weight: 1}}, targets)
+}
+
+func TestDNSProvider_SearchDomainsAndServerFallback(t *testing.T) {
+	dns := &fakeDNS{}
+	dns.set(map[uint16][]dnsRecord{}, nil)
+	dns.hosts = map[string]map[uint16][]dnsRecord{
+		"api.prod.svc.cluster.local": {typeA: {{IP: net.ParseIP("10.0.0.1")}}},
+	}
+	dns.down = map[string]bool{"10.0.0.53:53": true}
+	provider := newTestDNSProvider(dns, config.DiscoveryConfig{
+		DNSName: "api", DNSRecord: "a", Port: 80, Scheme: "http", MinRefresh: time.Second, MaxRefresh: time.Minute,
+	})
+	conf := resolvConf{servers: []string{"10.0.0.53:53", "10.0.0.54:53"}, search: []string{"prod.svc.cluster.local", "svc.cluster.local"}, ndots: 5}
+	provider.servers, provider.names = conf.servers, conf.names("api")
+
+	targets, err := provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{{URL: "http://10.0.0.1:80", Weight: 1}}, targets)
+	assert.Equal(t, []string{
+		"10.0.0.53:53 api.prod.svc.cluster.local", "10.0.0.54:53 api.prod.svc.cluster.local",
+		"10.0.0.53:53 api.prod.svc.cluster.local", "10.0.0.54:53 api.prod.svc.cluster.local",
+	}, dns.takeQueries())
+
+	dns.down["10.0.0.54:53"] = true
+	_, err = provider.Targets(context.Background())
+	assert.ErrorContains(t, err, "10.0.0.54:53: i/o timeout")
+}
+
+func TestDNSProvider_WatchKeepsLastGoodSet(t *testing.T) {
+	dns := &fakeDNS{}
+	dns.set(map[uint16][]dnsRecord{typeA: {{TTL: 0, IP: net.ParseIP("10.0.0.1")}}}, nil)
+	provider := newTestDNSProvider(dns, config.DiscoveryConfig{
+		DNSName: "api.internal", DNSRecord: "a", Port: 80, Scheme: "http", MinRefresh: 10 * time.Millisecond, MaxRefresh: time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan []Target, 10)
+	go provider.Watch(ctx, func(targets []Target) { updates <- targets })
+
+	next := func() []Target {
+		select {
+		case targets := <-updates:
+			return targets
+		case <-time.After(2 * time.Second):
+			t.Fatal("no update")
+			return nil
+		}
+	}
+	assert.Equal(t, []Target{{URL: "http://10.0.0.1:80", Weight: 1}}, next())
+
+	dns.set(nil, errors.New("server misbehaving"))
+	time.Sleep(50 * time.Millisecond)
+	dns.set(map[uint16][]dnsRecord{}, nil)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, updates)
+
+	dns.set(map[uint16][]dnsRecord{typeA: {{TTL: 0, IP: net.ParseIP("10.0.0.2")}}}, nil)
+	assert.Equal(t, []Target{{URL: "http://10.0.0.2:80", Weight: 1}}, next())
+}
@@ -0,0 +1,91 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// FileProvider reads backends from a file written by another process, such as
+// an orchestrator. Writes are picked up through inotify where it is available
+// and by polling otherwise. A file that cannot be parsed, or that lists no
+// backends, is ignored so a half-written or truncated file never empties a pool.
+type FileProvider struct {
+	path     string
+	interval time.Duration
+	logger   logger.Logger
+}
+
+func NewFileProvider(path string, interval time.Duration, logger logger.Logger) *FileProvider {
+	return &FileProvider{
+		path:     path,
+		interval: interval,
+		logger:   logger.With(zap.String("discovery_file", path)),
+	}
+}
+
+func (p *FileProvider) Targets(ctx context.Context) ([]Target, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := ParseTargets(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", p.path, err)
+	}
+	return targets, nil
+}
+
+func (p *FileProvider) Watch(ctx context.Context, update func([]Target)) {
+	changes := watchFile(ctx, p.path, p.interval, p.logger)
+
+	var last []byte
+	for {
+		data, err := os.ReadFile(p.path)
+		switch {
+		case err != nil:
+			p.logger.Warn("Cannot read discovery file", zap.Error(err))
+		case last != nil && bytes.Equal(data, last):
+		default:
+			last = data
+			p.apply(data, update)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+		}
+	}
+}
+
+func (p *FileProvider) apply(data []byte, update func([]Target)) {
+	targets, err := ParseTargets(data)
+	if err != nil {
+		p.logger.Error("Discovery file rejected, keeping the current backends", zap.Error(err))
+		return
+	}
+	if len(targets) == 0 {
+		p.logger.Warn("Discovery file lists no backends, keeping the current backends")
+		return
+	}
+	update(targets)
+}
+
+// watchFile notifies of changes to the file at path, watching its directory so
+// files replaced by a rename are noticed too.
+func watchFile(ctx context.Context, path string, interval time.Duration, logger logger.Logger) <-chan struct{} {
+	changes, err := watchInotify(ctx, path)
+	if err == nil {
+		return changes
+	}
+	logger.Debug("Cannot watch discovery file, polling it instead", zap.Error(err))
+	return config.WatchFile(ctx, path, interval)
+}
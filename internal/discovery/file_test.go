@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replaceFile writes the file next to path and renames it into place, as
+// orchestrators do.
+func replaceFile(t *testing.T, path, data string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(data), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestFileProvider_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backends.yaml")
+	replaceFile(t, path, "- http://10.0.0.1\n")
+
+	provider := NewFileProvider(path, 10*time.Millisecond, &testLogger{})
+	targets, err := provider.Targets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Target{{URL: "http://10.0.0.1", Weight: 1}}, targets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan []Target, 10)
+	go provider.Watch(ctx, func(targets []Target) { updates <- targets })
+
+	next := func() []Target {
+		select {
+		case targets := <-updates:
+			return targets
+		case <-time.After(2 * time.Second):
+			t.Fatal("no update")
+			return nil
+		}
+	}
+	assert.Equal(t, []Target{{URL: "http://10.0.0.1", Weight: 1}}, next())
+
+	replaceFile(t, path, "not: [valid")
+	replaceFile(t, path, "[]")
+	replaceFile(t, path, "- http://10.0.0.1\n- http://10.0.0.2\n")
+	assert.Equal(t, []Target{{URL: "http://10.0.0.1", Weight: 1}, {URL: "http://10.0.0.2", Weight: 1}}, next())
+}
@@ -0,0 +1,47 @@
+package discovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// watchInotify sends on the returned channel after any change in the directory
+// of path. Kubernetes and most deploy tools replace files by renaming them into
+// place, and mounted ConfigMaps swap a symlink next to the file, so watching the
+// file itself would miss updates.
+func watchInotify(ctx context.Context, path string) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM)
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), mask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// A non-blocking descriptor is read through the runtime poller, so closing
+	// the file unblocks the reader.
+	events := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		events.Close()
+	}()
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		buffer := make([]byte, 4096)
+		for {
+			if _, err := events.Read(buffer); err != nil {
+				return
+			}
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes, nil
+}
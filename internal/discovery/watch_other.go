@@ -0,0 +1,12 @@
+//go:build !linux
+
+package discovery
+
+import (
+	"context"
+	"errors"
+)
+
+func watchInotify(ctx context.Context, path string) (<-chan struct{}, error) {
+	return nil, errors.New("inotify is only available on Linux")
+}
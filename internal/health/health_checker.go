@@ -7,10 +7,18 @@ import (
 	"time"
 
 	"routing-api/internal/logger"
+	"routing-api/internal/metrics"
 
 	"go.uber.org/zap"
 )
 
+var (
+	healthCheckDuration = metrics.Default.NewHistogramVec("routing_api_health_check_duration_seconds",
+		"Time taken by backend health checks.", metrics.DefaultBuckets, "backend")
+	healthCheckFailures = metrics.Default.NewCounterVec("routing_api_health_check_failures_total",
+		"Failed backend health checks, by backend and reason: error or status.", "backend", "reason")
+)
+
 type HealthChecker interface {
 	Start(ctx context.Context, clients []HTTPClient, interval time.Duration, onHealthChange func())
 }
@@ -23,16 +31,46 @@ type httpHealthChecker struct {
 	mutex            sync.RWMutex
 }
 
+type HealthCheckConfig struct {
+	Path             string
+	FailureThreshold int
+}
+
+func DefaultHealthCheckConfig() HealthCheckConfig {
+	return HealthCheckConfig{
+		Path:             "/health",
+		FailureThreshold: 3,
+	}
+}
+
 func NewHTTPHealthChecker(logger logger.Logger) *httpHealthChecker {
+	return NewHTTPHealthCheckerWithConfig(DefaultHealthCheckConfig(), logger)
+}
+
+func NewHTTPHealthCheckerWithConfig(config HealthCheckConfig, log logger.Logger) *httpHealthChecker {
+	defaults := DefaultHealthCheckConfig()
+	if config.Path == "" {
+		config.Path = defaults.Path
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+
 	return &httpHealthChecker{
-		checkPath:        "/health",
-		failureThreshold: 3,
-		logger:           logger,
+		checkPath:        config.Path,
+		failureThreshold: config.FailureThreshold,
+		logger:           logger.Component(log, logger.ComponentHealth),
 		failureCounts:    make(map[string]int),
 	}
 }
 
 func (h *httpHealthChecker) Start(ctx context.Context, clients []HTTPClient, interval time.Duration, onHealthChange func()) {
+	h.Watch(ctx, func() []HTTPClient { return clients }, interval, onHealthChange)
+}
+
+// Watch is like Start but asks for the clients before every round of checks, so
+// backends added or removed at runtime are picked up.
+func (h *httpHealthChecker) Watch(ctx context.Context, clients func() []HTTPClient, interval time.Duration, onHealthChange func()) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -41,11 +79,16 @@ func (h *httpHealthChecker) Start(ctx context.Context, clients []HTTPClient, int
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			h.checkAllClients(clients, onHealthChange)
+			h.checkAllClients(clients(), onHealthChange)
 		}
 	}
 }
 
+// Check runs one round of checks on clients right away.
+func (h *httpHealthChecker) Check(clients []HTTPClient, onHealthChange func()) {
+	h.checkAllClients(clients, onHealthChange)
+}
+
 func (h *httpHealthChecker) checkAllClients(clients []HTTPClient, onHealthChange func()) {
 	var wg sync.WaitGroup
 	healthChanged := false
@@ -72,6 +115,7 @@ func (h *httpHealthChecker) checkAllClients(clients []HTTPClient, onHealthChange
 
 func (h *httpHealthChecker) checkClient(client HTTPClient) {
 	clientURL := client.GetBaseURL()
+	defer func() { client.SetLastCheck(time.Now()) }()
 
 	req, err := http.NewRequest("GET", clientURL+h.checkPath, nil)
 	if err != nil {
@@ -88,17 +132,20 @@ func (h *httpHealthChecker) checkClient(client HTTPClient) {
 	req = req.WithContext(ctx)
 
 	var resp *http.Response
+	start := time.Now()
 	if defaultClient, ok := client.(*DefaultHTTPClient); ok {
 		resp, err = defaultClient.Client.Do(req)
 	} else {
 		resp, err = client.Do(req)
 	}
+	healthCheckDuration.WithLabelValues(clientURL).Observe(time.Since(start).Seconds())
 
 	if err != nil {
 		h.logger.Warn("Health check failed",
 			zap.String("url", clientURL+h.checkPath),
 			zap.Error(err),
 		)
+		healthCheckFailures.WithLabelValues(clientURL, "error").Inc()
 		h.recordFailure(clientURL, client)
 		return
 	}
@@ -112,6 +159,7 @@ func (h *httpHealthChecker) checkClient(client HTTPClient) {
 			zap.String("url", clientURL+h.checkPath),
 			zap.Int("status", resp.StatusCode),
 		)
+		healthCheckFailures.WithLabelValues(clientURL, "status").Inc()
 		h.recordFailure(clientURL, client)
 	}
 }
@@ -1,10 +1,13 @@
 package health
 
 import (
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,13 +16,56 @@ type HTTPClient interface {
 	IsUp() bool
 	SetUp(isUp bool)
 	GetBaseURL() string
+	SetLastCheck(at time.Time)
+	InFlight() int64
+	State() BackendState
+	SetDraining(draining bool)
+	Status() BackendStatus
+}
+
+// BackendState is whether a backend is given new requests. A draining backend
+// gets none, whatever its health, while the requests it has finish.
+type BackendState int
+
+const (
+	StateUp BackendState = iota
+	StateDown
+	StateDraining
+)
+
+func (s BackendState) String() string {
+	switch s {
+	case StateUp:
+		return "up"
+	case StateDown:
+		return "down"
+	case StateDraining:
+		return "draining"
+	default:
+		return "unknown"
+	}
+}
+
+type BackendStatus struct {
+	URL           string    `json:"url"`
+	Healthy       bool      `json:"healthy"`
+	State         string    `json:"state"`
+	CircuitState  string    `json:"circuit_state,omitempty"`
+	CircuitForced bool      `json:"circuit_forced,omitempty"`
+	FailureCount  int       `json:"failure_count"`
+	SlowCount     int       `json:"slow_count"`
+	InFlight      int64     `json:"in_flight"`
+	LastCheck     time.Time `json:"last_check"`
 }
 
 type DefaultHTTPClient struct {
 	*http.Client
-	BaseURL string
-	Up      bool
-	mutex   sync.RWMutex
+	BaseURL   string
+	Up        bool
+	draining  bool
+	lastCheck time.Time
+	inFlight  int64
+	mutex     sync.RWMutex
 }
 
 func NewDefaultHTTPClient(baseURL string, requestTimeout, connectTimeout time.Duration) *DefaultHTTPClient {
@@ -48,10 +94,56 @@ func (c *DefaultHTTPClient) Do(req *http.Request) (*http.Response, error) {
 		return nil, err
 	}
 
-	req.URL = baseURL.ResolveReference(req.URL)
+	req.URL = targetURL(baseURL, req.URL)
 	req.RequestURI = ""
 
-	return c.Client.Do(req)
+	atomic.AddInt64(&c.inFlight, 1)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		atomic.AddInt64(&c.inFlight, -1)
+		return nil, err
+	}
+
+	body := &inFlightBody{ReadCloser: resp.Body, done: func() { atomic.AddInt64(&c.inFlight, -1) }}
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok {
+		resp.Body = &inFlightReadWriteBody{inFlightBody: body, writer: rwc}
+	} else {
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+// targetURL places a relative request URL under the base URL, keeping any path and
+// query on the base URL. Absolute URLs are used as they are.
+func targetURL(base, ref *url.URL) *url.URL {
+	if ref.IsAbs() {
+		return ref
+	}
+
+	target := *base
+	target.Path = joinPath(base.Path, ref.Path)
+	target.RawPath = ""
+	if base.RawPath != "" || ref.RawPath != "" {
+		target.RawPath = joinPath(base.EscapedPath(), ref.EscapedPath())
+	}
+	switch {
+	case base.RawQuery == "":
+		target.RawQuery = ref.RawQuery
+	case ref.RawQuery != "":
+		target.RawQuery = base.RawQuery + "&" + ref.RawQuery
+	}
+	target.Fragment = ""
+	return &target
+}
+
+func joinPath(base, path string) string {
+	switch {
+	case base == "" || base == "/":
+		return path
+	case path == "":
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
 }
 
 func (c *DefaultHTTPClient) IsUp() bool {
@@ -66,6 +158,73 @@ func (c *DefaultHTTPClient) SetUp(isUp bool) {
 	c.Up = isUp
 }
 
+func (c *DefaultHTTPClient) State() BackendState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.state()
+}
+
+func (c *DefaultHTTPClient) state() BackendState {
+	switch {
+	case c.draining:
+		return StateDraining
+	case c.Up:
+		return StateUp
+	default:
+		return StateDown
+	}
+}
+
+func (c *DefaultHTTPClient) SetDraining(draining bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.draining = draining
+}
+
 func (c *DefaultHTTPClient) GetBaseURL() string {
 	return c.BaseURL
 }
+
+func (c *DefaultHTTPClient) SetLastCheck(at time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lastCheck = at
+}
+
+func (c *DefaultHTTPClient) InFlight() int64 {
+	return atomic.LoadInt64(&c.inFlight)
+}
+
+func (c *DefaultHTTPClient) Status() BackendStatus {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return BackendStatus{
+		URL:       c.BaseURL,
+		Healthy:   c.Up,
+		State:     c.state().String(),
+		InFlight:  atomic.LoadInt64(&c.inFlight),
+		LastCheck: c.lastCheck,
+	}
+}
+
+type inFlightBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *inFlightBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+type inFlightReadWriteBody struct {
+	*inFlightBody
+	writer io.Writer
+}
+
+func (b *inFlightReadWriteBody) Write(p []byte) (int, error) {
+	return b.writer.Write(p)
+}
@@ -3,6 +3,7 @@ package health
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -31,6 +32,56 @@ func TestDefaultHTTPClient_Do(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestTargetURL(t *testing.T) {
+	tests := []struct {
+		base     string
+		ref      string
+		expected string
+	}{
+		{base: "http://backend:8080", ref: "/orders?id=1", expected: "http://backend:8080/orders?id=1"},
+		{base: "http://backend:8080/v1", ref: "/orders", expected: "http://backend:8080/v1/orders"},
+		{base: "http://backend:8080/v1/", ref: "/orders", expected: "http://backend:8080/v1/orders"},
+		{base: "http://backend:8080/v1?key=abc", ref: "/orders?id=1", expected: "http://backend:8080/v1/orders?key=abc&id=1"},
+		{base: "http://backend:8080/v1", ref: "/a%2Fb", expected: "http://backend:8080/v1/a%2Fb"},
+		{base: "http://backend:8080/v1", ref: "http://other:9090/health", expected: "http://other:9090/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base+tt.ref, func(t *testing.T) {
+			base, err := url.Parse(tt.base)
+			assert.NoError(t, err)
+			ref, err := url.Parse(tt.ref)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, targetURL(base, ref).String())
+		})
+	}
+}
+
+func TestDefaultHTTPClient_InFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("test response"))
+	}))
+	defer server.Close()
+
+	client := &DefaultHTTPClient{
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		BaseURL: server.URL,
+		Up:      true,
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	assert.NoError(t, err)
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), client.Status().InFlight)
+
+	resp.Body.Close()
+	resp.Body.Close()
+	assert.Equal(t, int64(0), client.Status().InFlight)
+}
+
 func TestDefaultHTTPClient_IsUp(t *testing.T) {
 	client := &DefaultHTTPClient{
 		Client:  &http.Client{Timeout: 5 * time.Second},
@@ -58,6 +109,27 @@ func TestDefaultHTTPClient_SetUp(t *testing.T) {
 	assert.False(t, client.IsUp())
 }
 
+func TestDefaultHTTPClient_State(t *testing.T) {
+	client := &DefaultHTTPClient{
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		BaseURL: "http://example.com",
+		Up:      true,
+	}
+	assert.Equal(t, StateUp, client.State())
+
+	client.SetDraining(true)
+	assert.Equal(t, StateDraining, client.State())
+	assert.Equal(t, "draining", client.Status().State)
+
+	client.SetUp(false)
+	client.SetUp(true)
+	assert.Equal(t, StateDraining, client.State(), "health changes do not end a drain")
+
+	client.SetDraining(false)
+	client.SetUp(false)
+	assert.Equal(t, StateDown, client.State())
+}
+
 func TestDefaultHTTPClient_ConcurrentAccess(t *testing.T) {
 	client := &DefaultHTTPClient{
 		Client:  &http.Client{Timeout: 5 * time.Second},
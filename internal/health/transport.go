@@ -0,0 +1,45 @@
+package health
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	ProtocolHTTP1 = "http1"
+	ProtocolHTTP2 = "http2"
+)
+
+type TransportConfig struct {
+	Protocol              string
+	ConnectTimeout        time.Duration
+	ResponseHeaderTimeout time.Duration
+}
+
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		Protocol:              ProtocolHTTP1,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+}
+
+func NewTransport(config TransportConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	if config.ConnectTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   config.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+
+	if config.Protocol == ProtocolHTTP2 {
+		// HTTP/2 only: ALPN for https backends, prior-knowledge h2c for http backends.
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return transport
+}
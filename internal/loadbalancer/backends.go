@@ -0,0 +1,256 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/health"
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrBackendNotFound = errors.New("backend not found")
+	ErrBackendExists   = errors.New("backend already exists")
+)
+
+// Backend is a server of a balancer together with the settings that can be
+// changed at runtime.
+type Backend struct {
+	URL     string
+	Weight  int
+	Enabled bool
+	Client  health.HTTPClient
+}
+
+type healthChecker interface {
+	Watch(ctx context.Context, clients func() []health.HTTPClient, interval time.Duration, onHealthChange func())
+	Check(clients []health.HTTPClient, onHealthChange func())
+}
+
+// backendSet holds the backends shared by the balancer implementations. After every
+// change to the set or to a backend's health, rebuild is called with the mutex held
+// so the balancer can recompute its rotation.
+type backendSet struct {
+	clients  []health.HTTPClient
+	weights  []int
+	disabled []bool
+	config   BalancerConfig
+	checker  healthChecker
+	mutex    sync.RWMutex
+	logger   logger.Logger
+	rebuild  func()
+}
+
+func (s *backendSet) init(config BalancerConfig, log logger.Logger, rebuild func()) {
+	s.clients = newBackendClients(config)
+	s.weights = backendWeights(config.Servers, config.Weights)
+	s.disabled = make([]bool, len(config.Servers))
+	s.config = config
+	s.checker = health.NewHTTPHealthCheckerWithConfig(config.HealthCheck, log)
+	s.logger = logger.Component(log, logger.ComponentBalancer)
+	s.rebuild = rebuild
+	s.updateAvailableClients()
+}
+
+func newBackendClients(config BalancerConfig) []health.HTTPClient {
+	clients := make([]health.HTTPClient, len(config.Servers))
+	for i, serverURL := range config.Servers {
+		clients[i], _ = config.newClient(serverURL)
+	}
+	return clients
+}
+
+// newClient returns a client for serverURL, and whether it is new rather than
+// one that already serves requests and knows the backend's health.
+func (c BalancerConfig) newClient(serverURL string) (health.HTTPClient, bool) {
+	if c.Clients != nil {
+		return c.Clients.client(serverURL, c)
+	}
+	return newBackendClient(serverURL, c.Circuit, c.Transport), true
+}
+
+func (s *backendSet) Clients() []health.HTTPClient {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	clients := make([]health.HTTPClient, len(s.clients))
+	copy(clients, s.clients)
+	return clients
+}
+
+func (s *backendSet) Backends() []Backend {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	backends := make([]Backend, len(s.clients))
+	for i, client := range s.clients {
+		backends[i] = Backend{
+			URL:     client.GetBaseURL(),
+			Weight:  s.weights[i],
+			Enabled: !s.disabled[i],
+			Client:  client,
+		}
+	}
+	return backends
+}
+
+func (s *backendSet) AddBackend(serverURL string, weight int) error {
+	_, err := s.add(serverURL, weight, true)
+	return err
+}
+
+// AddPendingBackend adds a backend that gets no requests until it passes a
+// health check, which is run straight away.
+func (s *backendSet) AddPendingBackend(serverURL string, weight int) error {
+	client, err := s.add(serverURL, weight, false)
+	if err != nil {
+		return err
+	}
+	go s.checker.Check([]health.HTTPClient{client}, s.updateAvailableClients)
+	return nil
+}
+
+func (s *backendSet) add(serverURL string, weight int, up bool) (health.HTTPClient, error) {
+	if err := checkWeight(weight); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.index(serverURL) != -1 {
+		return nil, fmt.Errorf("%w: %s", ErrBackendExists, serverURL)
+	}
+	client, created := s.config.newClient(serverURL)
+	if !up && created {
+		client.SetUp(false)
+	}
+	s.clients = append(s.clients, client)
+	s.weights = append(s.weights, weight)
+	s.disabled = append(s.disabled, false)
+	s.rebuild()
+
+	s.logger.Info("Backend added", zap.String("backend_url", serverURL), zap.Int("weight", weight), zap.Bool("pending", !up))
+	return client, nil
+}
+
+// RemoveBackend takes a backend out of the balancer and returns its client, which
+// may still be serving requests that were started before the removal.
+func (s *backendSet) RemoveBackend(serverURL string) (health.HTTPClient, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.index(serverURL)
+	if i == -1 {
+		return nil, fmt.Errorf("%w: %s", ErrBackendNotFound, serverURL)
+	}
+	client := s.clients[i]
+	s.clients = append(s.clients[:i:i], s.clients[i+1:]...)
+	s.weights = append(s.weights[:i:i], s.weights[i+1:]...)
+	s.disabled = append(s.disabled[:i:i], s.disabled[i+1:]...)
+	s.rebuild()
+
+	s.logger.Info("Backend removed", zap.String("backend_url", serverURL))
+	return client, nil
+}
+
+func (s *backendSet) SetWeight(serverURL string, weight int) error {
+	if err := checkWeight(weight); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.index(serverURL)
+	if i == -1 {
+		return fmt.Errorf("%w: %s", ErrBackendNotFound, serverURL)
+	}
+	s.weights[i] = weight
+	s.rebuild()
+
+	s.logger.Info("Backend weight changed", zap.String("backend_url", serverURL), zap.Int("weight", weight))
+	return nil
+}
+
+// SetEnabled puts a backend in or out of rotation regardless of its health.
+func (s *backendSet) SetEnabled(serverURL string, enabled bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.index(serverURL)
+	if i == -1 {
+		return fmt.Errorf("%w: %s", ErrBackendNotFound, serverURL)
+	}
+	s.disabled[i] = !enabled
+	s.rebuild()
+
+	s.logger.Info("Backend rotation changed", zap.String("backend_url", serverURL), zap.Bool("enabled", enabled))
+	return nil
+}
+
+// SetDraining stops or resumes giving new requests to a backend. Requests it is
+// already serving are left to finish.
+func (s *backendSet) SetDraining(serverURL string, draining bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.index(serverURL)
+	if i == -1 {
+		return fmt.Errorf("%w: %s", ErrBackendNotFound, serverURL)
+	}
+	s.clients[i].SetDraining(draining)
+	s.rebuild()
+
+	s.logger.Info("Backend drain changed", zap.String("backend_url", serverURL), zap.Bool("draining", draining))
+	return nil
+}
+
+func (s *backendSet) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go s.checker.Watch(ctx, s.Clients, interval, s.updateAvailableClients)
+}
+
+func (s *backendSet) updateAvailableClients() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rebuild()
+}
+
+// available returns the enabled clients that are up, with their weights. The caller
+// holds the mutex.
+func (s *backendSet) available() ([]health.HTTPClient, []int) {
+	available := make([]health.HTTPClient, 0, len(s.clients))
+	var weights []int
+	for i, client := range s.clients {
+		if client.State() == health.StateUp && !s.disabled[i] {
+			available = append(available, client)
+			weights = append(weights, s.weights[i])
+		}
+	}
+	return available, weights
+}
+
+func (s *backendSet) index(serverURL string) int {
+	for i, client := range s.clients {
+		if client.GetBaseURL() == serverURL {
+			return i
+		}
+	}
+	return -1
+}
+
+func checkWeight(weight int) error {
+	if weight < 0 {
+		return fmt.Errorf("weight cannot be negative, got %d", weight)
+	}
+	if weight > config.MaxWeight {
+		return fmt.Errorf("weight cannot be more than %d, got %d", config.MaxWeight, weight)
+	}
+	return nil
+}
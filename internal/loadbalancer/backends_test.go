@@ -0,0 +1,102 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendSet_RuntimeChanges(t *testing.T) {
+	for _, balancerType := range []string{"round-robin", "least-connections"} {
+		t.Run(balancerType, func(t *testing.T) {
+			config := testBalancerConfig([]string{"http://a", "http://b"}, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute})
+			config.Type = balancerType
+			balancer := NewLoadBalancerFactory().CreateLoadBalancerWithConfig(config, &testLogger{})
+			adapter := NewLoadBalancerAdapter(balancer)
+
+			require.NoError(t, balancer.SetEnabled("http://a", false))
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, "http://b", balancer.Next().GetBaseURL())
+			}
+
+			require.NoError(t, balancer.AddBackend("http://c", 1))
+			assert.ErrorIs(t, balancer.AddBackend("http://c", 1), ErrBackendExists)
+			assert.EqualError(t, balancer.AddBackend("http://d", 1001), "weight cannot be more than 1000, got 1001")
+			assert.EqualError(t, balancer.SetWeight("http://c", 1001), "weight cannot be more than 1000, got 1001")
+			require.NoError(t, balancer.SetWeight("http://b", 0))
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, "http://c", balancer.Next().GetBaseURL())
+			}
+
+			client, err := balancer.RemoveBackend("http://c")
+			require.NoError(t, err)
+			assert.Equal(t, "http://c", client.GetBaseURL())
+			_, err = balancer.RemoveBackend("http://c")
+			assert.ErrorIs(t, err, ErrBackendNotFound)
+			assert.Nil(t, balancer.Next())
+			assert.False(t, adapter.HasHealthyBackend())
+
+			require.NoError(t, balancer.SetEnabled("http://a", true))
+			assert.Equal(t, "http://a", balancer.Next().GetBaseURL())
+			assert.True(t, adapter.HasHealthyBackend())
+			assert.Equal(t, []Backend{
+				{URL: "http://a", Weight: 1, Enabled: true, Client: balancer.Clients()[0]},
+				{URL: "http://b", Weight: 0, Enabled: true, Client: balancer.Clients()[1]},
+			}, balancer.Backends())
+		})
+	}
+}
+
+func TestBackendSet_Draining(t *testing.T) {
+	for _, balancerType := range []string{"round-robin", "least-connections"} {
+		t.Run(balancerType, func(t *testing.T) {
+			config := testBalancerConfig([]string{"http://a", "http://b"}, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute})
+			config.Type = balancerType
+			balancer := NewLoadBalancerFactory().CreateLoadBalancerWithConfig(config, &testLogger{})
+			adapter := NewLoadBalancerAdapter(balancer)
+
+			require.NoError(t, balancer.SetDraining("http://a", true))
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, "http://b", balancer.Next().GetBaseURL())
+			}
+			require.NoError(t, balancer.SetEnabled("http://b", false))
+			assert.False(t, adapter.HasHealthyBackend())
+			require.NoError(t, balancer.SetEnabled("http://b", true))
+
+			balancer.Clients()[1].SetUp(false)
+			balancer.(interface{ updateAvailableClients() }).updateAvailableClients()
+			assert.Nil(t, balancer.Next())
+			assert.False(t, adapter.HasHealthyBackend())
+
+			require.NoError(t, balancer.SetDraining("http://a", false))
+			assert.Equal(t, "http://a", balancer.Next().GetBaseURL())
+			assert.ErrorIs(t, balancer.SetDraining("http://c", true), ErrBackendNotFound)
+		})
+	}
+}
+
+func TestBackendSet_PendingBackendWaitsForHealthCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	balancer := newRoundRobinLoadBalancer(testBalancerConfig(nil, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute}), &testLogger{})
+
+	require.NoError(t, balancer.AddPendingBackend(unhealthy.URL, 1))
+	assert.Never(t, func() bool { return balancer.Next() != nil }, 100*time.Millisecond, 10*time.Millisecond)
+
+	require.NoError(t, balancer.AddPendingBackend(healthy.URL, 1))
+	assert.Eventually(t, func() bool { return balancer.Next() != nil }, 2*time.Second, 10*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, healthy.URL, balancer.Next().GetBaseURL())
+	}
+}
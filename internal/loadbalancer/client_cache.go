@@ -0,0 +1,81 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/health"
+)
+
+// ClientCache hands out backend clients keyed by pool, URL and settings. A cache made with
+// Rebuild reuses the clients of the cache it was made from, so balancers built from a
+// new configuration keep the health and circuit state of backends that did not change.
+type ClientCache struct {
+	mutex    sync.Mutex
+	clients  map[string]health.HTTPClient
+	previous map[string]health.HTTPClient
+}
+
+func NewClientCache() *ClientCache {
+	return &ClientCache{clients: make(map[string]health.HTTPClient)}
+}
+
+func (c *ClientCache) Rebuild() *ClientCache {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	previous := make(map[string]health.HTTPClient, len(c.clients))
+	for key, client := range c.clients {
+		previous[key] = client
+	}
+	return &ClientCache{
+		clients:  make(map[string]health.HTTPClient),
+		previous: previous,
+	}
+}
+
+// Retired returns the clients of the previous cache that were not reused.
+func (c *ClientCache) Retired() []health.HTTPClient {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var retired []health.HTTPClient
+	for key, client := range c.previous {
+		if _, ok := c.clients[key]; !ok {
+			retired = append(retired, client)
+		}
+	}
+	return retired
+}
+
+// client returns the client for server, and whether it was made rather than
+// reused.
+func (c *ClientCache) client(server string, config BalancerConfig) (health.HTTPClient, bool) {
+	key := fmt.Sprintf("%s|%s|%+v|%+v|%+v", config.Pool, server, config.Circuit, config.Transport, config.HealthCheck)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if client, ok := c.clients[key]; ok {
+		return client, false
+	}
+	client, ok := c.previous[key]
+	if !ok {
+		client = newBackendClient(server, config.Circuit, config.Transport)
+	}
+	c.clients[key] = client
+	return client, !ok
+}
+
+func newBackendClient(serverURL string, circuitConfig circuit.CircuitBreakerConfig, transportConfig health.TransportConfig) health.HTTPClient {
+	baseClient := &health.DefaultHTTPClient{
+		Client: &http.Client{
+			Transport: health.NewTransport(transportConfig),
+		},
+		BaseURL: serverURL,
+		Up:      true,
+	}
+	return circuit.NewCircuitBreakerClient(baseClient, circuitConfig)
+}
@@ -0,0 +1,58 @@
+package loadbalancer
+
+import (
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCache_Rebuild(t *testing.T) {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	config := testBalancerConfig([]string{"http://a:8080", "http://b:8080"}, circuitConfig)
+	config.Clients = NewClientCache()
+	first := newBackendClients(config)
+
+	rebuilt := config.Clients.Rebuild()
+	config.Clients = rebuilt
+	config.Servers = []string{"http://a:8080", "http://c:8080"}
+	second := newBackendClients(config)
+
+	assert.Same(t, first[0], second[0])
+	assert.NotSame(t, first[1], second[1])
+	assert.Equal(t, []string{"http://b:8080"}, baseURLs(rebuilt.Retired()))
+
+	config.Clients = rebuilt.Rebuild()
+	config.Servers = []string{"http://a:8080"}
+	config.Circuit.MaxFailures = 10
+	third := newBackendClients(config)
+	assert.NotSame(t, first[0], third[0])
+	assert.ElementsMatch(t, []string{"http://a:8080", "http://c:8080"}, baseURLs(config.Clients.Retired()))
+}
+
+func TestClientCache_ClientsPerPool(t *testing.T) {
+	config := testBalancerConfig([]string{"http://a:8080"}, circuit.CircuitBreakerConfig{MaxFailures: 5})
+	config.Clients = NewClientCache()
+	config.Pool = "default"
+	first := newBackendClients(config)
+	config.Pool = "other"
+	other := newBackendClients(config)
+
+	assert.NotSame(t, first[0], other[0])
+	config.Pool = "default"
+	assert.Same(t, first[0], newBackendClients(config)[0])
+}
+
+func baseURLs(clients []health.HTTPClient) []string {
+	urls := make([]string, len(clients))
+	for i, client := range clients {
+		urls[i] = client.GetBaseURL()
+	}
+	return urls
+}
@@ -0,0 +1,87 @@
+package loadbalancer
+
+import (
+	"context"
+	"time"
+
+	"routing-api/internal/discovery"
+	"routing-api/internal/health"
+
+	"go.uber.org/zap"
+)
+
+// discoveryTimeout bounds the first lookup of a balancer's backends, which
+// holds up building the route table on startup and reload.
+var discoveryTimeout = 5 * time.Second
+
+// addDiscoveredBackends adds the backends the discovery source knows now. Like
+// those found later by WatchDiscovery, they get requests once they pass a health
+// check.
+func (s *backendSet) addDiscoveredBackends() {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	targets, err := s.config.Discovery.Targets(ctx)
+	if err != nil {
+		s.logger.Warn("Discovery found no backends yet", zap.Error(err))
+		return
+	}
+	for _, target := range targets {
+		if err := s.AddPendingBackend(target.URL, target.Weight); err != nil {
+			s.logger.Warn("Cannot add discovered backend", zap.String("backend_url", target.URL), zap.Error(err))
+		}
+	}
+}
+
+// WatchDiscovery keeps the backends in step with the balancer's discovery source
+// until ctx is done. New backends get requests once they pass a health check,
+// and removed ones are handed to drain.
+func (s *backendSet) WatchDiscovery(ctx context.Context, drain func(health.HTTPClient)) {
+	if s.config.Discovery == nil {
+		return
+	}
+	s.config.Discovery.Watch(ctx, func(targets []discovery.Target) {
+		s.reconcile(targets, drain)
+	})
+}
+
+func (s *backendSet) reconcile(targets []discovery.Target, drain func(health.HTTPClient)) {
+	wanted := make(map[string]int, len(targets))
+	for _, target := range targets {
+		wanted[target.URL] = target.Weight
+	}
+
+	var added, removed int
+	for _, backend := range s.Backends() {
+		weight, ok := wanted[backend.URL]
+		delete(wanted, backend.URL)
+		switch {
+		case !ok:
+			client, err := s.RemoveBackend(backend.URL)
+			if err != nil {
+				continue
+			}
+			removed++
+			go drain(client)
+		case weight != backend.Weight:
+			s.SetWeight(backend.URL, weight)
+		}
+	}
+	for _, target := range targets {
+		if _, ok := wanted[target.URL]; !ok {
+			continue
+		}
+		if err := s.AddPendingBackend(target.URL, target.Weight); err != nil {
+			s.logger.Warn("Cannot add discovered backend", zap.String("backend_url", target.URL), zap.Error(err))
+			continue
+		}
+		added++
+	}
+
+	if added > 0 || removed > 0 {
+		s.logger.Info("Discovered backends changed",
+			zap.Int("added", added),
+			zap.Int("removed", removed),
+			zap.Int("backends", len(targets)),
+		)
+	}
+}
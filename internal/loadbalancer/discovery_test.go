@@ -0,0 +1,129 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/discovery"
+	"routing-api/internal/health"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// channelProvider hands out the backend sets sent to it.
+type channelProvider struct {
+	initial []discovery.Target
+	updates chan []discovery.Target
+}
+
+func (p *channelProvider) Targets(ctx context.Context) ([]discovery.Target, error) {
+	if p.initial == nil {
+		return nil, errors.New("registry unavailable")
+	}
+	return p.initial, nil
+}
+
+func (p *channelProvider) Watch(ctx context.Context, update func([]discovery.Target)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case targets := <-p.updates:
+			update(targets)
+		}
+	}
+}
+
+func TestLoadBalancerFactory_FollowsDiscovery(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer first.Close()
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer second.Close()
+
+	for _, balancerType := range []string{"round-robin", "least-connections"} {
+		t.Run(balancerType, func(t *testing.T) {
+			provider := &channelProvider{
+				initial: []discovery.Target{{URL: first.URL, Weight: 2}},
+				updates: make(chan []discovery.Target),
+			}
+			config := testBalancerConfig([]string{"http://ignored"}, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute})
+			config.Type = balancerType
+			config.Discovery = provider
+			balancer := NewLoadBalancerFactory().CreateLoadBalancerWithConfig(config, &testLogger{})
+
+			require.Len(t, balancer.Backends(), 1)
+			assert.Equal(t, first.URL, balancer.Backends()[0].URL)
+			assert.Equal(t, 2, balancer.Backends()[0].Weight)
+			assert.Eventually(t, func() bool { return balancer.Next() != nil }, 2*time.Second, 10*time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			drained := make(chan health.HTTPClient, 1)
+			go balancer.WatchDiscovery(ctx, func(client health.HTTPClient) { drained <- client })
+
+			provider.updates <- []discovery.Target{{URL: first.URL, Weight: 1}, {URL: second.URL, Weight: 1}}
+			assert.Eventually(t, func() bool {
+				backends := balancer.Backends()
+				return len(backends) == 2 && backends[0].Weight == 1 && backends[1].Client.State() == health.StateUp
+			}, 2*time.Second, 10*time.Millisecond)
+
+			provider.updates <- []discovery.Target{{URL: second.URL, Weight: 1}}
+			assert.Equal(t, first.URL, (<-drained).GetBaseURL())
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, second.URL, balancer.Next().GetBaseURL())
+			}
+		})
+	}
+}
+
+func TestLoadBalancerFactory_DiscoveryUnavailableAtStart(t *testing.T) {
+	config := testBalancerConfig([]string{"http://ignored"}, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute})
+	config.Discovery = &channelProvider{}
+	balancer := NewLoadBalancerFactory().CreateLoadBalancerWithConfig(config, &testLogger{})
+
+	assert.Empty(t, balancer.Backends())
+	assert.Nil(t, balancer.Next())
+}
+
+// blockingProvider stands for a registry that does not answer.
+type blockingProvider struct{}
+
+func (blockingProvider) Targets(ctx context.Context) ([]discovery.Target, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingProvider) Watch(ctx context.Context, update func([]discovery.Target)) {}
+
+func TestLoadBalancerFactory_DiscoveryTimesOut(t *testing.T) {
+	defer func(timeout time.Duration) { discoveryTimeout = timeout }(discoveryTimeout)
+	discoveryTimeout = 50 * time.Millisecond
+
+	config := testBalancerConfig(nil, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute})
+	config.Discovery = blockingProvider{}
+	start := time.Now()
+	balancer := NewLoadBalancerFactory().CreateLoadBalancerWithConfig(config, &testLogger{})
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Empty(t, balancer.Backends())
+}
+
+func TestLoadBalancerFactory_DiscoveredBackendsWaitForHealthCheck(t *testing.T) {
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	config := testBalancerConfig(nil, circuit.CircuitBreakerConfig{MaxFailures: 5, ResetTimeout: time.Minute})
+	config.Discovery = &channelProvider{initial: []discovery.Target{{URL: unhealthy.URL, Weight: 1}}}
+	balancer := NewLoadBalancerFactory().CreateLoadBalancerWithConfig(config, &testLogger{})
+
+	require.Len(t, balancer.Backends(), 1)
+	assert.Never(t, func() bool { return balancer.Next() != nil }, 100*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,56 @@
+package loadbalancer
+
+import (
+	"routing-api/internal/health"
+	"routing-api/internal/logger"
+)
+
+type leastConnectionsLoadBalancer struct {
+	backendSet
+	availableClients []health.HTTPClient
+	availableWeights []int
+	offset           int
+}
+
+func newLeastConnectionsLoadBalancer(config BalancerConfig, logger logger.Logger) *leastConnectionsLoadBalancer {
+	balancer := &leastConnectionsLoadBalancer{}
+	balancer.init(config, logger, balancer.rebuild)
+	return balancer
+}
+
+func (l *leastConnectionsLoadBalancer) Next() health.HTTPClient {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	count := len(l.availableClients)
+	if count == 0 {
+		return nil
+	}
+
+	// Start scanning at a rotating offset so ties are spread evenly. Clients are
+	// compared by in-flight requests per unit of weight.
+	var selected health.HTTPClient
+	var lowest int64
+	var lowestWeight int64
+	for i := 0; i < count; i++ {
+		index := (l.offset + i) % count
+		weight := int64(l.availableWeights[index])
+		if weight == 0 {
+			continue
+		}
+		client := l.availableClients[index]
+		inFlight := client.InFlight()
+		if selected == nil || inFlight*lowestWeight < lowest*weight {
+			selected = client
+			lowest = inFlight
+			lowestWeight = weight
+		}
+	}
+	l.offset = (l.offset + 1) % count
+	return selected
+}
+
+func (l *leastConnectionsLoadBalancer) rebuild() {
+	l.availableClients, l.availableWeights = l.available()
+	l.offset = 0
+}
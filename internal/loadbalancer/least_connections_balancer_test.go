@@ -0,0 +1,84 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeastConnectionsLoadBalancer_PrefersIdleBackend(t *testing.T) {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	balancer := newLeastConnectionsLoadBalancer(testBalancerConfig([]string{server.URL, server.URL + "/"}, circuitConfig), &testLogger{})
+
+	busy := balancer.Next()
+	req, _ := http.NewRequest("GET", "/", nil)
+	resp, err := busy.Do(req)
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		assert.NotSame(t, busy, balancer.Next())
+	}
+
+	resp.Body.Close()
+	first := balancer.Next()
+	second := balancer.Next()
+	assert.NotSame(t, first, second)
+}
+
+func TestLeastConnectionsLoadBalancer_NoAvailableClients(t *testing.T) {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+
+	balancer := newLeastConnectionsLoadBalancer(testBalancerConfig([]string{"http://localhost:8080"}, circuitConfig), &testLogger{})
+	balancer.clients[0].SetUp(false)
+	balancer.updateAvailableClients()
+
+	assert.Nil(t, balancer.Next())
+}
+
+func TestLeastConnectionsLoadBalancer_Weights(t *testing.T) {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	heavy, light, off := server.URL, server.URL+"/", server.URL+"/off"
+	config := testBalancerConfig([]string{heavy, light, off}, circuitConfig)
+	config.Weights = map[string]int{heavy: 3, off: 0}
+	balancer := newLeastConnectionsLoadBalancer(config, &testLogger{})
+
+	// With in-flight requests held open, the weight 3 backend takes three for every one on the other.
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		client := balancer.Next()
+		req, _ := http.NewRequest("GET", "/", nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		counts[client.GetBaseURL()]++
+	}
+
+	assert.Equal(t, map[string]int{heavy: 6, light: 2}, counts)
+}
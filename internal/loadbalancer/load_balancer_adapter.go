@@ -24,3 +24,23 @@ func (a *loadBalancerAdapter) GetClient() health.HTTPClient {
 func (a *loadBalancerAdapter) StartHealthChecks(ctx context.Context, interval time.Duration) {
 	a.loadBalancer.StartHealthChecks(ctx, interval)
 }
+
+// HasHealthyBackend reports whether Next can pick a backend: one that is up, is
+// not draining and has not been taken out of rotation.
+func (a *loadBalancerAdapter) HasHealthyBackend() bool {
+	for _, backend := range a.loadBalancer.Backends() {
+		if backend.Client.State() == health.StateUp && backend.Enabled && backend.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *loadBalancerAdapter) Status() []health.BackendStatus {
+	clients := a.loadBalancer.Clients()
+	statuses := make([]health.BackendStatus, len(clients))
+	for i, client := range clients {
+		statuses[i] = client.Status()
+	}
+	return statuses
+}
@@ -2,9 +2,31 @@ package loadbalancer
 
 import (
 	"routing-api/internal/circuit"
+	"routing-api/internal/discovery"
+	"routing-api/internal/health"
 	"routing-api/internal/logger"
 )
 
+type BalancerConfig struct {
+	// Pool names the pool the balancer serves. A ClientCache gives each pool
+	// clients of its own, so drains and forced circuits stay within one pool.
+	Pool    string
+	Type    string
+	Servers []string
+	// Weights maps server URLs to their share of traffic. Servers without an entry
+	// have a weight of 1, and a weight of 0 takes a server out of rotation.
+	Weights     map[string]int
+	Circuit     circuit.CircuitBreakerConfig
+	Transport   health.TransportConfig
+	HealthCheck health.HealthCheckConfig
+	// Clients, when set, supplies the backend clients so they can be shared
+	// with balancers built from an earlier configuration.
+	Clients *ClientCache
+	// Discovery, when set, supplies the servers instead of Servers and Weights
+	// and keeps them up to date through WatchDiscovery.
+	Discovery discovery.Provider
+}
+
 type LoadBalancerFactory struct{}
 
 func NewLoadBalancerFactory() *LoadBalancerFactory {
@@ -12,10 +34,31 @@ func NewLoadBalancerFactory() *LoadBalancerFactory {
 }
 
 func (f *LoadBalancerFactory) CreateLoadBalancer(balancerType string, servers []string, circuitConfig circuit.CircuitBreakerConfig, logger logger.Logger) LoadBalancer {
-	switch balancerType {
-	case "round-robin":
-		return newRoundRobinLoadBalancer(servers, circuitConfig, logger)
+	return f.CreateLoadBalancerWithConfig(BalancerConfig{
+		Type:        balancerType,
+		Servers:     servers,
+		Circuit:     circuitConfig,
+		Transport:   health.DefaultTransportConfig(),
+		HealthCheck: health.DefaultHealthCheckConfig(),
+	}, logger)
+}
+
+func (f *LoadBalancerFactory) CreateLoadBalancerWithConfig(config BalancerConfig, logger logger.Logger) LoadBalancer {
+	if config.Discovery != nil {
+		config.Servers, config.Weights = nil, nil
+	}
+	var balancer interface {
+		LoadBalancer
+		addDiscoveredBackends()
+	}
+	switch config.Type {
+	case "least-connections":
+		balancer = newLeastConnectionsLoadBalancer(config, logger)
 	default:
-		return newRoundRobinLoadBalancer(servers, circuitConfig, logger)
+		balancer = newRoundRobinLoadBalancer(config, logger)
+	}
+	if config.Discovery != nil {
+		balancer.addDiscoveredBackends()
 	}
+	return balancer
 }
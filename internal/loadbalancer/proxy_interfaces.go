@@ -10,9 +10,26 @@ import (
 type ClientProvider interface {
 	GetClient() health.HTTPClient
 	StartHealthChecks(ctx context.Context, interval time.Duration)
+	HasHealthyBackend() bool
+	Status() []health.BackendStatus
 }
 
 type LoadBalancer interface {
 	Next() health.HTTPClient
 	StartHealthChecks(ctx context.Context, interval time.Duration)
+	Clients() []health.HTTPClient
+	BackendManager
+}
+
+// BackendManager changes the backends of a balancer at runtime. Backends are
+// identified by their URL.
+type BackendManager interface {
+	Backends() []Backend
+	AddBackend(serverURL string, weight int) error
+	AddPendingBackend(serverURL string, weight int) error
+	RemoveBackend(serverURL string) (health.HTTPClient, error)
+	SetWeight(serverURL string, weight int) error
+	SetEnabled(serverURL string, enabled bool) error
+	SetDraining(serverURL string, draining bool) error
+	WatchDiscovery(ctx context.Context, drain func(health.HTTPClient))
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"routing-api/internal/circuit"
+	"routing-api/internal/health"
 	"routing-api/internal/logger"
 
 	"github.com/stretchr/testify/assert"
@@ -23,6 +24,15 @@ func (l *testLogger) Fatal(msg string, fields ...zap.Field)  {}
 func (l *testLogger) With(fields ...zap.Field) logger.Logger { return l }
 func (l *testLogger) Sync() error                            { return nil }
 
+func testBalancerConfig(servers []string, circuitConfig circuit.CircuitBreakerConfig) BalancerConfig {
+	return BalancerConfig{
+		Servers:     servers,
+		Circuit:     circuitConfig,
+		Transport:   health.DefaultTransportConfig(),
+		HealthCheck: health.DefaultHealthCheckConfig(),
+	}
+}
+
 func TestRoundRobinLoadBalancer_UpdateAvailableClients(t *testing.T) {
 	circuitConfig := circuit.CircuitBreakerConfig{
 		MaxFailures:  5,
@@ -39,7 +49,7 @@ func TestRoundRobinLoadBalancer_UpdateAvailableClients(t *testing.T) {
 	}))
 	defer server2.Close()
 
-	balancer := newRoundRobinLoadBalancer([]string{server1.URL, server2.URL}, circuitConfig, &testLogger{})
+	balancer := newRoundRobinLoadBalancer(testBalancerConfig([]string{server1.URL, server2.URL}, circuitConfig), &testLogger{})
 	assert.Equal(t, 2, len(balancer.availableClients))
 }
 
@@ -49,7 +59,7 @@ func TestRoundRobinLoadBalancer_AllClientsDown(t *testing.T) {
 		ResetTimeout: 60 * time.Second,
 	}
 
-	balancer := newRoundRobinLoadBalancer([]string{"http://localhost:8080", "http://localhost:8081"}, circuitConfig, &testLogger{})
+	balancer := newRoundRobinLoadBalancer(testBalancerConfig([]string{"http://localhost:8080", "http://localhost:8081"}, circuitConfig), &testLogger{})
 	assert.Equal(t, 2, len(balancer.availableClients))
 
 	client := balancer.Next()
@@ -72,7 +82,7 @@ func TestRoundRobinLoadBalancer_IndexManagement(t *testing.T) {
 	}))
 	defer server2.Close()
 
-	balancer := newRoundRobinLoadBalancer([]string{server1.URL, server2.URL}, circuitConfig, &testLogger{})
+	balancer := newRoundRobinLoadBalancer(testBalancerConfig([]string{server1.URL, server2.URL}, circuitConfig), &testLogger{})
 
 	client1 := balancer.Next()
 	client2 := balancer.Next()
@@ -103,7 +113,7 @@ func TestRoundRobinLoadBalancer_IndexAdjustmentOnClientRemoval(t *testing.T) {
 	}))
 	defer server2.Close()
 
-	balancer := newRoundRobinLoadBalancer([]string{server1.URL, server2.URL}, circuitConfig, &testLogger{})
+	balancer := newRoundRobinLoadBalancer(testBalancerConfig([]string{server1.URL, server2.URL}, circuitConfig), &testLogger{})
 	balancer.currentIndex = 1
 	balancer.updateAvailableClients()
 
@@ -121,7 +131,7 @@ func TestRoundRobinLoadBalancer_ConcurrentAccess(t *testing.T) {
 	}))
 	defer server.Close()
 
-	balancer := newRoundRobinLoadBalancer([]string{server.URL}, circuitConfig, &testLogger{})
+	balancer := newRoundRobinLoadBalancer(testBalancerConfig([]string{server.URL}, circuitConfig), &testLogger{})
 
 	done := make(chan bool, 2)
 
@@ -146,3 +156,29 @@ func TestRoundRobinLoadBalancer_ConcurrentAccess(t *testing.T) {
 	client := balancer.Next()
 	assert.NotNil(t, client)
 }
+
+func TestRoundRobinLoadBalancer_Weights(t *testing.T) {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+
+	config := testBalancerConfig([]string{"http://a:8080", "http://b:8080", "http://c:8080"}, circuitConfig)
+	config.Weights = map[string]int{"http://a:8080": 4, "http://c:8080": 0}
+	balancer := newRoundRobinLoadBalancer(config, &testLogger{})
+
+	var picked []string
+	for i := 0; i < 10; i++ {
+		picked = append(picked, balancer.Next().GetBaseURL())
+	}
+	assert.Equal(t, []string{
+		"http://a:8080", "http://a:8080", "http://b:8080", "http://a:8080", "http://a:8080",
+		"http://a:8080", "http://a:8080", "http://b:8080", "http://a:8080", "http://a:8080",
+	}, picked)
+
+	balancer.clients[0].SetUp(false)
+	balancer.updateAvailableClients()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "http://b:8080", balancer.Next().GetBaseURL())
+	}
+}
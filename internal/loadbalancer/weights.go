@@ -0,0 +1,51 @@
+package loadbalancer
+
+import "routing-api/internal/health"
+
+func backendWeights(servers []string, weights map[string]int) []int {
+	result := make([]int, len(servers))
+	for i, server := range servers {
+		result[i] = 1
+		if weight, ok := weights[server]; ok {
+			result[i] = weight
+		}
+	}
+	return result
+}
+
+// weightedSchedule returns one cycle of smooth weighted round-robin over the clients,
+// in which each client appears in proportion to its weight and heavy clients are
+// interleaved with light ones. Equal weights give the clients in their original order.
+func weightedSchedule(clients []health.HTTPClient, weights []int) []health.HTTPClient {
+	divisor := 0
+	total := 0
+	for _, weight := range weights {
+		divisor = gcd(divisor, weight)
+		total += weight
+	}
+	if total == 0 {
+		return []health.HTTPClient{}
+	}
+
+	current := make([]int, len(clients))
+	schedule := make([]health.HTTPClient, 0, total/divisor)
+	for len(schedule) < cap(schedule) {
+		best := -1
+		for i, weight := range weights {
+			current[i] += weight / divisor
+			if weight > 0 && (best == -1 || current[i] > current[best]) {
+				best = i
+			}
+		}
+		current[best] -= total / divisor
+		schedule = append(schedule, clients[best])
+	}
+	return schedule
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
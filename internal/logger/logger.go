@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"fmt"
+	"sync/atomic"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -23,28 +26,84 @@ func (l *zapLogger) With(fields ...zap.Field) Logger {
 	return &zapLogger{Logger: l.Logger.With(fields...)}
 }
 
-var globalLogger Logger
+// Log formats.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
 
-func Init(level string) error {
-	var config zap.Config
+// Components whose log levels can be set apart from the global level.
+const (
+	ComponentProxy    = "proxy"
+	ComponentHealth   = "health"
+	ComponentCircuit  = "circuit"
+	ComponentBalancer = "balancer"
+)
 
-	if level == "development" {
-		config = zap.NewDevelopmentConfig()
-	} else {
+var Components = []string{ComponentProxy, ComponentHealth, ComponentCircuit, ComponentBalancer}
+
+var (
+	globalLogger Logger
+	globalLevel  = zap.NewAtomicLevel()
+	// componentLevels has an entry for every component, so it is only read
+	// after initialization.
+	componentLevels = make(map[string]*componentLevel)
+	nopLogger       = &zapLogger{Logger: zap.NewNop()}
+)
+
+func init() {
+	for _, component := range Components {
+		componentLevels[component] = &componentLevel{level: zap.NewAtomicLevel()}
+	}
+}
+
+// componentLevel follows the global level until a level of its own is set.
+type componentLevel struct {
+	level zap.AtomicLevel
+	set   atomic.Bool
+}
+
+func (c *componentLevel) Enabled(level zapcore.Level) bool {
+	if c.set.Load() {
+		return c.level.Enabled(level)
+	}
+	return globalLevel.Enabled(level)
+}
+
+// Init builds the global logger. The console format is meant for development:
+// it is easier to read and adds stack traces to warnings.
+func Init(level, format string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	var config zap.Config
+	switch format {
+	case FormatJSON, "":
 		config = zap.NewProductionConfig()
+	case FormatConsole:
+		config = zap.NewDevelopmentConfig()
+	default:
+		return fmt.Errorf("unknown log format %q", format)
 	}
 
-	config.Level = zap.NewAtomicLevelAt(parseLogLevel(level))
+	// The core lets every entry through; levelCore filters them, so that levels
+	// can be changed while running and differ by component.
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	config.EncoderConfig.CallerKey = "caller"
 	config.EncoderConfig.StacktraceKey = "stacktrace"
 
-	logger, err := config.Build()
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelCore{Core: core, enabler: globalLevel}
+	}))
 	if err != nil {
 		return err
 	}
 
+	globalLevel.SetLevel(parsed)
 	globalLogger = &zapLogger{Logger: logger}
 	zap.ReplaceGlobals(logger)
 	return nil
@@ -54,19 +113,109 @@ func Global() Logger {
 	return globalLogger
 }
 
-func parseLogLevel(level string) zapcore.Level {
+// Component returns a logger for one of Components, named after it and
+// filtered by its level. Loggers that were not built by Init are returned
+// unchanged, and a nil logger gives one that discards everything.
+func Component(l Logger, component string) Logger {
+	if l == nil {
+		return nopLogger
+	}
+	z, ok := l.(*zapLogger)
+	level, known := componentLevels[component]
+	if !ok || !known {
+		return l
+	}
+	return &zapLogger{Logger: z.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if filtered, ok := core.(*levelCore); ok {
+			core = filtered.Core
+		}
+		return &levelCore{Core: core, enabler: level}
+	})).Named(component)}
+}
+
+// ParseLevel accepts debug, info, warn and error.
+func ParseLevel(level string) (zapcore.Level, error) {
 	switch level {
 	case "debug":
-		return zapcore.DebugLevel
+		return zapcore.DebugLevel, nil
 	case "info":
-		return zapcore.InfoLevel
+		return zapcore.InfoLevel, nil
 	case "warn":
-		return zapcore.WarnLevel
+		return zapcore.WarnLevel, nil
 	case "error":
-		return zapcore.ErrorLevel
+		return zapcore.ErrorLevel, nil
 	default:
-		return zapcore.InfoLevel
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level %q, expected one of debug, info, warn, error", level)
+	}
+}
+
+// Level returns the global level.
+func Level() string {
+	return globalLevel.Level().String()
+}
+
+// SetLevel changes the global level, which applies to components that have no
+// level of their own.
+func SetLevel(level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	globalLevel.SetLevel(parsed)
+	return nil
+}
+
+// SetComponentLevel gives a component a level of its own, or makes it follow
+// the global level again when level is empty.
+func SetComponentLevel(component, level string) error {
+	c, ok := componentLevels[component]
+	if !ok {
+		return fmt.Errorf("unknown log component %q", component)
+	}
+	if level == "" {
+		c.set.Store(false)
+		return nil
+	}
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	c.level.SetLevel(parsed)
+	c.set.Store(true)
+	return nil
+}
+
+// ComponentLevels returns the levels of the components that have one of their
+// own.
+func ComponentLevels() map[string]string {
+	levels := make(map[string]string)
+	for component, c := range componentLevels {
+		if c.set.Load() {
+			levels[component] = c.level.Level().String()
+		}
+	}
+	return levels
+}
+
+// levelCore drops entries below the level of enabler.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.enabler.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(entry.Level) {
+		return checked
 	}
+	return c.Core.Check(entry, checked)
 }
 
 func Sync() {
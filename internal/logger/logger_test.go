@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObservedLogger builds a logger the way Init does, recording entries
+// instead of writing them.
+func newObservedLogger(t *testing.T) (Logger, *observer.ObservedLogs) {
+	t.Helper()
+	t.Cleanup(func() {
+		globalLevel.SetLevel(zapcore.InfoLevel)
+		for _, component := range Components {
+			SetComponentLevel(component, "")
+		}
+	})
+	core, logs := observer.New(zapcore.DebugLevel)
+	return &zapLogger{Logger: zap.New(&levelCore{Core: core, enabler: globalLevel})}, logs
+}
+
+func messages(logs *observer.ObservedLogs) []string {
+	var result []string
+	for _, entry := range logs.TakeAll() {
+		result = append(result, entry.LoggerName+": "+entry.Message)
+	}
+	return result
+}
+
+func TestSetLevel(t *testing.T) {
+	log, logs := newObservedLogger(t)
+
+	log.Debug("hidden")
+	log.Info("shown")
+	require.NoError(t, SetLevel("debug"))
+	log.With(zap.String("pool", "default")).Debug("debug")
+	require.NoError(t, SetLevel("error"))
+	log.Warn("hidden")
+
+	assert.Equal(t, []string{": shown", ": debug"}, messages(logs))
+	assert.Equal(t, "error", Level())
+	assert.EqualError(t, SetLevel("development"), `unknown log level "development", expected one of debug, info, warn, error`)
+}
+
+func TestComponent(t *testing.T) {
+	log, logs := newObservedLogger(t)
+	proxy := Component(log.With(zap.String("route", "/")), ComponentProxy)
+	health := Component(log, ComponentHealth)
+
+	proxy.Debug("hidden")
+	require.NoError(t, SetComponentLevel(ComponentProxy, "debug"))
+	proxy.Debug("proxy debug")
+	log.Debug("hidden")
+	health.Debug("hidden")
+
+	require.NoError(t, SetLevel("warn"))
+	health.Info("hidden")
+	proxy.Info("proxy info")
+	assert.Equal(t, map[string]string{ComponentProxy: "debug"}, ComponentLevels())
+
+	require.NoError(t, SetComponentLevel(ComponentProxy, ""))
+	proxy.Info("hidden")
+	health.Warn("health warn")
+
+	assert.Equal(t, []string{"proxy: proxy debug", "proxy: proxy info", "health: health warn"}, messages(logs))
+	assert.Empty(t, ComponentLevels())
+	assert.Error(t, SetComponentLevel("router", "debug"))
+	assert.Error(t, SetComponentLevel(ComponentProxy, "trace"))
+}
+
+func TestComponent_OtherLoggers(t *testing.T) {
+	assert.Equal(t, nopLogger, Component(nil, ComponentCircuit))
+
+	log, _ := newObservedLogger(t)
+	assert.Equal(t, log, Component(log, "router"))
+}
+
+func TestInit(t *testing.T) {
+	t.Cleanup(func() { globalLevel.SetLevel(zapcore.InfoLevel) })
+	assert.Error(t, Init("verbose", FormatJSON))
+	assert.Error(t, Init("info", "text"))
+
+	require.NoError(t, Init("warn", FormatConsole))
+	assert.Equal(t, "warn", Level())
+	assert.NotNil(t, Global())
+}
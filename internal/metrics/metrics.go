@@ -0,0 +1,423 @@
+// Package metrics keeps counters, gauges and histograms and serves them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets in seconds, suited to request latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry the instrumented packages record to.
+var Default = NewRegistry()
+
+// labelSeparator joins label values into a series key. It cannot appear in
+// valid UTF-8 text.
+const labelSeparator = "\xff"
+
+type family interface {
+	write(w *bufio.Writer)
+	delete(label, value string)
+}
+
+// Registry holds metric families in the order they were created.
+type Registry struct {
+	mutex    sync.Mutex
+	names    map[string]bool
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, f family) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.names[name] {
+		panic("metrics: " + name + " is already registered")
+	}
+	r.names[name] = true
+	r.families = append(r.families, f)
+}
+
+// WriteTo writes every metric in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	families := slices.Clone(r.families)
+	r.mutex.Unlock()
+
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counter)
+	for _, f := range families {
+		f.write(buffered)
+	}
+	err := buffered.Flush()
+	return counter.n, err
+}
+
+// Delete removes the series of every family whose label has the given value, so
+// that label values that are gone for good, such as removed backends, are no
+// longer reported.
+func (r *Registry) Delete(label, value string) {
+	r.mutex.Lock()
+	families := slices.Clone(r.families)
+	r.mutex.Unlock()
+
+	for _, f := range families {
+		f.delete(label, value)
+	}
+}
+
+// Handler serves the registry to Prometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// vec holds the series of a family, keyed by their label values.
+type vec[T any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	mutex  sync.RWMutex
+	series map[string]*T
+	values map[string][]string
+	create func() *T
+}
+
+func newVec[T any](name, help, kind string, labels []string, create func() *T) *vec[T] {
+	return &vec[T]{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*T),
+		values: make(map[string][]string),
+		create: create,
+	}
+}
+
+func (v *vec[T]) with(labelValues []string) *T {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, labelSeparator)
+
+	v.mutex.RLock()
+	series, ok := v.series[key]
+	v.mutex.RUnlock()
+	if ok {
+		return series
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if series, ok := v.series[key]; ok {
+		return series
+	}
+	series = v.create()
+	v.series[key] = series
+	v.values[key] = slices.Clone(labelValues)
+	return series
+}
+
+func (v *vec[T]) delete(label, value string) {
+	i := slices.Index(v.labels, label)
+	if i < 0 {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for key, values := range v.values {
+		if values[i] == value {
+			delete(v.series, key)
+			delete(v.values, key)
+		}
+	}
+}
+
+// each calls fn for every series in order of their label values.
+func (v *vec[T]) each(fn func(labelValues []string, series *T)) {
+	type entry struct {
+		values []string
+		series *T
+	}
+	v.mutex.RLock()
+	entries := make([]entry, 0, len(v.series))
+	for key, series := range v.series {
+		entries = append(entries, entry{v.values[key], series})
+	}
+	v.mutex.RUnlock()
+
+	slices.SortFunc(entries, func(a, b entry) int { return slices.Compare(a.values, b.values) })
+	for _, e := range entries {
+		fn(e.values, e.series)
+	}
+}
+
+func (v *vec[T]) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.kind)
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	mutex sync.Mutex
+	value float64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.mutex.Lock()
+	c.value += delta
+	c.mutex.Unlock()
+}
+
+func (c *Counter) Value() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.value
+}
+
+type CounterVec struct {
+	*vec[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newVec(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.register(name, v)
+	return v
+}
+
+func (v *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return v.with(labelValues)
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.each(func(labelValues []string, c *Counter) {
+		writeSample(w, v.name, v.labels, labelValues, "", "", c.Value())
+	})
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	mutex sync.Mutex
+	value float64
+}
+
+func (g *Gauge) Set(value float64) {
+	g.mutex.Lock()
+	g.value = value
+	g.mutex.Unlock()
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.mutex.Lock()
+	g.value += delta
+	g.mutex.Unlock()
+}
+
+func (g *Gauge) Inc() { g.Add(1) }
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) Value() float64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.value
+}
+
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{newVec(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	r.register(name, v)
+	return v
+}
+
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return v.with(labelValues)
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.each(func(labelValues []string, g *Gauge) {
+		writeSample(w, v.name, v.labels, labelValues, "", "", g.Value())
+	})
+}
+
+// GaugeFunc is a gauge whose series are read when the registry is written, for
+// values such as backend health that are already kept elsewhere.
+type GaugeFunc struct {
+	name    string
+	help    string
+	labels  []string
+	collect func(set func(value float64, labelValues ...string))
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(set func(value float64, labelValues ...string))) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, labels: labels, collect: collect}
+	r.register(name, g)
+	return g
+}
+
+// delete does nothing, as the series of a GaugeFunc are not kept.
+func (g *GaugeFunc) delete(label, value string) {}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, escapeHelp(g.help), g.name)
+	g.collect(func(value float64, labelValues ...string) {
+		writeSample(w, g.name, g.labels, labelValues, "", "", value)
+	})
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(value float64) {
+	i, _ := slices.BinarySearch(h.buckets, value)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.count
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	v := &HistogramVec{buckets: buckets}
+	v.vec = newVec(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})
+	r.register(name, v)
+	return v
+}
+
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return v.with(labelValues)
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	v.each(func(labelValues []string, h *Histogram) {
+		h.mutex.Lock()
+		counts := slices.Clone(h.counts)
+		count, sum := h.count, h.sum
+		h.mutex.Unlock()
+
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += counts[i]
+			writeSample(w, v.name+"_bucket", v.labels, labelValues, "le", formatFloat(bound), float64(cumulative))
+		}
+		writeSample(w, v.name+"_bucket", v.labels, labelValues, "le", "+Inf", float64(count))
+		writeSample(w, v.name+"_sum", v.labels, labelValues, "", "", sum)
+		writeSample(w, v.name+"_count", v.labels, labelValues, "", "", float64(count))
+	})
+}
+
+// writeSample writes one line, with an extra label such as a bucket's le when
+// extraName is set.
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label, labelValues[i])
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(labelEscaper.Replace(value))
+	w.WriteByte('"')
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Requests served.", "route", "code")
+	inFlight := registry.NewGaugeVec("in_flight", "Requests in progress.")
+	latency := registry.NewHistogramVec("latency_seconds", "Request latency.", []float64{1, 0.1}, "route")
+	registry.NewGaugeFunc("backend_up", "Backend health,\nby backend.", []string{"backend"}, func(set func(float64, ...string)) {
+		set(1, `http://a`)
+		set(0, `http://"b"\`)
+	})
+
+	requests.WithLabelValues("/orders", "2xx").Add(2)
+	requests.WithLabelValues("/", "5xx").Inc()
+	inFlight.WithLabelValues().Inc()
+	inFlight.WithLabelValues().Inc()
+	inFlight.WithLabelValues().Dec()
+	latency.WithLabelValues("/").Observe(0.05)
+	latency.WithLabelValues("/").Observe(0.5)
+	latency.WithLabelValues("/").Observe(1)
+	latency.WithLabelValues("/").Observe(4)
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/",code="5xx"} 1
+requests_total{route="/orders",code="2xx"} 2
+# HELP in_flight Requests in progress.
+# TYPE in_flight gauge
+in_flight 1
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/",le="0.1"} 1
+latency_seconds_bucket{route="/",le="1"} 3
+latency_seconds_bucket{route="/",le="+Inf"} 4
+latency_seconds_sum{route="/"} 5.55
+latency_seconds_count{route="/"} 4
+# HELP backend_up Backend health,\nby backend.
+# TYPE backend_up gauge
+backend_up{backend="http://a"} 1
+backend_up{backend="http://\"b\"\\"} 0
+`, recorder.Body.String())
+}
+
+func TestRegistry_RejectsMisuse(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Requests served.", "route")
+
+	assert.Panics(t, func() { registry.NewGaugeVec("requests_total", "Again.") })
+	assert.Panics(t, func() { requests.WithLabelValues() })
+	assert.Panics(t, func() { requests.WithLabelValues("/").Add(-1) })
+}
+
+func TestRegistry_Delete(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Requests served.", "route", "backend")
+	latency := registry.NewHistogramVec("latency_seconds", "Request latency.", []float64{1}, "backend")
+	inFlight := registry.NewGaugeVec("in_flight", "Requests in progress.", "route")
+
+	requests.WithLabelValues("/", "http://a").Inc()
+	requests.WithLabelValues("/orders", "http://a").Inc()
+	requests.WithLabelValues("/", "http://b").Inc()
+	latency.WithLabelValues("http://a").Observe(0.5)
+	inFlight.WithLabelValues("http://a").Inc()
+
+	registry.Delete("backend", "http://a")
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+	assert.Equal(t, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/",backend="http://b"} 1
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+# HELP in_flight Requests in progress.
+# TYPE in_flight gauge
+in_flight{route="http://a"} 1
+`, out.String())
+	assert.Equal(t, float64(0), requests.WithLabelValues("/", "http://a").Value())
+}
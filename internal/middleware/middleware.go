@@ -1,20 +1,87 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"net"
 	"net/http"
-
-	"go.uber.org/zap"
+	"net/netip"
+	"strings"
 )
 
-func LoggingMiddleware() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			next.ServeHTTP(w, r)
+// RequestIDHeader carries the ID that ties together the logs of a request in the
+// router and in the backends.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the inbound IDs that are accepted.
+const maxRequestIDLength = 128
+
+// RequestID makes sure every request has an X-Request-ID, keeping the caller's
+// when it is well formed and generating one otherwise. The ID is forwarded to
+// the backend with the request and echoed on the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		r.Header.Set(RequestIDHeader, requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r)
+	})
+}
 
-			zap.L().Info("ROUTING-API",
-				zap.String("method", r.Method),
-				zap.String("path", r.URL.Path),
-			)
-		})
+// validRequestID accepts IDs of up to 128 letters, digits and the punctuation
+// found in UUIDs, ULIDs and base64, so that an ID is safe to log and to copy into
+// other headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.:+/=@", c) >= 0) {
+			return false
+		}
 	}
+	return true
+}
+
+// newRequestID returns a random version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// LoopbackOnly rejects requests that do not come from the local machine.
+func LoopbackOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.IsLoopback() {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BearerToken rejects requests without an Authorization header carrying token.
+func BearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name    string
+		inbound string
+		keep    bool
+	}{
+		{name: "missing"},
+		{name: "UUID", inbound: "0f8fad5b-d9cb-469f-a165-70867728950e", keep: true},
+		{name: "opaque token", inbound: "edge:01HZX3K9/abc+def=", keep: true},
+		{name: "space", inbound: "abc def"},
+		{name: "quote", inbound: `abc"def`},
+		{name: "non-ASCII", inbound: "abcé"},
+		{name: "too long", inbound: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "longest", inbound: strings.Repeat("a", maxRequestIDLength), keep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r.Header.Get(RequestIDHeader)
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.inbound != "" {
+				req.Header.Set(RequestIDHeader, tt.inbound)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if tt.keep {
+				assert.Equal(t, tt.inbound, forwarded)
+			} else {
+				assert.Regexp(t, uuidPattern, forwarded)
+			}
+			assert.Equal(t, []string{forwarded}, recorder.Header().Values(RequestIDHeader))
+		})
+	}
+}
+
+func TestRequestID_Unique(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 1000 {
+		id := newRequestID()
+		assert.False(t, seen[id])
+		seen[id] = true
+	}
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/health"
+	"routing-api/internal/loadbalancer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newH2CServer(handler http.Handler) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	return server
+}
+
+func TestProxyRequest_GRPCOverH2C(t *testing.T) {
+	backend := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, "trailers", r.Header.Get("Te"))
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("payload"))
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set("Grpc-Message", "ok")
+	}))
+	defer backend.Close()
+
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	transportConfig := health.DefaultTransportConfig()
+	transportConfig.Protocol = health.ProtocolHTTP2
+
+	factory := loadbalancer.NewLoadBalancerFactory()
+	balancer := factory.CreateLoadBalancerWithConfig(loadbalancer.BalancerConfig{
+		Type:        "round-robin",
+		Servers:     []string{backend.URL},
+		Circuit:     circuitConfig,
+		Transport:   transportConfig,
+		HealthCheck: health.DefaultHealthCheckConfig(),
+	}, &testLogger{})
+	handler := NewProxyHandler(loadbalancer.NewLoadBalancerAdapter(balancer), &testLogger{})
+
+	front := newH2CServer(http.HandlerFunc(handler.ProxyRequest))
+	defer front.Close()
+
+	client := &http.Client{Transport: health.NewTransport(transportConfig)}
+	req, _ := http.NewRequest("POST", front.URL+"/helloworld.Greeter/SayHello", strings.NewReader("request"))
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "payload", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	assert.Equal(t, "ok", resp.Trailer.Get("Grpc-Message"))
+}
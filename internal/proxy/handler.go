@@ -2,68 +2,161 @@ package proxy
 
 import (
 	"context"
-	"encoding/json"
-	"io"
+	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
+	"routing-api/internal/accesslog"
 	"routing-api/internal/circuit"
+	"routing-api/internal/health"
 	"routing-api/internal/loadbalancer"
 	"routing-api/internal/logger"
+	"routing-api/internal/middleware"
+	"routing-api/internal/tracing"
 
 	"go.uber.org/zap"
 )
 
-type HealthResponse struct {
-	Status string `json:"status"`
+type ProxyConfig struct {
+	// Route names the route served by the handler in metrics and traces.
+	Route              string
+	RequestTimeout     time.Duration
+	FlushInterval      time.Duration
+	UpgradeIdleTimeout time.Duration
+	UpgradeMaxLifetime time.Duration
+	// Tracer records spans of proxied requests; nil turns tracing off.
+	Tracer  *tracing.Tracer
+	Mirror  *MirrorConfig
+	Rewrite *RewriteConfig
 }
 
 type ProxyHandler struct {
 	clientProvider loadbalancer.ClientProvider
+	config         ProxyConfig
+	mirror         *mirror
 	logger         logger.Logger
 }
 
 func NewProxyHandler(clientProvider loadbalancer.ClientProvider, logger logger.Logger) *ProxyHandler {
-	return &ProxyHandler{
-		clientProvider: clientProvider,
-		logger:         logger,
+	config := ProxyConfig{
+		RequestTimeout:     30 * time.Second,
+		UpgradeIdleTimeout: 60 * time.Second,
 	}
+	return NewProxyHandlerWithConfig(clientProvider, config, logger)
 }
 
-func (h *ProxyHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{Status: "healthy"}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+func NewProxyHandlerWithConfig(clientProvider loadbalancer.ClientProvider, config ProxyConfig, log logger.Logger) *ProxyHandler {
+	return &ProxyHandler{
+		clientProvider: clientProvider,
+		config:         config,
+		mirror:         newMirror(config.Mirror, config.RequestTimeout),
+		logger:         logger.Component(log, logger.ComponentProxy),
+	}
 }
 
 func (h *ProxyHandler) ProxyRequest(w http.ResponseWriter, req *http.Request) {
 	log := h.logger
+	requestID := req.Header.Get(middleware.RequestIDHeader)
+	if requestID != "" {
+		log = log.With(zap.String("request_id", requestID))
+	}
+	route := h.config.Route
+	details := accesslog.FromContext(req.Context())
+	details.SetRoute(route)
+
+	start := time.Now()
+	inFlight := requestsInFlight.WithLabelValues(route)
+	inFlight.Inc()
+	var status int
+	var backendURL string
+	span := h.startServerSpan(req)
+	defer func() {
+		inFlight.Dec()
+		labels := []string{route, metricMethod(req.Method), statusClass(status), backendURL}
+		requestsTotal.WithLabelValues(labels...).Inc()
+		requestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		endSpan(span, status)
+	}()
 
 	client := h.clientProvider.GetClient()
 	if client == nil {
 		log.Error("No servers configured")
-		http.Error(w, "no servers configured", http.StatusInternalServerError)
+		details.SetError("no servers configured")
+		status = http.StatusInternalServerError
+		rejectedRequests.WithLabelValues(route, rejectNoBackend).Inc()
+		http.Error(w, "no servers configured", status)
 		return
 	}
+	backendURL = client.GetBaseURL()
+	details.SetBackend(backendURL)
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	var requestTimer *time.Timer
+	if h.config.RequestTimeout > 0 {
+		requestTimer = time.AfterFunc(h.config.RequestTimeout, cancel)
+		defer requestTimer.Stop()
+	}
 
-	resp, err := client.Do(req)
+	outReq := req.WithContext(ctx)
+	outReq.Header = outboundHeader(req.Header)
+	outReq.URL = &url.URL{
+		Path:     req.URL.Path,
+		RawPath:  req.URL.RawPath,
+		RawQuery: req.URL.RawQuery,
+	}
+	if h.config.Rewrite != nil {
+		h.config.Rewrite.rewriteRequest(outReq, req)
+	}
+	tracing.Inject(outReq.Header, span.Context())
+
+	if h.mirror != nil && h.mirror.sample(req) {
+		body, ok, err := bufferBody(outReq)
+		if err != nil {
+			log.Error("Cannot read request body", zap.String("path", req.URL.Path), zap.Error(err))
+			details.SetError("cannot read request body: " + err.Error())
+			status = http.StatusBadRequest
+			rejectedRequests.WithLabelValues(route, rejectBadRequest).Inc()
+			http.Error(w, "cannot read request body", status)
+			return
+		}
+		if ok {
+			h.mirror.send(outReq, body, log)
+		}
+	}
+
+	clientSpan := h.startClientSpan(span, client, outReq)
+	tracing.Inject(outReq.Header, clientSpan.Context())
+	upstreamStart := time.Now()
+	resp, err := client.Do(outReq)
+	details.SetUpstreamLatency(time.Since(upstreamStart))
 	if err != nil {
+		details.SetError(err.Error())
+		clientSpan.SetError(err.Error())
+		clientSpan.End()
 		log.Error("Cannot reach server",
 			zap.String("method", req.Method),
 			zap.String("path", req.URL.Path),
 			zap.Error(err),
 		)
-		
+
+		status = http.StatusBadGateway
 		if cbErr, ok := err.(*circuit.CircuitBreakerError); ok {
-			http.Error(w, cbErr.Message, http.StatusBadGateway)
+			if cbErr.Open {
+				rejectedRequests.WithLabelValues(route, rejectCircuitOpen).Inc()
+			}
+			http.Error(w, cbErr.Message, status)
 		} else {
-			http.Error(w, "cannot reach server", http.StatusBadGateway)
+			http.Error(w, "cannot reach server", status)
 		}
 		return
 	}
 	defer resp.Body.Close()
+	defer endSpan(clientSpan, resp.StatusCode)
 
-	backendURL := client.GetBaseURL()
+	status = resp.StatusCode
 	log.Info("ROUTING-API-BACKEND",
 		zap.String("method", req.Method),
 		zap.String("backend_url", backendURL),
@@ -71,21 +164,110 @@ func (h *ProxyHandler) ProxyRequest(w http.ResponseWriter, req *http.Request) {
 		zap.Int("status", resp.StatusCode),
 	)
 
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		if requestTimer != nil {
+			requestTimer.Stop()
 		}
+		if err := h.handleUpgradeResponse(w, req, resp, log); err != nil {
+			log.Error("Cannot proxy upgraded connection",
+				zap.String("method", req.Method),
+				zap.String("path", req.URL.Path),
+				zap.Error(err),
+			)
+			details.SetError(err.Error())
+			status = http.StatusBadGateway
+			http.Error(w, "cannot proxy upgraded connection", status)
+		}
+		return
+	}
+
+	removeHopByHopHeaders(resp.Header)
+	copyHeader(w.Header(), resp.Header)
+	if requestID != "" {
+		w.Header().Set(middleware.RequestIDHeader, requestID)
+	}
+	if h.config.Rewrite != nil {
+		h.config.Rewrite.rewriteResponse(w.Header(), req)
 	}
+	announced := len(resp.Trailer)
+	announceTrailers(w.Header(), resp.Trailer)
+
+	flushInterval := h.config.FlushInterval
+	if flushInterval == 0 && isStreamingResponse(resp) {
+		flushInterval = immediateFlush
+	}
+	if flushInterval != 0 {
+		if requestTimer != nil {
+			requestTimer.Stop()
+		}
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+
 	w.WriteHeader(resp.StatusCode)
 
-	_, err = io.Copy(w, resp.Body)
+	err = copyResponse(w, resp.Body, flushInterval)
 	if err != nil {
 		log.Error("Error copying response body",
 			zap.String("method", req.Method),
 			zap.String("path", req.URL.Path),
 			zap.Error(err),
 		)
+		details.SetError(err.Error())
+		return
+	}
+
+	copyTrailers(w.Header(), resp.Trailer, announced)
+}
+
+// startServerSpan begins the span of a request, continuing the caller's trace
+// when the request carries one.
+func (h *ProxyHandler) startServerSpan(req *http.Request) *tracing.Span {
+	name := req.Method
+	if h.config.Route != "" {
+		name += " " + h.config.Route
+	}
+	span := h.config.Tracer.StartSpan(name, tracing.SpanKindServer, tracing.Extract(req.Header))
+	if span.Recording() {
+		span.SetAttributes(
+			tracing.String("http.request.method", req.Method),
+			tracing.String("http.route", h.config.Route),
+			tracing.String("url.path", req.URL.Path),
+			tracing.String("server.address", req.Host),
+			tracing.String("client.address", req.RemoteAddr),
+			tracing.String("network.protocol.version", fmt.Sprintf("%d.%d", req.ProtoMajor, req.ProtoMinor)),
+		)
+		if userAgent := req.UserAgent(); userAgent != "" {
+			span.SetAttributes(tracing.String("user_agent.original", userAgent))
+		}
+	}
+	return span
+}
+
+// startClientSpan begins the span of the request to a backend.
+func (h *ProxyHandler) startClientSpan(parent *tracing.Span, client health.HTTPClient, outReq *http.Request) *tracing.Span {
+	span := h.config.Tracer.StartSpan(outReq.Method, tracing.SpanKindClient, parent.Context())
+	if span.Recording() {
+		backendURL := client.GetBaseURL()
+		span.SetAttributes(
+			tracing.String("http.request.method", outReq.Method),
+			tracing.String("url.full", backendURL+outReq.URL.RequestURI()),
+			tracing.String("routing_api.backend", backendURL),
+		)
+		if circuitState := client.Status().CircuitState; circuitState != "" {
+			span.SetAttributes(tracing.String("routing_api.circuit_state", circuitState))
+		}
+	}
+	return span
+}
+
+func endSpan(span *tracing.Span, status int) {
+	if status != 0 {
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+	}
+	if status >= 500 {
+		span.SetError(http.StatusText(status))
 	}
+	span.End()
 }
 
 func (h *ProxyHandler) StartHealthChecks(ctx context.Context, interval time.Duration) {
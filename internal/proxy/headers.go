@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func removeHopByHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
+
+func outboundHeader(inbound http.Header) http.Header {
+	header := inbound.Clone()
+	upgrade := upgradeType(inbound)
+	keepTrailers := false
+	for _, value := range inbound.Values("Te") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "trailers") {
+				keepTrailers = true
+			}
+		}
+	}
+
+	removeHopByHopHeaders(header)
+
+	if keepTrailers {
+		header.Set("Te", "trailers")
+	}
+	if upgrade != "" {
+		header.Set("Connection", "Upgrade")
+		header.Set("Upgrade", upgrade)
+	}
+	return header
+}
+
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+func announceTrailers(dst http.Header, trailer http.Header) {
+	for key := range trailer {
+		dst.Add("Trailer", key)
+	}
+}
+
+func copyTrailers(dst http.Header, trailer http.Header, announced int) {
+	prefix := ""
+	if len(trailer) != announced {
+		prefix = http.TrailerPrefix
+	}
+	for key, values := range trailer {
+		for _, value := range values {
+			dst.Add(prefix+key, value)
+		}
+	}
+}
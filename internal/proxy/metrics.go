@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+
+	"routing-api/internal/metrics"
+)
+
+// Reasons a request is answered without reaching a backend.
+const (
+	rejectNoBackend   = "no_backend"
+	rejectCircuitOpen = "circuit_open"
+	rejectBadRequest  = "bad_request"
+)
+
+var (
+	requestsTotal = metrics.Default.NewCounterVec("routing_api_requests_total",
+		"Proxied requests, by route, method, status class and backend.", "route", "method", "status_class", "backend")
+	requestDuration = metrics.Default.NewHistogramVec("routing_api_request_duration_seconds",
+		"Time taken to proxy requests, including the response body.", metrics.DefaultBuckets, "route", "method", "status_class", "backend")
+	requestsInFlight = metrics.Default.NewGaugeVec("routing_api_requests_in_flight",
+		"Requests being proxied, by route.", "route")
+	rejectedRequests = metrics.Default.NewCounterVec("routing_api_rejected_requests_total",
+		"Requests answered without reaching a backend, by route and reason.", "route", "reason")
+)
+
+// metricMethod keeps the method label to the standard methods, since clients
+// can send any token as a method.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
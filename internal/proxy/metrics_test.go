@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyRequest_RecordsMetrics(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer backend.Close()
+
+	client := circuit.NewCircuitBreakerClient(
+		health.NewDefaultHTTPClient(backend.URL, time.Second, time.Second),
+		circuit.CircuitBreakerConfig{MaxFailures: 1, ResetTimeout: time.Minute},
+	)
+	provider := &MockClientProvider{client: client}
+	handler := NewProxyHandlerWithConfig(provider, ProxyConfig{Route: "/metrics-test/*"}, &testLogger{})
+
+	handler.ProxyRequest(httptest.NewRecorder(), httptest.NewRequest("POST", "/metrics-test/orders", nil))
+	handler.ProxyRequest(httptest.NewRecorder(), httptest.NewRequest("BREW", "/metrics-test/orders", nil))
+	assert.Equal(t, float64(1), requestsTotal.WithLabelValues("/metrics-test/*", "POST", "2xx", backend.URL).Value())
+	assert.Equal(t, float64(1), requestsTotal.WithLabelValues("/metrics-test/*", "OTHER", "2xx", backend.URL).Value())
+	assert.Equal(t, uint64(1), requestDuration.WithLabelValues("/metrics-test/*", "POST", "2xx", backend.URL).Count())
+	assert.Equal(t, float64(0), requestsInFlight.WithLabelValues("/metrics-test/*").Value())
+
+	client.ForceCircuit(circuit.StateOpen)
+	recorder := httptest.NewRecorder()
+	handler.ProxyRequest(recorder, httptest.NewRequest("GET", "/metrics-test/orders", nil))
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, float64(1), rejectedRequests.WithLabelValues("/metrics-test/*", rejectCircuitOpen).Value())
+	assert.Equal(t, float64(1), requestsTotal.WithLabelValues("/metrics-test/*", "GET", "5xx", backend.URL).Value())
+
+	provider.client = nil
+	handler.ProxyRequest(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics-test/orders", nil))
+	assert.Equal(t, float64(1), rejectedRequests.WithLabelValues("/metrics-test/*", rejectNoBackend).Value())
+	assert.Equal(t, float64(1), requestsTotal.WithLabelValues("/metrics-test/*", "GET", "5xx", "").Value())
+}
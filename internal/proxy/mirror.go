@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"routing-api/internal/loadbalancer"
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ShadowHeader = "X-Routing-Shadow"
+
+	// maxMirrorBodySize bounds how much of a request body is buffered for mirroring;
+	// larger requests are proxied normally without a shadow copy.
+	maxMirrorBodySize = 1 << 20
+	maxMirrorInFlight = 100
+)
+
+type MirrorConfig struct {
+	ClientProvider loadbalancer.ClientProvider
+	Percent        float64
+}
+
+type mirror struct {
+	config   MirrorConfig
+	timeout  time.Duration
+	inFlight chan struct{}
+}
+
+func newMirror(config *MirrorConfig, timeout time.Duration) *mirror {
+	if config == nil || config.ClientProvider == nil || config.Percent <= 0 {
+		return nil
+	}
+	return &mirror{
+		config:   *config,
+		timeout:  timeout,
+		inFlight: make(chan struct{}, maxMirrorInFlight),
+	}
+}
+
+func (m *mirror) sample(req *http.Request) bool {
+	if upgradeType(req.Header) != "" {
+		return false
+	}
+	return m.config.Percent >= 100 || rand.Float64()*100 < m.config.Percent
+}
+
+// bufferBody reads the request body into memory so it can be sent twice. When the body
+// is too large it is restored unread and ok is false.
+func bufferBody(req *http.Request) (body []byte, ok bool, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if req.ContentLength > maxMirrorBodySize {
+		return nil, false, nil
+	}
+
+	buffered, err := io.ReadAll(io.LimitReader(req.Body, maxMirrorBodySize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(buffered) > maxMirrorBodySize {
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(buffered), req.Body), req.Body}
+		return nil, false, nil
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(buffered))
+	return buffered, true, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// send fires a copy of the request at the shadow pool and discards the response.
+// Shadow requests are dropped when too many are already outstanding.
+func (m *mirror) send(req *http.Request, body []byte, log logger.Logger) {
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		log.Warn("Dropping shadow request, too many in flight", zap.String("path", req.URL.Path))
+		return
+	}
+
+	ctx := context.WithoutCancel(req.Context())
+	shadowReq := req.Clone(ctx)
+	shadowReq.Header.Set(ShadowHeader, "true")
+	shadowReq.Host = shadowHost(req.Host)
+	shadowReq.URL = &url.URL{
+		Path:     req.URL.Path,
+		RawPath:  req.URL.RawPath,
+		RawQuery: req.URL.RawQuery,
+	}
+	shadowReq.Body = http.NoBody
+	if body != nil {
+		shadowReq.Body = io.NopCloser(bytes.NewReader(body))
+		shadowReq.ContentLength = int64(len(body))
+	}
+
+	// The primary request is rewritten while it is sent, so the goroutine only
+	// uses its own copy.
+	path := req.URL.Path
+	go func() {
+		defer func() { <-m.inFlight }()
+
+		if m.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+			shadowReq = shadowReq.WithContext(ctx)
+		}
+
+		client := m.config.ClientProvider.GetClient()
+		if client == nil {
+			return
+		}
+		resp, err := client.Do(shadowReq)
+		if err != nil {
+			log.Debug("Shadow request failed", zap.String("path", path), zap.Error(err))
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+}
+
+func shadowHost(host string) string {
+	if host == "" {
+		return ""
+	}
+	if name, port, err := net.SplitHostPort(host); err == nil {
+		return net.JoinHostPort(name+"-shadow", port)
+	}
+	return host + "-shadow"
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/loadbalancer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type shadowRequest struct {
+	host   string
+	header string
+	body   string
+}
+
+func newTestProvider(url string) loadbalancer.ClientProvider {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	factory := loadbalancer.NewLoadBalancerFactory()
+	balancer := factory.CreateLoadBalancer("round-robin", []string{url}, circuitConfig, &testLogger{})
+	return loadbalancer.NewLoadBalancerAdapter(balancer)
+}
+
+func TestProxyRequest_MirrorsToShadowPool(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("primary:" + string(body)))
+	}))
+	defer primary.Close()
+
+	shadowed := make(chan shadowRequest, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		shadowed <- shadowRequest{host: r.Host, header: r.Header.Get(ShadowHeader), body: string(body)}
+		http.Error(w, "shadow failure", http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	config := ProxyConfig{
+		RequestTimeout: 5 * time.Second,
+		Mirror:         &MirrorConfig{ClientProvider: newTestProvider(shadow.URL), Percent: 100},
+	}
+	handler := NewProxyHandlerWithConfig(newTestProvider(primary.URL), config, &testLogger{})
+
+	req := httptest.NewRequest("POST", "http://api.example.com:8080/orders", strings.NewReader("payload"))
+	w := httptest.NewRecorder()
+	handler.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "primary:payload", w.Body.String())
+
+	select {
+	case got := <-shadowed:
+		assert.Equal(t, shadowRequest{host: "api.example.com-shadow:8080", header: "true", body: "payload"}, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("shadow request was not sent")
+	}
+}
+
+func TestProxyRequest_ShadowFailureDoesNotAffectPrimary(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer primary.Close()
+
+	config := ProxyConfig{
+		RequestTimeout: 5 * time.Second,
+		Mirror:         &MirrorConfig{ClientProvider: newTestProvider("http://127.0.0.1:1"), Percent: 100},
+	}
+	handler := NewProxyHandlerWithConfig(newTestProvider(primary.URL), config, &testLogger{})
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		handler.ProxyRequest(w, httptest.NewRequest("GET", "/orders", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ok", w.Body.String())
+	}
+}
+
+func TestBufferBody_TooLarge(t *testing.T) {
+	payload := strings.Repeat("x", maxMirrorBodySize+10)
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(payload))
+	req.ContentLength = -1
+
+	body, ok, err := bufferBody(req)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, body)
+
+	rest, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, payload, string(rest))
+}
+
+func TestShadowHost(t *testing.T) {
+	assert.Equal(t, "api.example.com-shadow", shadowHost("api.example.com"))
+	assert.Equal(t, "api.example.com-shadow:8080", shadowHost("api.example.com:8080"))
+	assert.Equal(t, "", shadowHost(""))
+}
@@ -7,14 +7,20 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"routing-api/internal/accesslog"
 	"routing-api/internal/circuit"
+	"routing-api/internal/config"
 	"routing-api/internal/health"
 	"routing-api/internal/loadbalancer"
 	"routing-api/internal/logger"
+	"routing-api/internal/middleware"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -30,45 +36,6 @@ func (l *testLogger) Fatal(msg string, fields ...zap.Field)  {}
 func (l *testLogger) With(fields ...zap.Field) logger.Logger { return l }
 func (l *testLogger) Sync() error                            { return nil }
 
-func TestHealthHandler(t *testing.T) {
-	circuitConfig := circuit.CircuitBreakerConfig{
-		MaxFailures:  5,
-		ResetTimeout: 60 * time.Second,
-	}
-	factory := loadbalancer.NewLoadBalancerFactory()
-	loadBalancer := factory.CreateLoadBalancer("round-robin", []string{"http://localhost:8080"}, circuitConfig, &testLogger{})
-	clientProvider := loadbalancer.NewLoadBalancerAdapter(loadBalancer)
-	handler := NewProxyHandler(clientProvider, &testLogger{})
-
-	tests := []struct {
-		name           string
-		method         string
-		expectedStatus int
-	}{
-		{
-			name:           "health check works",
-			method:         "GET",
-			expectedStatus: 200,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req, _ := http.NewRequest(tt.method, "/health", nil)
-			w := httptest.NewRecorder()
-
-			handler.HealthHandler(w, req)
-
-			assert.Equal(t, tt.expectedStatus, w.Code)
-
-			var response HealthResponse
-			err := json.Unmarshal(w.Body.Bytes(), &response)
-			assert.NoError(t, err)
-			assert.Equal(t, "healthy", response.Status)
-		})
-	}
-}
-
 func TestProxyRequest(t *testing.T) {
 	mockProvider := &MockClientProvider{client: nil}
 	handler := NewProxyHandler(mockProvider, &testLogger{})
@@ -117,6 +84,17 @@ func (m *MockClientProvider) StartHealthChecks(ctx context.Context, interval tim
 	// Mock implementation - do nothing
 }
 
+func (m *MockClientProvider) HasHealthyBackend() bool {
+	return m.client != nil && m.client.IsUp()
+}
+
+func (m *MockClientProvider) Status() []health.BackendStatus {
+	if m.client == nil {
+		return nil
+	}
+	return []health.BackendStatus{m.client.Status()}
+}
+
 type MockHTTPClient struct {
 	response *http.Response
 	err      error
@@ -293,3 +271,78 @@ func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		Header:     make(http.Header),
 	}, nil
 }
+
+// recordingLogger keeps the fields of the log lines written through it.
+type recordingLogger struct {
+	testLogger
+	fields []zap.Field
+	lines  *[][]zap.Field
+}
+
+func (l *recordingLogger) Info(msg string, fields ...zap.Field) {
+	*l.lines = append(*l.lines, append(slices.Clone(l.fields), fields...))
+}
+
+func (l *recordingLogger) With(fields ...zap.Field) logger.Logger {
+	return &recordingLogger{fields: append(slices.Clone(l.fields), fields...), lines: l.lines}
+}
+
+func TestProxyRequest_RequestID(t *testing.T) {
+	var received string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(middleware.RequestIDHeader)
+		w.Header().Set(middleware.RequestIDHeader, "backend-id")
+	}))
+	defer backend.Close()
+
+	var lines [][]zap.Field
+	provider := &MockClientProvider{client: health.NewDefaultHTTPClient(backend.URL, time.Second, time.Second)}
+	handler := middleware.RequestID(http.HandlerFunc(
+		NewProxyHandler(provider, &recordingLogger{lines: &lines}).ProxyRequest,
+	))
+
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, "abc-123", received)
+	assert.Equal(t, []string{"abc-123"}, recorder.Header().Values(middleware.RequestIDHeader))
+	assert.NotEmpty(t, lines)
+	for _, line := range lines {
+		assert.Contains(t, line, zap.String("request_id", "abc-123"))
+	}
+}
+
+func TestProxyRequest_AccessLogDetails(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	accessLog, err := accesslog.New(config.AccessLogConfig{
+		Format:      config.AccessLogTemplate,
+		Template:    "{route} {backend} {status} {bytes} {error}",
+		File:        path,
+		SampleRatio: 1,
+	}, &testLogger{})
+	assert.NoError(t, err)
+	defer accessLog.Close()
+
+	provider := &MockClientProvider{client: health.NewDefaultHTTPClient(backend.URL, time.Second, time.Second)}
+	proxy := NewProxyHandlerWithConfig(provider, ProxyConfig{Route: "/orders/*"}, &testLogger{})
+	handler := accessLog.Middleware(http.HandlerFunc(proxy.ProxyRequest))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/1", nil))
+	backend.Close()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/1", nil))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "/orders/* "+backend.URL+" 200 2 -", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "/orders/* "+backend.URL+" 502 20 "), lines[1])
+	assert.Contains(t, lines[1], "connection refused")
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"routing-api/internal/middleware"
+)
+
+// RewriteConfig changes the outbound request path and headers, and the headers of
+// the response returned to the client. The path is rewritten in its escaped form.
+type RewriteConfig struct {
+	StripPrefix      string
+	PrefixRewrite    string
+	Regex            *regexp.Regexp
+	RegexReplacement string
+	RequestHeaders   HeaderRules
+	ResponseHeaders  HeaderRules
+}
+
+// HeaderRules removes, then sets, then adds headers. Values may contain the
+// placeholders {client_ip}, {request_id}, {host}, {method}, {path} and {scheme}.
+type HeaderRules struct {
+	Set    []HeaderValue
+	Add    []HeaderValue
+	Remove []string
+}
+
+type HeaderValue struct {
+	Name  string
+	Value string
+}
+
+func (c *RewriteConfig) rewriteRequest(outReq, inReq *http.Request) {
+	path := c.rewritePath(outReq.URL.EscapedPath())
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		outReq.URL.Path = unescaped
+		outReq.URL.RawPath = path
+	}
+
+	for _, name := range c.RequestHeaders.Remove {
+		outReq.Header.Del(name)
+	}
+	for _, header := range c.RequestHeaders.Set {
+		value := expandHeaderValue(header.Value, inReq)
+		if http.CanonicalHeaderKey(header.Name) == "Host" {
+			outReq.Host = value
+			continue
+		}
+		outReq.Header.Set(header.Name, value)
+	}
+	for _, header := range c.RequestHeaders.Add {
+		outReq.Header.Add(header.Name, expandHeaderValue(header.Value, inReq))
+	}
+}
+
+func (c *RewriteConfig) rewritePath(path string) string {
+	if c.StripPrefix != "" && hasPathPrefix(path, c.StripPrefix) {
+		rest := path[len(c.StripPrefix):]
+		prefix := strings.TrimSuffix(c.PrefixRewrite, "/")
+		switch {
+		case rest == "" && c.PrefixRewrite != "":
+			path = c.PrefixRewrite
+		case rest == "":
+			path = "/"
+		case rest[0] != '/':
+			path = prefix + "/" + rest
+		default:
+			path = prefix + rest
+		}
+	}
+	if c.Regex != nil {
+		path = c.Regex.ReplaceAllString(path, c.RegexReplacement)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+func (c *RewriteConfig) rewriteResponse(header http.Header, inReq *http.Request) {
+	c.ResponseHeaders.Apply(header, inReq)
+}
+
+// Apply changes header according to the rules, expanding placeholders from req.
+func (r HeaderRules) Apply(header http.Header, req *http.Request) {
+	for _, name := range r.Remove {
+		header.Del(name)
+	}
+	for _, value := range r.Set {
+		header.Set(value.Name, expandHeaderValue(value.Value, req))
+	}
+	for _, value := range r.Add {
+		header.Add(value.Name, expandHeaderValue(value.Value, req))
+	}
+}
+
+// hasPathPrefix reports whether prefix matches path at a segment boundary,
+// so /api strips /api and /api/orders but not /apis.
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+func expandHeaderValue(value string, req *http.Request) string {
+	if !strings.Contains(value, "{") {
+		return value
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return strings.NewReplacer(
+		"{client_ip}", clientIP(req),
+		"{request_id}", req.Header.Get(middleware.RequestIDHeader),
+		"{host}", req.Host,
+		"{method}", req.Method,
+		"{path}", req.URL.EscapedPath(),
+		"{scheme}", scheme,
+	).Replace(value)
+}
+
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
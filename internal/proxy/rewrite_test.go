@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewritePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		rewrite  RewriteConfig
+		path     string
+		expected string
+	}{
+		{name: "strip prefix", rewrite: RewriteConfig{StripPrefix: "/api"}, path: "/api/orders", expected: "/orders"},
+		{name: "strip whole path", rewrite: RewriteConfig{StripPrefix: "/api"}, path: "/api", expected: "/"},
+		{name: "strip only at segment boundary", rewrite: RewriteConfig{StripPrefix: "/api"}, path: "/apis/orders", expected: "/apis/orders"},
+		{name: "replace prefix", rewrite: RewriteConfig{StripPrefix: "/api", PrefixRewrite: "/v2"}, path: "/api/orders", expected: "/v2/orders"},
+		{name: "replace prefix with trailing slash", rewrite: RewriteConfig{StripPrefix: "/api/", PrefixRewrite: "/v2/"}, path: "/api/orders", expected: "/v2/orders"},
+		{name: "replace whole path", rewrite: RewriteConfig{StripPrefix: "/api", PrefixRewrite: "/v2"}, path: "/api", expected: "/v2"},
+		{
+			name:     "regex with capture groups",
+			rewrite:  RewriteConfig{Regex: regexp.MustCompile(`^/users/(\d+)/profile$`), RegexReplacement: "/profiles/$1"},
+			path:     "/users/42/profile",
+			expected: "/profiles/42",
+		},
+		{
+			name:     "strip then regex",
+			rewrite:  RewriteConfig{StripPrefix: "/api", Regex: regexp.MustCompile(`^/v1/`), RegexReplacement: "/legacy/"},
+			path:     "/api/v1/orders",
+			expected: "/legacy/orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rewrite.rewritePath(tt.path))
+		})
+	}
+}
+
+func TestProxyRequest_Rewrite(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/orders/a%2Fb", r.URL.EscapedPath())
+		assert.Equal(t, "id=1", r.URL.RawQuery)
+		assert.Equal(t, "internal.example.com", r.Host)
+		assert.Equal(t, "192.0.2.1 GET /api/orders/a%2Fb", r.Header.Get("X-Forwarded-Info"))
+		assert.Equal(t, []string{"one", "two"}, r.Header.Values("X-Tag"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Header().Set("Server", "backend/1.0")
+		w.Header().Set("X-Powered-By", "php")
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	config := ProxyConfig{
+		RequestTimeout: 5 * time.Second,
+		Rewrite: &RewriteConfig{
+			StripPrefix:   "/api",
+			PrefixRewrite: "/v2",
+			RequestHeaders: HeaderRules{
+				Set: []HeaderValue{
+					{Name: "Host", Value: "internal.example.com"},
+					{Name: "X-Forwarded-Info", Value: "{client_ip} {method} {path}"},
+				},
+				Add:    []HeaderValue{{Name: "X-Tag", Value: "two"}},
+				Remove: []string{"Authorization"},
+			},
+			ResponseHeaders: HeaderRules{
+				Set:    []HeaderValue{{Name: "Server", Value: "routing-api"}},
+				Remove: []string{"X-Powered-By"},
+			},
+		},
+	}
+	handler := NewProxyHandlerWithConfig(newTestProvider(backend.URL), config, &testLogger{})
+
+	req := httptest.NewRequest("GET", "/api/orders/a%2Fb?id=1", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tag", "one")
+	w := httptest.NewRecorder()
+	handler.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "routing-api", w.Header().Get("Server"))
+	assert.Empty(t, w.Header().Get("X-Powered-By"))
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"routing-api/internal/health"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+type ProbeResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type PoolStatus struct {
+	Name     string                 `json:"name"`
+	Required bool                   `json:"required"`
+	Healthy  bool                   `json:"healthy"`
+	Backends []health.BackendStatus `json:"backends"`
+}
+
+type StatusResponse struct {
+	Status   string       `json:"status"`
+	Draining bool         `json:"draining"`
+	Pools    []PoolStatus `json:"pools"`
+}
+
+type PoolStatusProvider interface {
+	PoolStatuses() []PoolStatus
+}
+
+type ProbeHandler struct {
+	pools    PoolStatusProvider
+	draining atomic.Bool
+}
+
+func NewProbeHandler(pools PoolStatusProvider) *ProbeHandler {
+	return &ProbeHandler{pools: pools}
+}
+
+func (h *ProbeHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Status: "healthy"}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ProbeHandler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ProbeResponse{Status: "alive"})
+}
+
+func (h *ProbeHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if reason := h.notReadyReason(h.pools.PoolStatuses()); reason != "" {
+		writeJSON(w, http.StatusServiceUnavailable, ProbeResponse{Status: "not ready", Reason: reason})
+		return
+	}
+	writeJSON(w, http.StatusOK, ProbeResponse{Status: "ready"})
+}
+
+func (h *ProbeHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	pools := h.pools.PoolStatuses()
+	status := "ready"
+	if h.notReadyReason(pools) != "" {
+		status = "not ready"
+	}
+
+	writeJSON(w, http.StatusOK, StatusResponse{
+		Status:   status,
+		Draining: h.draining.Load(),
+		Pools:    pools,
+	})
+}
+
+func (h *ProbeHandler) StartDrain() {
+	h.draining.Store(true)
+}
+
+func (h *ProbeHandler) notReadyReason(pools []PoolStatus) string {
+	if h.draining.Load() {
+		return "shutting down"
+	}
+	for _, pool := range pools {
+		if pool.Required && !pool.Healthy {
+			return fmt.Sprintf("no healthy backends in pool %q", pool.Name)
+		}
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/loadbalancer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticPools []PoolStatus
+
+func (p staticPools) PoolStatuses() []PoolStatus {
+	return p
+}
+
+func TestHealthHandler(t *testing.T) {
+	handler := NewProbeHandler(staticPools{})
+
+	tests := []struct {
+		name           string
+		method         string
+		expectedStatus int
+	}{
+		{
+			name:           "health check works",
+			method:         "GET",
+			expectedStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "/health", nil)
+			w := httptest.NewRecorder()
+
+			handler.HealthHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response HealthResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "healthy", response.Status)
+		})
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		pools          staticPools
+		draining       bool
+		expectedStatus int
+		expectedReason string
+	}{
+		{
+			name:           "ready with healthy backend",
+			pools:          staticPools{{Name: "default", Required: true, Healthy: true}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not ready without healthy backend in required pool",
+			pools:          staticPools{{Name: "default", Required: true, Healthy: true}, {Name: "orders", Required: true}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReason: `no healthy backends in pool "orders"`,
+		},
+		{
+			name:           "ready when only optional pool is down",
+			pools:          staticPools{{Name: "default", Required: true, Healthy: true}, {Name: "reports"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not ready while draining",
+			pools:          staticPools{{Name: "default", Required: true, Healthy: true}},
+			draining:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReason: "shutting down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewProbeHandler(tt.pools)
+			if tt.draining {
+				handler.StartDrain()
+			}
+
+			req, _ := http.NewRequest("GET", "/readyz", nil)
+			w := httptest.NewRecorder()
+			handler.ReadinessHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response ProbeResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedReason, response.Reason)
+		})
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	factory := loadbalancer.NewLoadBalancerFactory()
+	loadBalancer := factory.CreateLoadBalancer("round-robin", []string{"http://localhost:8080", "http://localhost:8081"}, circuitConfig, &testLogger{})
+	clientProvider := loadbalancer.NewLoadBalancerAdapter(loadBalancer)
+
+	handler := NewProbeHandler(staticPools{{
+		Name:     "default",
+		Required: true,
+		Healthy:  clientProvider.HasHealthyBackend(),
+		Backends: clientProvider.Status(),
+	}})
+
+	req, _ := http.NewRequest("GET", "/status", nil)
+	w := httptest.NewRecorder()
+	handler.StatusHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response StatusResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ready", response.Status)
+	assert.False(t, response.Draining)
+	assert.Len(t, response.Pools, 1)
+
+	backends := response.Pools[0].Backends
+	assert.Len(t, backends, 2)
+	assert.Equal(t, "http://localhost:8080", backends[0].URL)
+	assert.Equal(t, "closed", backends[0].CircuitState)
+	assert.True(t, backends[0].Healthy)
+}
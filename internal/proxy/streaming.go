@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const immediateFlush = time.Duration(-1)
+
+func isStreamingResponse(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" || strings.HasPrefix(mediaType, "application/grpc") {
+		return true
+	}
+
+	if resp.ContentLength != -1 {
+		return false
+	}
+	for _, encoding := range resp.TransferEncoding {
+		if encoding == "chunked" {
+			return true
+		}
+	}
+	return false
+}
+
+func copyResponse(w http.ResponseWriter, body io.Reader, flushInterval time.Duration) error {
+	var dst io.Writer = w
+	if flushInterval != 0 {
+		fw := &flushWriter{
+			dst:     w,
+			flush:   http.NewResponseController(w).Flush,
+			latency: flushInterval,
+		}
+		defer fw.stop()
+		dst = fw
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+type flushWriter struct {
+	dst     io.Writer
+	flush   func() error
+	latency time.Duration
+
+	mutex        sync.Mutex
+	timer        *time.Timer
+	flushPending bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	n, err := f.dst.Write(p)
+	if f.latency < 0 {
+		f.flush()
+		return n, err
+	}
+	if f.flushPending {
+		return n, err
+	}
+	if f.timer == nil {
+		f.timer = time.AfterFunc(f.latency, f.delayedFlush)
+	} else {
+		f.timer.Reset(f.latency)
+	}
+	f.flushPending = true
+	return n, err
+}
+
+func (f *flushWriter) delayedFlush() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.flushPending {
+		return
+	}
+	f.flush()
+	f.flushPending = false
+}
+
+func (f *flushWriter) stop() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.flushPending = false
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+}
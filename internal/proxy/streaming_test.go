@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/loadbalancer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStreamingResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		expected bool
+	}{
+		{
+			name: "server-sent events",
+			resp: &http.Response{
+				Header:        http.Header{"Content-Type": []string{"text/event-stream; charset=utf-8"}},
+				ContentLength: 100,
+			},
+			expected: true,
+		},
+		{
+			name: "chunked without content length",
+			resp: &http.Response{
+				Header:           http.Header{"Content-Type": []string{"application/json"}},
+				ContentLength:    -1,
+				TransferEncoding: []string{"chunked"},
+			},
+			expected: true,
+		},
+		{
+			name: "fixed length json",
+			resp: &http.Response{
+				Header:        http.Header{"Content-Type": []string{"application/json"}},
+				ContentLength: 42,
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isStreamingResponse(tt.resp))
+		})
+	}
+}
+
+func TestProxyRequest_StreamsServerSentEvents(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "data: event-%d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer backend.Close()
+
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	factory := loadbalancer.NewLoadBalancerFactory()
+	balancer := factory.CreateLoadBalancer("round-robin", []string{backend.URL}, circuitConfig, &testLogger{})
+	handler := NewProxyHandler(loadbalancer.NewLoadBalancerAdapter(balancer), &testLogger{})
+
+	front := httptest.NewUnstartedServer(http.HandlerFunc(handler.ProxyRequest))
+	front.Config.WriteTimeout = 100 * time.Millisecond
+	front.Start()
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	start := time.Now()
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "data: event-0\n", line)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	var events []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(line, "data: ") {
+			events = append(events, strings.TrimSpace(line))
+		}
+	}
+	assert.Equal(t, []string{"data: event-1", "data: event-2", "data: event-3"}, events)
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/health"
+	"routing-api/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyRequest_PropagatesTraceContext(t *testing.T) {
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	tracer := tracing.NewTracer(config.TracingConfig{
+		Endpoint:    "http://127.0.0.1:0/v1/traces",
+		SampleRatio: 1,
+		ServiceName: "routing-api",
+	}, &testLogger{})
+	provider := &MockClientProvider{client: health.NewDefaultHTTPClient(backend.URL, time.Second, time.Second)}
+	handler := NewProxyHandlerWithConfig(provider, ProxyConfig{Route: "/orders", Tracer: tracer}, &testLogger{})
+
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=1")
+	handler.ProxyRequest(httptest.NewRecorder(), req)
+
+	header := <-received
+	context := tracing.Extract(header)
+	require.True(t, context.IsValid())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", context.TraceID.String())
+	assert.NotEqual(t, "00f067aa0ba902b7", context.SpanID.String())
+	assert.True(t, context.Sampled)
+	assert.Equal(t, "vendor=1", header.Get("tracestate"))
+
+	// Without a tracer the caller's trace context passes through unchanged.
+	handler = NewProxyHandlerWithConfig(provider, ProxyConfig{Route: "/orders"}, &testLogger{})
+	handler.ProxyRequest(httptest.NewRecorder(), req)
+	header = <-received
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("traceparent"))
+}
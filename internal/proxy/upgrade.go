@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"routing-api/internal/logger"
+	"routing-api/internal/middleware"
+
+	"go.uber.org/zap"
+)
+
+func upgradeType(header http.Header) string {
+	for _, value := range header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return header.Get("Upgrade")
+			}
+		}
+	}
+	return ""
+}
+
+func (h *ProxyHandler) handleUpgradeResponse(w http.ResponseWriter, req *http.Request, resp *http.Response, log logger.Logger) error {
+	requestUpgrade := upgradeType(req.Header)
+	responseUpgrade := upgradeType(resp.Header)
+	if !strings.EqualFold(requestUpgrade, responseUpgrade) {
+		return fmt.Errorf("backend tried to switch protocol %q when %q was requested", responseUpgrade, requestUpgrade)
+	}
+
+	backendConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return errors.New("backend response body is not writable")
+	}
+	defer backendConn.Close()
+
+	clientConn, clientBuf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return fmt.Errorf("cannot hijack client connection: %w", err)
+	}
+	defer clientConn.Close()
+
+	copyHeader(w.Header(), resp.Header)
+	if requestID := req.Header.Get(middleware.RequestIDHeader); requestID != "" {
+		w.Header().Set(middleware.RequestIDHeader, requestID)
+	}
+	resp.Header = w.Header()
+	resp.Body = nil
+	if err := clientConn.SetDeadline(time.Time{}); err != nil {
+		log.Warn("Cannot clear client connection deadline", zap.Error(err))
+		return nil
+	}
+	if err := resp.Write(clientBuf); err != nil {
+		log.Warn("Cannot write upgrade response", zap.Error(err))
+		return nil
+	}
+	if err := clientBuf.Flush(); err != nil {
+		log.Warn("Cannot flush upgrade response", zap.Error(err))
+		return nil
+	}
+
+	log.Debug("Upgraded connection opened", zap.String("protocol", responseUpgrade))
+
+	t := newTunnel(h.config.UpgradeIdleTimeout, h.config.UpgradeMaxLifetime, clientConn, backendConn)
+	reason := t.run(clientBuf.Reader, clientConn, backendConn)
+
+	log.Debug("Upgraded connection closed",
+		zap.String("protocol", responseUpgrade),
+		zap.String("reason", reason),
+		zap.Duration("duration", time.Since(t.started)),
+	)
+	return nil
+}
+
+type tunnel struct {
+	idleTimeout  time.Duration
+	maxLifetime  time.Duration
+	started      time.Time
+	lastActivity atomic.Int64
+	closers      []io.Closer
+	closeOnce    sync.Once
+}
+
+func newTunnel(idleTimeout, maxLifetime time.Duration, closers ...io.Closer) *tunnel {
+	t := &tunnel{
+		idleTimeout: idleTimeout,
+		maxLifetime: maxLifetime,
+		started:     time.Now(),
+		closers:     closers,
+	}
+	t.touch()
+	return t
+}
+
+func (t *tunnel) touch() {
+	t.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (t *tunnel) close() {
+	t.closeOnce.Do(func() {
+		for _, c := range t.closers {
+			c.Close()
+		}
+	})
+}
+
+func (t *tunnel) run(clientReader io.Reader, clientWriter io.Writer, backend io.ReadWriter) string {
+	done := make(chan string, 2)
+	go func() {
+		io.Copy(&activityWriter{Writer: backend, tunnel: t}, clientReader)
+		done <- "client closed"
+	}()
+	go func() {
+		io.Copy(&activityWriter{Writer: clientWriter, tunnel: t}, backend)
+		done <- "backend closed"
+	}()
+
+	reason := t.wait(done)
+	t.close()
+	<-done
+	return reason
+}
+
+func (t *tunnel) wait(done <-chan string) string {
+	var lifetime <-chan time.Time
+	if t.maxLifetime > 0 {
+		timer := time.NewTimer(t.maxLifetime)
+		defer timer.Stop()
+		lifetime = timer.C
+	}
+
+	var idle <-chan time.Time
+	if t.idleTimeout > 0 {
+		ticker := time.NewTicker(t.idleTimeout / 4)
+		defer ticker.Stop()
+		idle = ticker.C
+	}
+
+	for {
+		select {
+		case reason := <-done:
+			return reason
+		case <-lifetime:
+			return "max lifetime reached"
+		case <-idle:
+			if time.Since(time.Unix(0, t.lastActivity.Load())) >= t.idleTimeout {
+				return "idle timeout"
+			}
+		}
+	}
+}
+
+type activityWriter struct {
+	io.Writer
+	tunnel *tunnel
+}
+
+func (w *activityWriter) Write(p []byte) (int, error) {
+	w.tunnel.touch()
+	return w.Writer.Write(p)
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/loadbalancer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEchoUpgradeBackend(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		buf.Flush()
+		io.Copy(conn, buf)
+	}))
+}
+
+func dialUpgrade(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: " + addr + "\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, "echo", resp.Header.Get("Upgrade"))
+	return conn, reader
+}
+
+func TestProxyRequest_UpgradeTunnel(t *testing.T) {
+	backend := newEchoUpgradeBackend(t)
+	defer backend.Close()
+
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	factory := loadbalancer.NewLoadBalancerFactory()
+	balancer := factory.CreateLoadBalancer("round-robin", []string{backend.URL}, circuitConfig, &testLogger{})
+	handler := NewProxyHandler(loadbalancer.NewLoadBalancerAdapter(balancer), &testLogger{})
+
+	front := httptest.NewUnstartedServer(http.HandlerFunc(handler.ProxyRequest))
+	front.Config.WriteTimeout = 100 * time.Millisecond
+	front.Start()
+	defer front.Close()
+
+	conn, reader := dialUpgrade(t, front.Listener.Addr().String())
+
+	time.Sleep(150 * time.Millisecond)
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(reader, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+	assert.Equal(t, int64(1), balancer.Clients()[0].InFlight())
+
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return balancer.Clients()[0].InFlight() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProxyRequest_UpgradeIdleTimeout(t *testing.T) {
+	backend := newEchoUpgradeBackend(t)
+	defer backend.Close()
+
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	factory := loadbalancer.NewLoadBalancerFactory()
+	balancer := factory.CreateLoadBalancer("round-robin", []string{backend.URL}, circuitConfig, &testLogger{})
+	proxyConfig := ProxyConfig{
+		RequestTimeout:     time.Second,
+		UpgradeIdleTimeout: 100 * time.Millisecond,
+	}
+	handler := NewProxyHandlerWithConfig(loadbalancer.NewLoadBalancerAdapter(balancer), proxyConfig, &testLogger{})
+
+	front := httptest.NewServer(http.HandlerFunc(handler.ProxyRequest))
+	defer front.Close()
+
+	conn, reader := dialUpgrade(t, front.Listener.Addr().String())
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := reader.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestProxyRequest_UpgradeRejectedByBackend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer backend.Close()
+
+	circuitConfig := circuit.CircuitBreakerConfig{
+		MaxFailures:  5,
+		ResetTimeout: 60 * time.Second,
+	}
+	factory := loadbalancer.NewLoadBalancerFactory()
+	balancer := factory.CreateLoadBalancer("round-robin", []string{backend.URL}, circuitConfig, &testLogger{})
+	handler := NewProxyHandler(loadbalancer.NewLoadBalancerAdapter(balancer), &testLogger{})
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	w := httptest.NewRecorder()
+	handler.ProxyRequest(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
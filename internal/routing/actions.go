@@ -0,0 +1,102 @@
+package routing
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"routing-api/internal/config"
+
+	"github.com/gorilla/mux"
+)
+
+// newRedirectHandler answers with a redirect to the route's location template. The
+// template may use {scheme}, {host}, {path}, {rest} (the path after the route prefix),
+// {query} and the route's path variables. The original query string is kept when the
+// template has neither a query nor {query}.
+func newRedirectHandler(route config.RouteConfig) http.Handler {
+	headers := headerRules(route.ResponseHeaders)
+	prefix := route.Prefix()
+	keepQuery := !strings.Contains(route.Location, "?") && !strings.Contains(route.Location, "{query}")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		pairs := []string{
+			"{scheme}", requestScheme(r),
+			"{host}", r.Host,
+			"{path}", path,
+			"{rest}", strings.TrimPrefix(path, prefix),
+			"{query}", r.URL.RawQuery,
+		}
+		for name, value := range mux.Vars(r) {
+			pairs = append(pairs, "{"+name+"}", value)
+		}
+
+		location := strings.NewReplacer(pairs...).Replace(route.Location)
+		if keepQuery && r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+
+		headers.Apply(w.Header(), r)
+		http.Redirect(w, r, location, route.Status)
+	})
+}
+
+func newDirectResponseHandler(route config.RouteConfig) (http.Handler, error) {
+	body := []byte(route.Body)
+	contentType := "text/plain; charset=utf-8"
+	if route.BodyFile != "" {
+		var err error
+		body, err = os.ReadFile(route.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", route.RouteName(), err)
+		}
+		contentType = mime.TypeByExtension(filepath.Ext(route.BodyFile))
+		if contentType == "" {
+			contentType = http.DetectContentType(body)
+		}
+	}
+	headers := headerRules(route.ResponseHeaders)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(body) > 0 {
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		headers.Apply(w.Header(), r)
+		w.WriteHeader(route.Status)
+		w.Write(body)
+	}), nil
+}
+
+// httpsRedirect sends plain HTTP requests to the same URL over HTTPS and passes
+// HTTPS requests on to next.
+func httpsRedirect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestScheme(r) == "https" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		host := r.Host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			host = name
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
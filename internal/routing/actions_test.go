@@ -0,0 +1,111 @@
+package routing
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"routing-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable_Redirects(t *testing.T) {
+	backend := newNamedBackend("default")
+	defer backend.Close()
+
+	cfg := testConfig(
+		[]config.PoolConfig{testPoolConfig("default", backend.URL)},
+		[]config.RouteConfig{
+			{Path: "/old/*", Action: config.ActionRedirect, Status: http.StatusMovedPermanently, Location: "/new{rest}"},
+			{Path: "/users/{id}", Action: config.ActionRedirect, Status: http.StatusPermanentRedirect, Location: "https://accounts.example.com/u/{id}?from={host}"},
+			{Path: "/secure/*", Pool: "default", HTTPSRedirect: true},
+		},
+	)
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		target   string
+		status   int
+		location string
+	}{
+		{target: "/old/orders/1?page=2", status: http.StatusMovedPermanently, location: "/new/orders/1?page=2"},
+		{target: "/old", status: http.StatusMovedPermanently, location: "/new"},
+		{target: "http://api.example.com/users/42", status: http.StatusPermanentRedirect, location: "https://accounts.example.com/u/42?from=api.example.com"},
+		{target: "http://api.example.com:8080/secure/data?x=1", status: http.StatusMovedPermanently, location: "https://api.example.com/secure/data?x=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(t, table, "GET", tt.target)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/secure/data", nil)
+	req.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	table.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "default", w.Body.String())
+}
+
+func TestTable_GlobalHTTPSRedirect(t *testing.T) {
+	backend := newNamedBackend("default")
+	defer backend.Close()
+
+	cfg := testConfig([]config.PoolConfig{testPoolConfig("default", backend.URL)}, nil)
+	cfg.HTTPSRedirect = true
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	w := serve(t, table, "GET", "http://example.com/orders")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/orders", w.Header().Get("Location"))
+}
+
+func TestTable_DirectResponses(t *testing.T) {
+	backend := newNamedBackend("default")
+	defer backend.Close()
+
+	page := filepath.Join(t.TempDir(), "maintenance.html")
+	require.NoError(t, os.WriteFile(page, []byte("<h1>Back soon</h1>"), 0o644))
+
+	cfg := testConfig(
+		[]config.PoolConfig{testPoolConfig("default", backend.URL)},
+		[]config.RouteConfig{
+			{Path: "/v1/*", Action: config.ActionRespond, Status: http.StatusGone, Body: "v1 has been retired"},
+			{
+				Path:     "/shop/*",
+				Action:   config.ActionRespond,
+				Status:   http.StatusServiceUnavailable,
+				BodyFile: page,
+				ResponseHeaders: config.HeaderRules{
+					Set: []config.HeaderValue{{Name: "Retry-After", Value: "3600"}},
+				},
+			},
+		},
+	)
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	w := serve(t, table, "GET", "/v1/orders")
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "v1 has been retired", w.Body.String())
+
+	w = serve(t, table, "GET", "/shop/cart")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+	assert.Equal(t, "<h1>Back soon</h1>", w.Body.String())
+
+	w = serve(t, table, "GET", "/orders")
+	assert.Equal(t, "default", w.Body.String())
+}
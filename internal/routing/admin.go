@@ -0,0 +1,460 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/health"
+	"routing-api/internal/loadbalancer"
+	"routing-api/internal/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminPool and AdminBackend describe pools and backends in admin API responses.
+type AdminPool struct {
+	Name     string         `json:"name"`
+	Healthy  bool           `json:"healthy"`
+	Backends []AdminBackend `json:"backends"`
+}
+
+type AdminBackend struct {
+	health.BackendStatus
+	Weight  int  `json:"weight"`
+	Enabled bool `json:"enabled"`
+}
+
+// DrainStatus reports a draining backend's progress. Drained is set once the
+// backend is draining and has no requests or streams left.
+type DrainStatus struct {
+	URL      string `json:"url"`
+	Draining bool   `json:"draining"`
+	InFlight int64  `json:"in_flight"`
+	Drained  bool   `json:"drained"`
+}
+
+type circuitController interface {
+	ForceCircuit(state circuit.CircuitBreakerState)
+	ReleaseCircuit()
+}
+
+// AdminHandler serves the admin API, which changes the backends of the current
+// route table, the weights of its splits and the log levels at runtime. Changes
+// last until the configuration is reloaded, except that drains and forced
+// circuits of backends that are still configured carry over. They apply to one
+// pool only, even when another pool lists the same backend.
+//
+// Backends are addressed by their URL, path-escaped, or by host:port when that
+// is unambiguous within the pool.
+type AdminHandler struct {
+	routes *Router
+	mux    *mux.Router
+}
+
+func NewAdminHandler(routes *Router) *AdminHandler {
+	h := &AdminHandler{routes: routes, mux: mux.NewRouter().UseEncodedPath()}
+	splits := NewSplitHandler(routes)
+
+	h.mux.HandleFunc("/pools", h.listPools).Methods("GET")
+	h.mux.HandleFunc("/pools/{pool}", h.getPool).Methods("GET")
+	h.mux.HandleFunc("/pools/{pool}/backends", h.addBackend).Methods("POST")
+	h.mux.HandleFunc("/pools/{pool}/backends/{backend}", h.updateBackend).Methods("PATCH")
+	h.mux.HandleFunc("/pools/{pool}/backends/{backend}", h.removeBackend).Methods("DELETE")
+	h.mux.HandleFunc("/pools/{pool}/backends/{backend}/circuit", h.setCircuit).Methods("PUT")
+	h.mux.HandleFunc("/pools/{pool}/backends/{backend}/drain", h.drainStatus).Methods("GET")
+	h.mux.HandleFunc("/pools/{pool}/backends/{backend}/drain", h.startDrain).Methods("PUT")
+	h.mux.HandleFunc("/pools/{pool}/backends/{backend}/drain", h.stopDrain).Methods("DELETE")
+	h.mux.HandleFunc("/splits", splits.ListHandler).Methods("GET")
+	h.mux.HandleFunc("/splits/{route:.+}", splits.UpdateHandler).Methods("PUT")
+	h.mux.HandleFunc("/logging", h.getLogging).Methods("GET")
+	h.mux.HandleFunc("/logging", h.setLogLevel).Methods("PUT")
+	h.mux.HandleFunc("/logging/{component}", h.setComponentLevel).Methods("PUT")
+	h.mux.HandleFunc("/logging/{component}", h.resetComponentLevel).Methods("DELETE")
+	return h
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *AdminHandler) listPools(w http.ResponseWriter, r *http.Request) {
+	pools := h.routes.Pools()
+	result := make([]AdminPool, len(pools))
+	for i, pool := range pools {
+		result[i] = adminPool(pool)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *AdminHandler) getPool(w http.ResponseWriter, r *http.Request) {
+	pool, ok := h.pool(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, adminPool(pool))
+}
+
+func (h *AdminHandler) addBackend(w http.ResponseWriter, r *http.Request) {
+	pool, ok := h.pool(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		URL    string `json:"url"`
+		Weight *int   `json:"weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkBackendURL(body.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	weight := 1
+	if body.Weight != nil {
+		weight = *body.Weight
+	}
+
+	if err := pool.Backends.AddBackend(body.URL, weight); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	backend, _ := findBackend(pool, body.URL)
+	writeJSON(w, http.StatusCreated, adminBackend(backend))
+}
+
+func (h *AdminHandler) updateBackend(w http.ResponseWriter, r *http.Request) {
+	pool, backend, ok := h.backend(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Weight  *int  `json:"weight"`
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Weight == nil && body.Enabled == nil {
+		http.Error(w, "weight or enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	if body.Weight != nil {
+		if err := pool.Backends.SetWeight(backend.URL, *body.Weight); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+	}
+	if body.Enabled != nil {
+		if err := pool.Backends.SetEnabled(backend.URL, *body.Enabled); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+	}
+	backend, _ = findBackend(pool, backend.URL)
+	writeJSON(w, http.StatusOK, adminBackend(backend))
+}
+
+func (h *AdminHandler) removeBackend(w http.ResponseWriter, r *http.Request) {
+	pool, backend, ok := h.backend(w, r)
+	if !ok {
+		return
+	}
+	if err := h.routes.RemoveBackend(pool, backend.URL); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setCircuit forces a backend's circuit "open" or "closed", or with "auto" hands
+// it back to the circuit breaker.
+func (h *AdminHandler) setCircuit(w http.ResponseWriter, r *http.Request) {
+	_, backend, ok := h.backend(w, r)
+	if !ok {
+		return
+	}
+	controller, ok := backend.Client.(circuitController)
+	if !ok {
+		http.Error(w, "backend has no circuit breaker", http.StatusConflict)
+		return
+	}
+
+	var body struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch body.State {
+	case "open":
+		controller.ForceCircuit(circuit.StateOpen)
+	case "closed":
+		controller.ForceCircuit(circuit.StateClosed)
+	case "auto":
+		controller.ReleaseCircuit()
+	default:
+		http.Error(w, fmt.Sprintf("state must be open, closed or auto, got %q", body.State), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, adminBackend(backend))
+}
+
+func (h *AdminHandler) startDrain(w http.ResponseWriter, r *http.Request) {
+	h.setDraining(w, r, true)
+}
+
+func (h *AdminHandler) stopDrain(w http.ResponseWriter, r *http.Request) {
+	h.setDraining(w, r, false)
+}
+
+func (h *AdminHandler) setDraining(w http.ResponseWriter, r *http.Request, draining bool) {
+	pool, backend, ok := h.backend(w, r)
+	if !ok {
+		return
+	}
+	if err := pool.Backends.SetDraining(backend.URL, draining); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, drainStatus(backend.Client))
+}
+
+// drainStatus reports on a drain. With ?wait=<duration> it holds the response
+// until the backend has drained or the duration has passed.
+func (h *AdminHandler) drainStatus(w http.ResponseWriter, r *http.Request) {
+	_, backend, ok := h.backend(w, r)
+	if !ok {
+		return
+	}
+
+	var wait time.Duration
+	if value := r.URL.Query().Get("wait"); value != "" {
+		var err error
+		if wait, err = time.ParseDuration(value); err != nil || wait < 0 {
+			http.Error(w, fmt.Sprintf("invalid wait %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	status := drainStatus(backend.Client)
+	if wait > 0 && status.Draining && !status.Drained {
+		// Waits may outlast the admin server's write timeout.
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		timeout := time.NewTimer(wait)
+		defer timeout.Stop()
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+
+		for status.Draining && !status.Drained {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-timeout.C:
+				writeJSON(w, http.StatusOK, status)
+				return
+			case <-ticker.C:
+				status = drainStatus(backend.Client)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// LogLevels reports the global log level and the components that have a level
+// of their own; the others follow the global level.
+type LogLevels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+func (h *AdminHandler) getLogging(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevels())
+}
+
+func (h *AdminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	level, ok := readLogLevel(w, r)
+	if !ok {
+		return
+	}
+	if err := logger.SetLevel(level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, logLevels())
+}
+
+func (h *AdminHandler) setComponentLevel(w http.ResponseWriter, r *http.Request) {
+	component, ok := logComponent(w, r)
+	if !ok {
+		return
+	}
+	level, ok := readLogLevel(w, r)
+	if !ok {
+		return
+	}
+	if err := logger.SetComponentLevel(component, level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, logLevels())
+}
+
+// resetComponentLevel makes a component follow the global level again.
+func (h *AdminHandler) resetComponentLevel(w http.ResponseWriter, r *http.Request) {
+	component, ok := logComponent(w, r)
+	if !ok {
+		return
+	}
+	logger.SetComponentLevel(component, "")
+	writeJSON(w, http.StatusOK, logLevels())
+}
+
+func readLogLevel(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if _, err := logger.ParseLevel(body.Level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return body.Level, true
+}
+
+func logComponent(w http.ResponseWriter, r *http.Request) (string, bool) {
+	component := mux.Vars(r)["component"]
+	if !slices.Contains(logger.Components, component) {
+		http.Error(w, fmt.Sprintf("no log component %q", component), http.StatusNotFound)
+		return "", false
+	}
+	return component, true
+}
+
+func logLevels() LogLevels {
+	return LogLevels{Level: logger.Level(), Components: logger.ComponentLevels()}
+}
+
+func (h *AdminHandler) pool(w http.ResponseWriter, r *http.Request) (*Pool, bool) {
+	name, err := url.PathUnescape(mux.Vars(r)["pool"])
+	if err != nil {
+		http.Error(w, "invalid pool name", http.StatusBadRequest)
+		return nil, false
+	}
+	pool, ok := h.routes.Pool(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no pool %q", name), http.StatusNotFound)
+		return nil, false
+	}
+	return pool, true
+}
+
+func (h *AdminHandler) backend(w http.ResponseWriter, r *http.Request) (*Pool, loadbalancer.Backend, bool) {
+	pool, ok := h.pool(w, r)
+	if !ok {
+		return nil, loadbalancer.Backend{}, false
+	}
+	id, err := url.PathUnescape(mux.Vars(r)["backend"])
+	if err != nil {
+		http.Error(w, "invalid backend", http.StatusBadRequest)
+		return nil, loadbalancer.Backend{}, false
+	}
+	backend, err := findBackend(pool, id)
+	if err != nil {
+		writeBackendError(w, err)
+		return nil, loadbalancer.Backend{}, false
+	}
+	return pool, backend, true
+}
+
+// findBackend looks a backend up by URL, or by host:port when only one backend
+// of the pool has it.
+func findBackend(pool *Pool, id string) (loadbalancer.Backend, error) {
+	var matches []loadbalancer.Backend
+	for _, backend := range pool.Backends.Backends() {
+		if backend.URL == id {
+			return backend, nil
+		}
+		if u, err := url.Parse(backend.URL); err == nil && u.Host == id {
+			matches = append(matches, backend)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return loadbalancer.Backend{}, fmt.Errorf("%w: %s", loadbalancer.ErrBackendNotFound, id)
+	case 1:
+		return matches[0], nil
+	}
+	return loadbalancer.Backend{}, fmt.Errorf("%w: %s matches %d backends, use the full URL", errAmbiguousBackend, id, len(matches))
+}
+
+var errAmbiguousBackend = errors.New("ambiguous backend")
+
+func checkBackendURL(backendURL string) error {
+	u, err := url.Parse(backendURL)
+	if err != nil {
+		return fmt.Errorf("invalid backend URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("backend URL %q needs an http or https scheme and a host", backendURL)
+	}
+	return nil
+}
+
+func writeBackendError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, loadbalancer.ErrBackendNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, loadbalancer.ErrBackendExists), errors.Is(err, errAmbiguousBackend):
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func drainStatus(client health.HTTPClient) DrainStatus {
+	draining := client.State() == health.StateDraining
+	inFlight := client.InFlight()
+	return DrainStatus{
+		URL:      client.GetBaseURL(),
+		Draining: draining,
+		InFlight: inFlight,
+		Drained:  draining && inFlight == 0,
+	}
+}
+
+func adminPool(pool *Pool) AdminPool {
+	backends := pool.Backends.Backends()
+	result := AdminPool{
+		Name:     pool.Name,
+		Healthy:  pool.ClientProvider.HasHealthyBackend(),
+		Backends: make([]AdminBackend, len(backends)),
+	}
+	for i, backend := range backends {
+		result.Backends[i] = adminBackend(backend)
+	}
+	return result
+}
+
+func adminBackend(backend loadbalancer.Backend) AdminBackend {
+	return AdminBackend{
+		BackendStatus: backend.Client.Status(),
+		Weight:        backend.Weight,
+		Enabled:       backend.Enabled,
+	}
+}
@@ -0,0 +1,209 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func adminRequest(t *testing.T, handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminHandler_ManagesBackends(t *testing.T) {
+	first := newNamedBackend("first")
+	defer first.Close()
+	second := newNamedBackend("second")
+	defer second.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", first.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router.Start(ctx)
+	admin := NewAdminHandler(router)
+
+	w := adminRequest(t, admin, "POST", "/pools/default/backends", `{"url":"`+second.URL+`","weight":2}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var added AdminBackend
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	assert.Equal(t, second.URL, added.URL)
+	assert.Equal(t, 2, added.Weight)
+	assert.True(t, added.Enabled)
+
+	assert.Equal(t, http.StatusConflict, adminRequest(t, admin, "POST", "/pools/default/backends", `{"url":"`+second.URL+`"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, admin, "POST", "/pools/default/backends", `{"url":"localhost:9"}`).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, admin, "POST", "/pools/missing/backends", `{"url":"http://localhost:9"}`).Code)
+
+	firstHost := strings.TrimPrefix(first.URL, "http://")
+	w = adminRequest(t, admin, "PATCH", "/pools/default/backends/"+firstHost, `{"enabled":false}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "second", serve(t, router, "GET", "/").Body.String())
+	}
+
+	w = adminRequest(t, admin, "GET", "/pools/default", "")
+	var pool AdminPool
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pool))
+	require.Len(t, pool.Backends, 2)
+	assert.False(t, pool.Backends[0].Enabled)
+	assert.True(t, pool.Healthy)
+
+	escaped := url.PathEscape(second.URL)
+	assert.Equal(t, http.StatusNoContent, adminRequest(t, admin, "DELETE", "/pools/default/backends/"+escaped, "").Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, admin, "DELETE", "/pools/default/backends/"+escaped, "").Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, admin, "PATCH", "/pools/default/backends/"+firstHost, `{}`).Code)
+
+	require.Equal(t, http.StatusOK, adminRequest(t, admin, "PATCH", "/pools/default/backends/"+firstHost, `{"enabled":true}`).Code)
+	assert.Equal(t, "first", serve(t, router, "GET", "/").Body.String())
+}
+
+func TestAdminHandler_ForcesCircuit(t *testing.T) {
+	backend := newNamedBackend("default")
+	defer backend.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", backend.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+	admin := NewAdminHandler(router)
+	target := "/pools/default/backends/" + url.PathEscape(backend.URL) + "/circuit"
+
+	w := adminRequest(t, admin, "PUT", target, `{"state":"open"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var status AdminBackend
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "open", status.CircuitState)
+	assert.True(t, status.CircuitForced)
+	assert.Equal(t, http.StatusBadGateway, serve(t, router, "GET", "/").Code)
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, admin, "PUT", target, `{"state":"half-open"}`).Code)
+
+	require.Equal(t, http.StatusOK, adminRequest(t, admin, "PUT", target, `{"state":"auto"}`).Code)
+	assert.Equal(t, "default", serve(t, router, "GET", "/").Body.String())
+}
+
+func TestAdminHandler_ChangesStayWithinPool(t *testing.T) {
+	backend := newNamedBackend("shared")
+	defer backend.Close()
+
+	cfg := testConfig(
+		[]config.PoolConfig{testPoolConfig("default", backend.URL), testPoolConfig("other", backend.URL)},
+		[]config.RouteConfig{{Path: "/other/*", Pool: "other"}},
+	)
+	router, err := NewRouter(cfg, &testLogger{})
+	require.NoError(t, err)
+	admin := NewAdminHandler(router)
+	target := "/pools/default/backends/" + url.PathEscape(backend.URL)
+
+	require.Equal(t, http.StatusOK, adminRequest(t, admin, "PUT", target+"/circuit", `{"state":"open"}`).Code)
+	require.Equal(t, http.StatusOK, adminRequest(t, admin, "PUT", target+"/drain", "").Code)
+	assert.NotEqual(t, http.StatusOK, serve(t, router, "GET", "/").Code)
+	assert.Equal(t, "shared", serve(t, router, "GET", "/other/orders").Body.String())
+
+	w := adminRequest(t, admin, "GET", "/pools/other", "")
+	var pool AdminPool
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pool))
+	require.Len(t, pool.Backends, 1)
+	assert.Equal(t, "closed", pool.Backends[0].CircuitState)
+	assert.False(t, pool.Backends[0].CircuitForced)
+	assert.True(t, pool.Backends[0].Healthy)
+	assert.JSONEq(t, `{"url":"`+backend.URL+`","draining":false,"in_flight":0,"drained":false}`,
+		adminRequest(t, admin, "GET", "/pools/other/backends/"+url.PathEscape(backend.URL)+"/drain", "").Body.String())
+}
+
+func TestAdminHandler_DrainsBackend(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	draining := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		w.Write([]byte("draining"))
+	}))
+	defer draining.Close()
+	other := newNamedBackend("other")
+	defer other.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", draining.URL, other.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+	admin := NewAdminHandler(router)
+	target := "/pools/default/backends/" + url.PathEscape(draining.URL) + "/drain"
+
+	done := make(chan string)
+	go func() {
+		done <- serve(t, router, "GET", "/slow").Body.String()
+	}()
+	<-started
+
+	w := adminRequest(t, admin, "PUT", target, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"url":"`+draining.URL+`","draining":true,"in_flight":1,"drained":false}`, w.Body.String())
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "other", serve(t, router, "GET", "/").Body.String())
+	}
+
+	server := httptest.NewUnstartedServer(admin)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+	var body []byte
+	if resp, err := http.Get(server.URL + target + "?wait=200ms"); assert.NoError(t, err) {
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	assert.JSONEq(t, `{"url":"`+draining.URL+`","draining":true,"in_flight":1,"drained":false}`, string(body))
+
+	close(release)
+	assert.Equal(t, "draining", <-done)
+	w = adminRequest(t, admin, "GET", target+"?wait=2s", "")
+	assert.JSONEq(t, `{"url":"`+draining.URL+`","draining":true,"in_flight":0,"drained":true}`, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, admin, "GET", target+"?wait=soon", "").Code)
+
+	w = adminRequest(t, admin, "DELETE", target, "")
+	assert.JSONEq(t, `{"url":"`+draining.URL+`","draining":false,"in_flight":0,"drained":false}`, w.Body.String())
+}
+
+func TestAdminHandler_SetsLogLevels(t *testing.T) {
+	t.Cleanup(func() {
+		logger.SetLevel("info")
+		logger.SetComponentLevel(logger.ComponentProxy, "")
+	})
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", "http://localhost:9")}, nil), &testLogger{})
+	require.NoError(t, err)
+	admin := NewAdminHandler(router)
+
+	w := adminRequest(t, admin, "PUT", "/logging", `{"level":"warn"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = adminRequest(t, admin, "PUT", "/logging/proxy", `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var levels LogLevels
+	require.NoError(t, json.Unmarshal(adminRequest(t, admin, "GET", "/logging", "").Body.Bytes(), &levels))
+	assert.Equal(t, LogLevels{Level: "warn", Components: map[string]string{"proxy": "debug"}}, levels)
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, admin, "PUT", "/logging", `{"level":"development"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(t, admin, "PUT", "/logging/proxy", `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, admin, "PUT", "/logging/router", `{"level":"debug"}`).Code)
+
+	w = adminRequest(t, admin, "DELETE", "/logging/proxy", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var reset LogLevels
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reset))
+	assert.Equal(t, LogLevels{Level: "warn", Components: map[string]string{}}, reset)
+}
@@ -0,0 +1,118 @@
+package routing
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"routing-api/internal/config"
+
+	"github.com/gorilla/mux"
+)
+
+func routeMatchers(route config.RouteConfig) ([]mux.MatcherFunc, error) {
+	var matchers []mux.MatcherFunc
+
+	if len(route.Methods) > 0 {
+		matchers = append(matchers, methodMatcher(route.Methods))
+	}
+
+	for _, header := range route.Headers {
+		matchValue, err := valueMatcher(header)
+		if err != nil {
+			return nil, err
+		}
+		name := header.Name
+		matchers = append(matchers, func(r *http.Request, _ *mux.RouteMatch) bool {
+			return anyMatch(r.Header.Values(name), matchValue)
+		})
+	}
+
+	for _, query := range route.Queries {
+		matchValue, err := valueMatcher(query)
+		if err != nil {
+			return nil, err
+		}
+		name := query.Name
+		matchers = append(matchers, func(r *http.Request, _ *mux.RouteMatch) bool {
+			values, ok := r.URL.Query()[name]
+			return ok && anyMatch(values, matchValue)
+		})
+	}
+
+	if len(route.SourceCIDRs) > 0 {
+		matcher, err := sourceMatcher(route.SourceCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
+
+func methodMatcher(methods []string) mux.MatcherFunc {
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		for _, method := range methods {
+			if strings.EqualFold(r.Method, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func valueMatcher(match config.ValueMatch) (func(string) bool, error) {
+	switch {
+	case match.Regex != "":
+		re, err := regexp.Compile(match.Regex)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case match.Exact != "":
+		return func(value string) bool { return value == match.Exact }, nil
+	default:
+		return func(string) bool { return true }, nil
+	}
+}
+
+func anyMatch(values []string, match func(string) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sourceMatcher(cidrs []string) (mux.MatcherFunc, error) {
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes[i] = prefix.Masked()
+	}
+
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
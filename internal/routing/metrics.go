@@ -0,0 +1,59 @@
+package routing
+
+import (
+	"routing-api/internal/circuit"
+	"routing-api/internal/metrics"
+)
+
+var circuitStates = []circuit.CircuitBreakerState{circuit.StateClosed, circuit.StateOpen, circuit.StateHalfOpen}
+
+// RegisterMetrics adds gauges to registry for the health, circuit state and
+// in-flight requests of the backends in r's current route table. They are read
+// on every scrape, so backends that are removed stop being reported. Other
+// series of registry with a backend label are deleted once a removed backend has
+// drained.
+func RegisterMetrics(registry *metrics.Registry, r *Router) {
+	r.mutex.Lock()
+	r.metrics = registry
+	r.mutex.Unlock()
+
+	registry.NewGaugeFunc("routing_api_backend_up",
+		"Whether a backend passes its health checks.",
+		[]string{"pool", "backend"},
+		func(set func(float64, ...string)) {
+			for _, pool := range r.PoolStatuses() {
+				for _, backend := range pool.Backends {
+					set(boolValue(backend.Healthy), pool.Name, backend.URL)
+				}
+			}
+		})
+	registry.NewGaugeFunc("routing_api_backend_circuit_state",
+		"Circuit breaker state of a backend: 1 for the current state, 0 for the others.",
+		[]string{"pool", "backend", "state"},
+		func(set func(float64, ...string)) {
+			for _, pool := range r.PoolStatuses() {
+				for _, backend := range pool.Backends {
+					for _, state := range circuitStates {
+						set(boolValue(backend.CircuitState == state.String()), pool.Name, backend.URL, state.String())
+					}
+				}
+			}
+		})
+	registry.NewGaugeFunc("routing_api_backend_in_flight",
+		"Requests a backend is serving.",
+		[]string{"pool", "backend"},
+		func(set func(float64, ...string)) {
+			for _, pool := range r.PoolStatuses() {
+				for _, backend := range pool.Backends {
+					set(float64(backend.InFlight), pool.Name, backend.URL)
+				}
+			}
+		})
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
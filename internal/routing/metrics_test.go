@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/config"
+	"routing-api/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", "http://a:8080")}, nil), &testLogger{})
+	require.NoError(t, err)
+	registry := metrics.NewRegistry()
+	RegisterMetrics(registry, router)
+
+	pool, _ := router.Pool("default")
+	pool.Backends.Backends()[0].Client.(*circuit.CircuitBreakerClient).ForceCircuit(circuit.StateOpen)
+
+	var out strings.Builder
+	_, err = registry.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `routing_api_backend_up{pool="default",backend="http://a:8080"} 1`)
+	assert.Contains(t, out.String(), `routing_api_backend_circuit_state{pool="default",backend="http://a:8080",state="closed"} 0`)
+	assert.Contains(t, out.String(), `routing_api_backend_circuit_state{pool="default",backend="http://a:8080",state="open"} 1`)
+	assert.Contains(t, out.String(), `routing_api_backend_in_flight{pool="default",backend="http://a:8080"} 0`)
+
+	require.NoError(t, router.Reload(testConfig([]config.PoolConfig{testPoolConfig("default", "http://b:8080")}, nil)))
+	out.Reset()
+	registry.WriteTo(&out)
+	assert.NotContains(t, out.String(), "http://a:8080")
+	assert.Contains(t, out.String(), `routing_api_backend_up{pool="default",backend="http://b:8080"} 1`)
+}
+
+func TestRegisterMetrics_DeletesSeriesOfRemovedBackends(t *testing.T) {
+	pools := []config.PoolConfig{testPoolConfig("default", "http://a:8080", "http://b:8080"), testPoolConfig("other", "http://b:8080")}
+	router, err := NewRouter(testConfig(pools, nil), &testLogger{})
+	require.NoError(t, err)
+	registry := metrics.NewRegistry()
+	RegisterMetrics(registry, router)
+	requests := registry.NewCounterVec("requests_total", "Requests.", "backend")
+	requests.WithLabelValues("http://a:8080").Inc()
+	requests.WithLabelValues("http://b:8080").Inc()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router.Start(ctx)
+
+	pool, _ := router.Pool("default")
+	require.NoError(t, router.RemoveBackend(pool, "http://b:8080"))
+	require.NoError(t, router.Reload(testConfig([]config.PoolConfig{testPoolConfig("default", "http://c:8080"), testPoolConfig("other", "http://b:8080")}, nil)))
+
+	scrape := func() string {
+		var out strings.Builder
+		registry.WriteTo(&out)
+		return out.String()
+	}
+	assert.Eventually(t, func() bool { return !strings.Contains(scrape(), `requests_total{backend="http://a:8080"}`) }, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, scrape(), `requests_total{backend="http://b:8080"} 1`)
+}
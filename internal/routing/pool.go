@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"context"
+	"time"
+
+	"routing-api/internal/circuit"
+	"routing-api/internal/config"
+	"routing-api/internal/discovery"
+	"routing-api/internal/health"
+	"routing-api/internal/loadbalancer"
+	"routing-api/internal/logger"
+	"routing-api/internal/proxy"
+
+	"go.uber.org/zap"
+)
+
+type Pool struct {
+	Name                string
+	Required            bool
+	HealthCheckInterval time.Duration
+	ClientProvider      loadbalancer.ClientProvider
+	Backends            loadbalancer.BackendManager
+}
+
+func NewPool(poolConfig config.PoolConfig, logger logger.Logger) *Pool {
+	return NewPoolWithClients(poolConfig, loadbalancer.NewClientCache(), logger)
+}
+
+func NewPoolWithClients(poolConfig config.PoolConfig, clients *loadbalancer.ClientCache, logger logger.Logger) *Pool {
+	poolLogger := logger.With(zap.String("pool", poolConfig.Name))
+	balancerConfig := loadbalancer.BalancerConfig{
+		Pool:    poolConfig.Name,
+		Type:    poolConfig.BalancerType,
+		Servers: poolConfig.APIs,
+		Weights: poolConfig.Weights,
+		Circuit: circuit.CircuitBreakerConfig{
+			MaxFailures:   poolConfig.MaxFailures,
+			ResetTimeout:  poolConfig.ResetTimeout,
+			SlowThreshold: poolConfig.SlowThreshold,
+			MaxSlowCount:  poolConfig.MaxSlowCount,
+		},
+		Transport: health.TransportConfig{
+			Protocol:              poolConfig.BackendProtocol,
+			ConnectTimeout:        poolConfig.ConnectTimeout,
+			ResponseHeaderTimeout: poolConfig.ResponseTimeout,
+		},
+		HealthCheck: health.HealthCheckConfig{
+			Path: poolConfig.HealthCheckPath,
+		},
+		Clients:   clients,
+		Discovery: discovery.New(poolConfig.Discovery, poolLogger),
+	}
+
+	loadBalancer := loadbalancer.NewLoadBalancerFactory().CreateLoadBalancerWithConfig(balancerConfig, poolLogger)
+
+	return &Pool{
+		Name:                poolConfig.Name,
+		Required:            poolConfig.Required,
+		HealthCheckInterval: poolConfig.HealthCheckInterval,
+		ClientProvider:      loadbalancer.NewLoadBalancerAdapter(loadBalancer),
+		Backends:            loadBalancer,
+	}
+}
+
+// WatchDiscovery keeps the pool's backends in step with its discovery source
+// until ctx is done.
+func (p *Pool) WatchDiscovery(ctx context.Context, drain func(health.HTTPClient)) {
+	p.Backends.WatchDiscovery(ctx, drain)
+}
+
+func (p *Pool) StartHealthChecks(ctx context.Context) {
+	p.ClientProvider.StartHealthChecks(ctx, p.HealthCheckInterval)
+}
+
+func (p *Pool) Status() proxy.PoolStatus {
+	return proxy.PoolStatus{
+		Name:     p.Name,
+		Required: p.Required,
+		Healthy:  p.ClientProvider.HasHealthyBackend(),
+		Backends: p.ClientProvider.Status(),
+	}
+}
@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_DiscoveryFile(t *testing.T) {
+	first := newNamedBackend("first")
+	defer first.Close()
+	second := newNamedBackend("second")
+	defer second.Close()
+
+	path := filepath.Join(t.TempDir(), "backends.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("- "+first.URL+"\n"), 0o644))
+
+	poolConfig := testPoolConfig("default")
+	poolConfig.Discovery = config.DiscoveryConfig{File: path, Interval: 10 * time.Millisecond}
+	router, err := NewRouter(testConfig([]config.PoolConfig{poolConfig}, nil), &testLogger{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router.Start(ctx)
+
+	// Discovered backends get requests once they pass a health check.
+	assert.Eventually(t, func() bool {
+		return serve(t, router, "GET", "/").Body.String() == "first"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte("- "+second.URL+"\n"), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+
+	assert.Eventually(t, func() bool {
+		backends := router.PoolStatuses()[0].Backends
+		return len(backends) == 1 && backends[0].URL == second.URL && backends[0].Healthy
+	}, 2*time.Second, 10*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "second", serve(t, router, "GET", "/").Body.String())
+	}
+}
@@ -0,0 +1,176 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/health"
+	"routing-api/internal/loadbalancer"
+	"routing-api/internal/logger"
+	"routing-api/internal/metrics"
+	"routing-api/internal/proxy"
+
+	"go.uber.org/zap"
+)
+
+const drainPollInterval = 100 * time.Millisecond
+
+// Router serves requests from the current route table. Reload builds a table from a
+// new configuration and swaps it in atomically; requests already in progress finish
+// on the table they started on.
+type Router struct {
+	table   atomic.Pointer[Table]
+	mutex   sync.Mutex
+	clients *loadbalancer.ClientCache
+	ctx     context.Context
+	stop    context.CancelFunc
+	logger  logger.Logger
+	// metrics is the registry given to RegisterMetrics, whose backend series
+	// are deleted once a removed backend has drained.
+	metrics *metrics.Registry
+}
+
+func NewRouter(cfg *config.Config, logger logger.Logger) (*Router, error) {
+	clients := loadbalancer.NewClientCache()
+	table, err := NewTableWithClients(cfg, clients, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	router := &Router{clients: clients, logger: logger}
+	router.table.Store(table)
+	return router, nil
+}
+
+// Start runs health checks for the current table and for every table swapped in
+// later, until ctx is done.
+func (r *Router) Start(ctx context.Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.ctx = ctx
+	r.startHealthChecks(r.table.Load())
+}
+
+// Reload replaces the route table. Backends whose URL and settings are unchanged keep
+// their clients, and with them their health and circuit state. Removed backends finish
+// their in-flight requests before their connections are closed. When the new
+// configuration cannot be built the current table keeps serving.
+func (r *Router) Reload(cfg *config.Config) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	clients := r.clients.Rebuild()
+	table, err := NewTableWithClients(cfg, clients, r.logger)
+	if err != nil {
+		return err
+	}
+
+	r.table.Store(table)
+	r.clients = clients
+	if r.ctx != nil {
+		r.startHealthChecks(table)
+		for _, client := range clients.Retired() {
+			go r.drain(client)
+		}
+	}
+	return nil
+}
+
+func (r *Router) startHealthChecks(table *Table) {
+	if r.stop != nil {
+		r.stop()
+	}
+	var ctx context.Context
+	ctx, r.stop = context.WithCancel(r.ctx)
+	table.StartHealthChecks(ctx)
+	for _, pool := range table.Pools() {
+		go pool.WatchDiscovery(ctx, r.drain)
+	}
+}
+
+func (r *Router) drain(client health.HTTPClient) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for client.InFlight() > 0 {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if closer, ok := client.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+	r.logger.Info("Removed backend drained", zap.String("backend_url", client.GetBaseURL()))
+	r.forgetMetrics(client.GetBaseURL())
+}
+
+// forgetMetrics deletes the series of a backend that no pool uses any more, so
+// that backends coming and going do not add series without end.
+func (r *Router) forgetMetrics(serverURL string) {
+	r.mutex.Lock()
+	registry := r.metrics
+	r.mutex.Unlock()
+	if registry == nil {
+		return
+	}
+	for _, pool := range r.PoolStatuses() {
+		for _, backend := range pool.Backends {
+			if backend.URL == serverURL {
+				return
+			}
+		}
+	}
+	registry.Delete("backend", serverURL)
+}
+
+func (r *Router) Table() *Table {
+	return r.table.Load()
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.table.Load().ServeHTTP(w, req)
+}
+
+func (r *Router) PoolStatuses() []proxy.PoolStatus {
+	return r.table.Load().PoolStatuses()
+}
+
+func (r *Router) Pools() []*Pool {
+	return r.table.Load().Pools()
+}
+
+func (r *Router) Pool(name string) (*Pool, bool) {
+	return r.table.Load().Pool(name)
+}
+
+// RemoveBackend takes a backend out of a pool of the current table. Its in-flight
+// requests finish before its connections are closed.
+func (r *Router) RemoveBackend(pool *Pool, serverURL string) error {
+	client, err := pool.Backends.RemoveBackend(serverURL)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.ctx != nil {
+		go r.drain(client)
+	}
+	return nil
+}
+
+func (r *Router) Splits() []*Split {
+	return r.table.Load().Splits()
+}
+
+func (r *Router) Split(name string) (*Split, bool) {
+	return r.table.Load().Split(name)
+}
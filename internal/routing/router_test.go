@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_ReloadSwapsRoutes(t *testing.T) {
+	first := newNamedBackend("first")
+	defer first.Close()
+	second := newNamedBackend("second")
+	defer second.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", first.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router.Start(ctx)
+
+	assert.Equal(t, "first", serve(t, router, "GET", "/orders").Body.String())
+
+	err = router.Reload(testConfig(
+		[]config.PoolConfig{testPoolConfig("default", first.URL), testPoolConfig("orders", second.URL)},
+		[]config.RouteConfig{{Path: "/orders/*", Pool: "orders"}},
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, "second", serve(t, router, "GET", "/orders").Body.String())
+	assert.Equal(t, "first", serve(t, router, "GET", "/users").Body.String())
+	assert.Len(t, router.PoolStatuses(), 2)
+}
+
+func TestRouter_ReloadKeepsBackendState(t *testing.T) {
+	kept := newNamedBackend("kept")
+	defer kept.Close()
+	added := newNamedBackend("added")
+	defer added.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", kept.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+
+	router.Table().Pools()[0].ClientProvider.GetClient().SetUp(false)
+
+	err = router.Reload(testConfig([]config.PoolConfig{testPoolConfig("default", kept.URL, added.URL)}, nil))
+	require.NoError(t, err)
+
+	backends := router.PoolStatuses()[0].Backends
+	require.Len(t, backends, 2)
+	assert.False(t, backends[0].Healthy)
+	assert.True(t, backends[1].Healthy)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "added", serve(t, router, "GET", "/").Body.String())
+	}
+}
+
+func TestRouter_RejectsInvalidConfig(t *testing.T) {
+	backend := newNamedBackend("default")
+	defer backend.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", backend.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+
+	err = router.Reload(testConfig(
+		[]config.PoolConfig{testPoolConfig("default", backend.URL)},
+		[]config.RouteConfig{{Path: "/orders/*", Pool: "missing"}},
+	))
+	assert.Error(t, err)
+	assert.Equal(t, "default", serve(t, router, "GET", "/orders").Body.String())
+}
+
+func TestRouter_RemovedBackendFinishesInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	removed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("removed"))
+	}))
+	defer removed.Close()
+	replacement := newNamedBackend("replacement")
+	defer replacement.Close()
+
+	router, err := NewRouter(testConfig([]config.PoolConfig{testPoolConfig("default", removed.URL)}, nil), &testLogger{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router.Start(ctx)
+
+	done := make(chan string)
+	go func() {
+		done <- serve(t, router, "GET", "/slow").Body.String()
+	}()
+	<-started
+
+	require.NoError(t, router.Reload(testConfig([]config.PoolConfig{testPoolConfig("default", replacement.URL)}, nil)))
+	assert.Equal(t, "replacement", serve(t, router, "GET", "/fast").Body.String())
+
+	close(release)
+	select {
+	case body := <-done:
+		assert.Equal(t, "removed", body)
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight request did not finish")
+	}
+}
@@ -0,0 +1,209 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync/atomic"
+
+	"routing-api/internal/config"
+
+	"github.com/gorilla/mux"
+)
+
+// PoolHeader names the pool that served a response, on routes with pool-header.
+const PoolHeader = "X-Routing-Pool"
+
+// Split spreads a route's traffic across pools in proportion to their weights.
+// When a sticky header or cookie is present, its hash picks the pool so the same
+// client keeps landing on the same pool while the weights are unchanged.
+type Split struct {
+	Name         string
+	stickyHeader string
+	stickyCookie string
+	poolHeader   bool
+	pools        []string
+	handlers     []http.Handler
+	weights      atomic.Pointer[[]int]
+}
+
+func newSplit(route config.RouteConfig, handlers []http.Handler) *Split {
+	split := &Split{
+		Name:         route.RouteName(),
+		stickyHeader: route.StickyHeader,
+		stickyCookie: route.StickyCookie,
+		poolHeader:   route.PoolHeader,
+		handlers:     handlers,
+	}
+	weights := make([]int, len(route.Splits))
+	for i, target := range route.Splits {
+		split.pools = append(split.pools, target.Pool)
+		weights[i] = target.Weight
+	}
+	split.weights.Store(&weights)
+	return split
+}
+
+func (s *Split) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i := s.pick(r)
+	if s.poolHeader {
+		w.Header().Set(PoolHeader, s.pools[i])
+	}
+	s.handlers[i].ServeHTTP(w, r)
+}
+
+func (s *Split) pick(r *http.Request) int {
+	weights := *s.weights.Load()
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	var n int
+	if key := s.stickyKey(r); key != "" {
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		n = int(hash.Sum32() % uint32(total))
+	} else {
+		n = rand.IntN(total)
+	}
+
+	for i, weight := range weights {
+		if n < weight {
+			return i
+		}
+		n -= weight
+	}
+	return len(weights) - 1
+}
+
+func (s *Split) stickyKey(r *http.Request) string {
+	if s.stickyHeader != "" {
+		if value := r.Header.Get(s.stickyHeader); value != "" {
+			return value
+		}
+	}
+	if s.stickyCookie != "" {
+		if cookie, err := r.Cookie(s.stickyCookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func (s *Split) Weights() map[string]int {
+	weights := *s.weights.Load()
+	result := make(map[string]int, len(weights))
+	for i, pool := range s.pools {
+		result[pool] = weights[i]
+	}
+	return result
+}
+
+// SetWeights replaces the weights atomically. Pools left out of the map get a weight of zero.
+func (s *Split) SetWeights(weights map[string]int) error {
+	pools := make(map[string]bool, len(s.pools))
+	for _, pool := range s.pools {
+		pools[pool] = true
+	}
+
+	targets := make([]config.WeightedPool, len(s.pools))
+	for i, pool := range s.pools {
+		targets[i] = config.WeightedPool{Pool: pool, Weight: weights[pool]}
+	}
+	for pool := range weights {
+		if !pools[pool] {
+			return fmt.Errorf("pool %q is not part of split %q", pool, s.Name)
+		}
+	}
+	if err := config.ValidateSplitWeights(targets, pools); err != nil {
+		return err
+	}
+
+	updated := make([]int, len(targets))
+	for i, target := range targets {
+		updated[i] = target.Weight
+	}
+	s.weights.Store(&updated)
+	return nil
+}
+
+type SplitStatus struct {
+	Route   string         `json:"route"`
+	Weights map[string]int `json:"weights"`
+}
+
+type SplitProvider interface {
+	Splits() []*Split
+	Split(name string) (*Split, bool)
+}
+
+type SplitHandler struct {
+	table SplitProvider
+}
+
+func NewSplitHandler(table SplitProvider) *SplitHandler {
+	return &SplitHandler{table: table}
+}
+
+func (h *SplitHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	splits := h.table.Splits()
+	statuses := make([]SplitStatus, len(splits))
+	for i, split := range splits {
+		statuses[i] = SplitStatus{Route: split.Name, Weights: split.Weights()}
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (h *SplitHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["route"]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	split, ok := h.table.Split(name)
+	if !ok {
+		// Unnamed routes are named after their path, which loses its leading slash in the URL.
+		split, ok = h.table.Split("/" + name)
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("no split route %q", name), http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Weights map[string]int `json:"weights"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Weights == nil {
+		http.Error(w, "weights are required", http.StatusBadRequest)
+		return
+	}
+	if err := split.SetWeights(body.Weights); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SplitStatus{Route: split.Name, Weights: split.Weights()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func sortedSplits(splits map[string]*Split) []*Split {
+	result := make([]*Split, 0, len(splits))
+	for _, split := range splits {
+		result = append(result, split)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
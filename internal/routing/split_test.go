@@ -0,0 +1,133 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"routing-api/internal/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSplitTable(t *testing.T, route config.RouteConfig) *Table {
+	t.Helper()
+	stable := newNamedBackend("stable")
+	t.Cleanup(stable.Close)
+	canary := newNamedBackend("canary")
+	t.Cleanup(canary.Close)
+
+	cfg := testConfig(
+		[]config.PoolConfig{
+			testPoolConfig("default", stable.URL),
+			testPoolConfig("stable", stable.URL),
+			testPoolConfig("canary", canary.URL),
+		},
+		[]config.RouteConfig{route},
+	)
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+	return table
+}
+
+func TestSplit_DistributesByWeight(t *testing.T) {
+	table := newSplitTable(t, config.RouteConfig{
+		Path:       "/api/*",
+		Splits:     []config.WeightedPool{{Pool: "stable", Weight: 80}, {Pool: "canary", Weight: 20}},
+		PoolHeader: true,
+	})
+
+	counts := make(map[string]int)
+	for i := 0; i < 500; i++ {
+		w := serve(t, table, "GET", "/api/orders")
+		require.Equal(t, http.StatusOK, w.Code)
+		pool := w.Header().Get(PoolHeader)
+		assert.Equal(t, pool, w.Body.String())
+		counts[pool]++
+	}
+
+	assert.InDelta(t, 400, counts["stable"], 60)
+	assert.InDelta(t, 100, counts["canary"], 60)
+}
+
+func TestSplit_PoolHeaderIsOptIn(t *testing.T) {
+	table := newSplitTable(t, config.RouteConfig{
+		Path:   "/api/*",
+		Splits: []config.WeightedPool{{Pool: "stable", Weight: 1}, {Pool: "canary", Weight: 1}},
+	})
+
+	w := serve(t, table, "GET", "/api/orders")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Header(), PoolHeader)
+}
+
+func TestSplit_Sticky(t *testing.T) {
+	table := newSplitTable(t, config.RouteConfig{
+		Path:         "/api/*",
+		Splits:       []config.WeightedPool{{Pool: "stable", Weight: 50}, {Pool: "canary", Weight: 50}},
+		StickyHeader: "X-User-ID",
+		StickyCookie: "user",
+		PoolHeader:   true,
+	})
+
+	pick := func(header, cookie string) string {
+		req := httptest.NewRequest("GET", "/api/orders", nil)
+		if header != "" {
+			req.Header.Set("X-User-ID", header)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "user", Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		table.ServeHTTP(w, req)
+		return w.Header().Get(PoolHeader)
+	}
+
+	for _, user := range []string{"alice", "bob", "carol", "dave"} {
+		first := pick(user, "")
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, pick(user, ""))
+			assert.Equal(t, first, pick("", user))
+		}
+	}
+}
+
+func TestSplit_RuntimeWeights(t *testing.T) {
+	table := newSplitTable(t, config.RouteConfig{
+		Name:       "api",
+		Path:       "/api/*",
+		Splits:     []config.WeightedPool{{Pool: "stable", Weight: 100}, {Pool: "canary", Weight: 0}},
+		PoolHeader: true,
+	})
+
+	router := mux.NewRouter()
+	handler := NewSplitHandler(table)
+	router.HandleFunc("/splits", handler.ListHandler).Methods("GET")
+	router.HandleFunc("/splits/{route:.+}", handler.UpdateHandler).Methods("PUT")
+
+	w := serve(t, router, "GET", "/splits")
+	assert.JSONEq(t, `[{"route":"api","weights":{"stable":100,"canary":0}}]`, w.Body.String())
+
+	update := func(route, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/splits/"+route, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = update("api", `{"weights":{"canary":100}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"route":"api","weights":{"stable":0,"canary":100}}`, w.Body.String())
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "canary", serve(t, table, "GET", "/api/orders").Header().Get(PoolHeader))
+	}
+
+	assert.Equal(t, http.StatusBadRequest, update("api", `{"weights":{"stable":0}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, update("api", `{"weights":{"other":10}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, update("api", `not json`).Code)
+	assert.Equal(t, http.StatusNotFound, update("missing", `{"weights":{"stable":1}}`).Code)
+	assert.Equal(t, map[string]int{"stable": 0, "canary": 100}, table.Splits()[0].Weights())
+}
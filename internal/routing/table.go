@@ -0,0 +1,353 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"routing-api/internal/config"
+	"routing-api/internal/loadbalancer"
+	"routing-api/internal/logger"
+	"routing-api/internal/proxy"
+	"routing-api/internal/tracing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type Table struct {
+	pools   []*Pool
+	splits  map[string]*Split
+	router  *mux.Router
+	handler http.Handler
+}
+
+func NewTable(cfg *config.Config, logger logger.Logger) (*Table, error) {
+	return NewTableWithClients(cfg, loadbalancer.NewClientCache(), logger)
+}
+
+func NewTableWithClients(cfg *config.Config, clients *loadbalancer.ClientCache, logger logger.Logger) (*Table, error) {
+	table := &Table{router: mux.NewRouter()}
+	table.handler = table.router
+	if cfg.HTTPSRedirect {
+		table.handler = httpsRedirect(table.router)
+	}
+	builder := &tableBuilder{
+		cfg:    cfg,
+		pools:  make(map[string]*Pool, len(cfg.Pools)),
+		splits: make(map[string]*Split),
+		logger: logger,
+	}
+	table.splits = builder.splits
+
+	for _, poolConfig := range cfg.Pools {
+		pool := NewPoolWithClients(poolConfig, clients, logger)
+		table.pools = append(table.pools, pool)
+		builder.pools[pool.Name] = pool
+	}
+
+	var sharedRoutes []config.RouteConfig
+	hostRoutes := make(map[string][]config.RouteConfig)
+	for _, route := range cfg.Routes {
+		if route.Host == "" {
+			sharedRoutes = append(sharedRoutes, route)
+		} else {
+			hostRoutes[route.Host] = append(hostRoutes[route.Host], route)
+		}
+	}
+
+	if len(cfg.VirtualHosts) == 0 {
+		if err := builder.registerRoutes(table.router, sharedRoutes, cfg.DefaultPool); err != nil {
+			return nil, err
+		}
+		return table, nil
+	}
+
+	hosts := append([]config.VirtualHostConfig(nil), cfg.VirtualHosts...)
+	sortHosts(hosts)
+
+	for _, host := range hosts {
+		routes := append(hostRoutes[host.Host], sharedRoutes...)
+		subrouter := table.router.Host(hostTemplate(host.Host)).Subrouter()
+		if err := builder.registerRoutes(subrouter, routes, host.DefaultPool); err != nil {
+			return nil, fmt.Errorf("virtual host %q: %w", host.Host, err)
+		}
+	}
+
+	unmatchedStatus := cfg.UnmatchedHostStatus
+	table.router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no route for host "+r.Host, unmatchedStatus)
+	})
+
+	return table, nil
+}
+
+type tableBuilder struct {
+	cfg    *config.Config
+	pools  map[string]*Pool
+	splits map[string]*Split
+	logger logger.Logger
+}
+
+// registerRoutes adds routes in order of specificity followed by a catch-all for defaultPool.
+// Host-specific routes take precedence over shared routes of equal specificity.
+func (b *tableBuilder) registerRoutes(router *mux.Router, routes []config.RouteConfig, defaultPool string) error {
+	routes = append([]config.RouteConfig(nil), routes...)
+	sortRoutes(routes)
+
+	for _, route := range routes {
+		handler, err := b.routeHandler(route)
+		if err != nil {
+			return err
+		}
+		matchers, err := routeMatchers(route)
+		if err != nil {
+			return fmt.Errorf("route %q: %w", route.Path, err)
+		}
+		registerPath(router, route.Path, handler, matchers...)
+	}
+
+	pool, ok := b.pools[defaultPool]
+	if !ok {
+		return fmt.Errorf("default pool %q is not defined", defaultPool)
+	}
+	defaultRoute := config.RouteConfig{Path: "/*", Pool: pool.Name}
+	handler, err := b.newRouteHandler(defaultRoute, pool)
+	if err != nil {
+		return err
+	}
+	router.PathPrefix("/").Handler(handler)
+	return nil
+}
+
+func (b *tableBuilder) routeHandler(route config.RouteConfig) (http.Handler, error) {
+	handler, err := b.routeAction(route)
+	if err != nil {
+		return nil, err
+	}
+	if route.HTTPSRedirect {
+		handler = httpsRedirect(handler)
+	}
+	return handler, nil
+}
+
+// routeAction returns the handler that answers a route's requests. Split routes shared
+// between virtual hosts reuse one Split so weight changes apply to every host.
+func (b *tableBuilder) routeAction(route config.RouteConfig) (http.Handler, error) {
+	switch route.Action {
+	case config.ActionRedirect:
+		return newRedirectHandler(route), nil
+	case config.ActionRespond:
+		return newDirectResponseHandler(route)
+	}
+
+	if len(route.Splits) == 0 {
+		pool, ok := b.pools[route.Pool]
+		if !ok {
+			return nil, fmt.Errorf("route %q references unknown pool %q", route.Path, route.Pool)
+		}
+		return b.newRouteHandler(route, pool)
+	}
+
+	if split, ok := b.splits[route.RouteName()]; ok {
+		return split, nil
+	}
+	handlers := make([]http.Handler, len(route.Splits))
+	for i, target := range route.Splits {
+		pool, ok := b.pools[target.Pool]
+		if !ok {
+			return nil, fmt.Errorf("route %q references unknown pool %q", route.Path, target.Pool)
+		}
+		handler, err := b.newRouteHandler(route, pool)
+		if err != nil {
+			return nil, err
+		}
+		handlers[i] = handler
+	}
+	split := newSplit(route, handlers)
+	b.splits[split.Name] = split
+	return split, nil
+}
+
+func (t *Table) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.handler.ServeHTTP(w, r)
+}
+
+func (t *Table) StartHealthChecks(ctx context.Context) {
+	for _, pool := range t.pools {
+		go pool.StartHealthChecks(ctx)
+	}
+}
+
+func (t *Table) Pools() []*Pool {
+	return t.pools
+}
+
+func (t *Table) Pool(name string) (*Pool, bool) {
+	for _, pool := range t.pools {
+		if pool.Name == name {
+			return pool, true
+		}
+	}
+	return nil, false
+}
+
+func (t *Table) Splits() []*Split {
+	return sortedSplits(t.splits)
+}
+
+func (t *Table) Split(name string) (*Split, bool) {
+	split, ok := t.splits[name]
+	return split, ok
+}
+
+func (t *Table) PoolStatuses() []proxy.PoolStatus {
+	statuses := make([]proxy.PoolStatus, len(t.pools))
+	for i, pool := range t.pools {
+		statuses[i] = pool.Status()
+	}
+	return statuses
+}
+
+func (b *tableBuilder) newRouteHandler(route config.RouteConfig, pool *Pool) (http.Handler, error) {
+	cfg := b.cfg
+	proxyConfig := proxy.ProxyConfig{
+		Route:              route.RouteName(),
+		RequestTimeout:     cfg.RequestTimeout,
+		FlushInterval:      cfg.FlushInterval,
+		UpgradeIdleTimeout: cfg.UpgradeIdleTimeout,
+		UpgradeMaxLifetime: cfg.UpgradeMaxLifetime,
+		Tracer:             tracing.Global(),
+	}
+	if route.FlushInterval != 0 {
+		proxyConfig.FlushInterval = route.FlushInterval
+	}
+	if shadow, ok := b.pools[route.MirrorPool]; ok {
+		proxyConfig.Mirror = &proxy.MirrorConfig{
+			ClientProvider: shadow.ClientProvider,
+			Percent:        route.MirrorPercent,
+		}
+	}
+
+	rewrite, err := routeRewrite(route)
+	if err != nil {
+		return nil, fmt.Errorf("route %q: %w", route.RouteName(), err)
+	}
+	proxyConfig.Rewrite = rewrite
+
+	routeLogger := b.logger.With(zap.String("route", route.RouteName()), zap.String("pool", pool.Name))
+	handler := proxy.NewProxyHandlerWithConfig(pool.ClientProvider, proxyConfig, routeLogger)
+	return http.HandlerFunc(handler.ProxyRequest), nil
+}
+
+func routeRewrite(route config.RouteConfig) (*proxy.RewriteConfig, error) {
+	if route.StripPrefix == "" && route.RegexRewrite == "" && isEmpty(route.RequestHeaders) && isEmpty(route.ResponseHeaders) {
+		return nil, nil
+	}
+
+	rewrite := &proxy.RewriteConfig{
+		StripPrefix:      route.StripPrefix,
+		PrefixRewrite:    route.PrefixRewrite,
+		RegexReplacement: route.RegexReplacement,
+		RequestHeaders:   headerRules(route.RequestHeaders),
+		ResponseHeaders:  headerRules(route.ResponseHeaders),
+	}
+	if route.RegexRewrite != "" {
+		re, err := regexp.Compile(route.RegexRewrite)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rewrite regex: %w", err)
+		}
+		rewrite.Regex = re
+	}
+	return rewrite, nil
+}
+
+func headerRules(rules config.HeaderRules) proxy.HeaderRules {
+	converted := proxy.HeaderRules{Remove: rules.Remove}
+	for _, header := range rules.Set {
+		converted.Set = append(converted.Set, proxy.HeaderValue{Name: header.Name, Value: header.Value})
+	}
+	for _, header := range rules.Add {
+		converted.Add = append(converted.Add, proxy.HeaderValue{Name: header.Name, Value: header.Value})
+	}
+	return converted
+}
+
+func isEmpty(rules config.HeaderRules) bool {
+	return len(rules.Set) == 0 && len(rules.Add) == 0 && len(rules.Remove) == 0
+}
+
+func registerPath(router *mux.Router, path string, handler http.Handler, matchers ...mux.MatcherFunc) {
+	var routes []*mux.Route
+	prefix, isPrefix := strings.CutSuffix(path, "*")
+	if !isPrefix {
+		routes = append(routes, router.Path(path))
+	} else {
+		if trimmed := strings.TrimSuffix(prefix, "/"); trimmed != "" && trimmed != prefix {
+			routes = append(routes, router.Path(trimmed))
+		}
+		routes = append(routes, router.PathPrefix(prefix))
+	}
+
+	for _, route := range routes {
+		for _, matcher := range matchers {
+			route.MatcherFunc(matcher)
+		}
+		route.Handler(handler)
+	}
+}
+
+// sortRoutes orders routes so the most specific pattern is matched first:
+// more literal characters win, an exact pattern beats a prefix of the same length,
+// and among equal paths the route with more match conditions wins.
+func sortRoutes(routes []config.RouteConfig) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		iLiteral, iExact := specificity(routes[i].Path)
+		jLiteral, jExact := specificity(routes[j].Path)
+		if iLiteral != jLiteral {
+			return iLiteral > jLiteral
+		}
+		if iExact != jExact {
+			return iExact
+		}
+		return routes[i].Conditions() > routes[j].Conditions()
+	})
+}
+
+func specificity(path string) (int, bool) {
+	literal := 0
+	depth := 0
+	for _, c := range path {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case depth == 0 && c != '*':
+			literal++
+		}
+	}
+	return literal, !strings.HasSuffix(path, "*")
+}
+
+// sortHosts orders exact hosts before wildcards, and longer wildcard suffixes first.
+func sortHosts(hosts []config.VirtualHostConfig) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		iWildcard := strings.HasPrefix(hosts[i].Host, "*.")
+		jWildcard := strings.HasPrefix(hosts[j].Host, "*.")
+		if iWildcard != jWildcard {
+			return !iWildcard
+		}
+		return len(hosts[i].Host) > len(hosts[j].Host)
+	})
+}
+
+func hostTemplate(host string) string {
+	if suffix, ok := strings.CutPrefix(host, "*."); ok {
+		return "{subdomain:.+}." + suffix
+	}
+	return host
+}
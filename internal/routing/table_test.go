@@ -0,0 +1,270 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testLogger struct{}
+
+func (l *testLogger) Debug(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Info(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Warn(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Error(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Fatal(msg string, fields ...zap.Field)  {}
+func (l *testLogger) With(fields ...zap.Field) logger.Logger { return l }
+func (l *testLogger) Sync() error                            { return nil }
+
+func newNamedBackend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+}
+
+func testPoolConfig(name string, apis ...string) config.PoolConfig {
+	return config.PoolConfig{
+		Name:                name,
+		APIs:                apis,
+		BalancerType:        "round-robin",
+		BackendProtocol:     "http1",
+		Required:            true,
+		HealthCheckInterval: time.Second,
+		HealthCheckPath:     "/health",
+		MaxFailures:         5,
+		ResetTimeout:        time.Minute,
+	}
+}
+
+func testConfig(pools []config.PoolConfig, routes []config.RouteConfig) *config.Config {
+	return &config.Config{
+		RequestTimeout: 5 * time.Second,
+		Pools:          pools,
+		Routes:         routes,
+		DefaultPool:    config.DefaultPoolName,
+	}
+}
+
+func serve(t *testing.T, handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestTable_LongestMatchPrecedence(t *testing.T) {
+	defaultBackend := newNamedBackend("default")
+	defer defaultBackend.Close()
+	ordersBackend := newNamedBackend("orders")
+	defer ordersBackend.Close()
+	itemsBackend := newNamedBackend("items")
+	defer itemsBackend.Close()
+	usersBackend := newNamedBackend("users")
+	defer usersBackend.Close()
+	meBackend := newNamedBackend("me")
+	defer meBackend.Close()
+
+	cfg := testConfig(
+		[]config.PoolConfig{
+			testPoolConfig("default", defaultBackend.URL),
+			testPoolConfig("orders", ordersBackend.URL),
+			testPoolConfig("items", itemsBackend.URL),
+			testPoolConfig("users", usersBackend.URL),
+			testPoolConfig("me", meBackend.URL),
+		},
+		[]config.RouteConfig{
+			{Path: "/orders/*", Pool: "orders"},
+			{Path: "/users/{id}", Pool: "users"},
+			{Path: "/orders/{id}/items/*", Pool: "items"},
+			{Path: "/users/me", Pool: "me"},
+		},
+	)
+
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/orders", expected: "orders"},
+		{path: "/orders/42", expected: "orders"},
+		{path: "/orders/42/items/7", expected: "items"},
+		{path: "/users/42", expected: "users"},
+		{path: "/users/me", expected: "me"},
+		{path: "/users/42/profile", expected: "default"},
+		{path: "/anything", expected: "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(t, table, "GET", tt.path)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+func TestTable_UnknownPool(t *testing.T) {
+	cfg := testConfig(
+		[]config.PoolConfig{testPoolConfig("default", "http://localhost:8080")},
+		[]config.RouteConfig{{Path: "/orders/*", Pool: "orders"}},
+	)
+
+	_, err := NewTable(cfg, &testLogger{})
+	assert.Error(t, err)
+}
+
+func TestTable_PoolStatuses(t *testing.T) {
+	optional := testPoolConfig("reports", "http://localhost:8081")
+	optional.Required = false
+
+	cfg := testConfig(
+		[]config.PoolConfig{testPoolConfig("default", "http://localhost:8080"), optional},
+		nil,
+	)
+
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	statuses := table.PoolStatuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "default", statuses[0].Name)
+	assert.True(t, statuses[0].Required)
+	assert.Equal(t, "reports", statuses[1].Name)
+	assert.False(t, statuses[1].Required)
+	assert.Len(t, statuses[1].Backends, 1)
+}
+
+func TestTable_VirtualHosts(t *testing.T) {
+	defaultBackend := newNamedBackend("default")
+	defer defaultBackend.Close()
+	apiBackend := newNamedBackend("api")
+	defer apiBackend.Close()
+	tenantsBackend := newNamedBackend("tenants")
+	defer tenantsBackend.Close()
+	ordersBackend := newNamedBackend("orders")
+	defer ordersBackend.Close()
+
+	cfg := testConfig(
+		[]config.PoolConfig{
+			testPoolConfig("default", defaultBackend.URL),
+			testPoolConfig("api", apiBackend.URL),
+			testPoolConfig("tenants", tenantsBackend.URL),
+			testPoolConfig("orders", ordersBackend.URL),
+		},
+		[]config.RouteConfig{
+			{Host: "api.example.com", Path: "/orders/*", Pool: "orders"},
+			{Path: "/shared/*", Pool: "default"},
+		},
+	)
+	cfg.VirtualHosts = []config.VirtualHostConfig{
+		{Host: "*.api.example.com", DefaultPool: "tenants"},
+		{Host: "api.example.com", DefaultPool: "api"},
+	}
+	cfg.UnmatchedHostStatus = http.StatusMisdirectedRequest
+
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expected       string
+	}{
+		{name: "exact host default", target: "http://api.example.com/users", expectedStatus: http.StatusOK, expected: "api"},
+		{name: "exact host with port", target: "http://api.example.com:3000/users", expectedStatus: http.StatusOK, expected: "api"},
+		{name: "host specific route", target: "http://api.example.com/orders/1", expectedStatus: http.StatusOK, expected: "orders"},
+		{name: "host route not shared", target: "http://acme.api.example.com/orders/1", expectedStatus: http.StatusOK, expected: "tenants"},
+		{name: "wildcard host", target: "http://acme.api.example.com/users", expectedStatus: http.StatusOK, expected: "tenants"},
+		{name: "nested wildcard host", target: "http://eu.acme.api.example.com/", expectedStatus: http.StatusOK, expected: "tenants"},
+		{name: "shared route on every host", target: "http://acme.api.example.com/shared/x", expectedStatus: http.StatusOK, expected: "default"},
+		{name: "unmatched host", target: "http://other.example.com/users", expectedStatus: http.StatusMisdirectedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, table, "GET", tt.target)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTable_RouteConditions(t *testing.T) {
+	defaultBackend := newNamedBackend("default")
+	defer defaultBackend.Close()
+	v2Backend := newNamedBackend("v2")
+	defer v2Backend.Close()
+	internalBackend := newNamedBackend("internal")
+	defer internalBackend.Close()
+	writesBackend := newNamedBackend("writes")
+	defer writesBackend.Close()
+
+	cfg := testConfig(
+		[]config.PoolConfig{
+			testPoolConfig("default", defaultBackend.URL),
+			testPoolConfig("v2", v2Backend.URL),
+			testPoolConfig("internal", internalBackend.URL),
+			testPoolConfig("writes", writesBackend.URL),
+		},
+		[]config.RouteConfig{
+			{Path: "/*", Pool: "v2", Headers: []config.ValueMatch{{Name: "X-Api-Version", Exact: "2"}}},
+			{Path: "/*", Pool: "v2", Queries: []config.ValueMatch{{Name: "version", Regex: "^2(\\.[0-9]+)?$"}}},
+			{Path: "/*", Pool: "internal", SourceCIDRs: []string{"10.0.0.0/8"}, Headers: []config.ValueMatch{{Name: "X-Internal"}}},
+			{Path: "/orders/*", Pool: "writes", Methods: []string{"POST", "PUT"}},
+		},
+	)
+
+	table, err := NewTable(cfg, &testLogger{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		header     http.Header
+		remoteAddr string
+		expected   string
+	}{
+		{name: "no conditions match", method: "GET", target: "/users", expected: "default"},
+		{name: "exact header", method: "GET", target: "/users", header: http.Header{"X-Api-Version": {"2"}}, expected: "v2"},
+		{name: "header value mismatch", method: "GET", target: "/users", header: http.Header{"X-Api-Version": {"3"}}, expected: "default"},
+		{name: "query regex", method: "GET", target: "/users?version=2.1", expected: "v2"},
+		{name: "query regex mismatch", method: "GET", target: "/users?version=20", expected: "default"},
+		{name: "cidr and header presence", method: "GET", target: "/users", header: http.Header{"X-Internal": {"yes"}}, remoteAddr: "10.1.2.3:5000", expected: "internal"},
+		{name: "cidr without header", method: "GET", target: "/users", remoteAddr: "10.1.2.3:5000", expected: "default"},
+		{name: "header outside cidr", method: "GET", target: "/users", header: http.Header{"X-Internal": {"yes"}}, remoteAddr: "192.168.1.1:5000", expected: "default"},
+		{name: "method match", method: "POST", target: "/orders/1", expected: "writes"},
+		{name: "method mismatch falls through", method: "GET", target: "/orders/1", expected: "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+			table.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"routing-api/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	maxQueuedSpans = 2048
+	maxBatchSpans  = 512
+	exportInterval = 5 * time.Second
+	exportTimeout  = 10 * time.Second
+	scopeName      = "routing-api"
+)
+
+// OTLP status codes.
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// exporter sends ended spans to a collector in batches, as OTLP/HTTP JSON.
+// Spans that arrive while the queue is full are dropped rather than holding up
+// requests.
+type exporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	queue    chan *Span
+	stop     chan struct{}
+	done     chan struct{}
+	// pending holds the spans run had batched when it stopped.
+	pending  []*Span
+	dropped  atomic.Int64
+	interval time.Duration
+	logger   logger.Logger
+}
+
+func newExporter(endpoint, service string, logger logger.Logger) *exporter {
+	return &exporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: exportTimeout},
+		queue:    make(chan *Span, maxQueuedSpans),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		interval: exportInterval,
+		logger:   logger.With(zap.String("tracing_endpoint", endpoint)),
+	}
+}
+
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSpans)
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < maxBatchSpans {
+				continue
+			}
+		case <-ticker.C:
+		case <-e.stop:
+			e.pending = batch
+			return
+		}
+		e.export(context.Background(), batch)
+		batch = batch[:0]
+	}
+}
+
+// shutdown stops the background export and sends what is left in the queue.
+func (e *exporter) shutdown(ctx context.Context) {
+	close(e.stop)
+	<-e.done
+
+	batch := e.pending
+drain:
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+		default:
+			break drain
+		}
+	}
+	for len(batch) > 0 && ctx.Err() == nil {
+		n := min(len(batch), maxBatchSpans)
+		e.export(ctx, batch[:n])
+		batch = batch[n:]
+	}
+}
+
+func (e *exporter) export(ctx context.Context, batch []*Span) {
+	if dropped := e.dropped.Swap(0); dropped > 0 {
+		e.logger.Warn("Dropped spans because the export queue was full", zap.Int64("spans", dropped))
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		e.logger.Error("Cannot encode spans", zap.Error(err))
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		e.logger.Error("Cannot export spans", zap.Error(err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.logger.Warn("Cannot export spans", zap.Int("spans", len(batch)), zap.Error(err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		e.logger.Warn("Collector rejected spans",
+			zap.Int("spans", len(batch)),
+			zap.Int("status", resp.StatusCode),
+			zap.String("response", string(message)),
+		)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+}
+
+// The OTLP/HTTP JSON encoding of an export request. IDs are hex and 64-bit
+// integers are strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		TraceState        string          `json:"traceState,omitempty"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+)
+
+func (e *exporter) request(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, span := range batch {
+		spans[i] = span.otlp()
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{attribute(String("service.name", e.service))}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: spans}},
+	}}}
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	span := otlpSpan{
+		TraceID:           s.context.TraceID.String(),
+		SpanID:            s.context.SpanID.String(),
+		TraceState:        s.context.TraceState,
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: statusUnset},
+	}
+	if s.parent.IsValid() {
+		span.ParentSpanID = s.parent.String()
+	}
+	if s.failed {
+		span.Status = otlpStatus{Code: statusError, Message: s.message}
+	}
+	for _, a := range s.attributes {
+		span.Attributes = append(span.Attributes, attribute(a))
+	}
+	return span
+}
+
+func attribute(a Attribute) otlpAttribute {
+	var value otlpValue
+	switch v := a.Value.(type) {
+	case string:
+		value.StringValue = &v
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		value.IntValue = &s
+	case bool:
+		value.BoolValue = &v
+	default:
+		s := fmt.Sprint(v)
+		value.StringValue = &s
+	}
+	return otlpAttribute{Key: a.Key, Value: value}
+}
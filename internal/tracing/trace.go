@@ -0,0 +1,139 @@
+// Package tracing records spans of proxied requests, propagates W3C trace
+// context to backends and exports the spans to an OpenTelemetry collector over
+// OTLP/HTTP.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	traceparentHeader = "Traceparent"
+	tracestateHeader  = "Tracestate"
+	flagSampled       = 0x01
+)
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool  { return t != TraceID{} }
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+// Extract reads the trace context a caller sent in the traceparent and
+// tracestate headers. The result is not valid when there is none or it is
+// malformed.
+func Extract(header http.Header) SpanContext {
+	context, ok := parseTraceparent(header.Get(traceparentHeader))
+	if !ok {
+		return SpanContext{}
+	}
+	context.TraceState = strings.Join(header.Values(tracestateHeader), ",")
+	return context
+}
+
+// Inject writes context to the traceparent and tracestate headers, replacing
+// those of the caller.
+func Inject(header http.Header, context SpanContext) {
+	if !context.IsValid() {
+		return
+	}
+	flags := "00"
+	if context.Sampled {
+		flags = "01"
+	}
+	header.Set(traceparentHeader, "00-"+context.TraceID.String()+"-"+context.SpanID.String()+"-"+flags)
+	header.Del(tracestateHeader)
+	if context.TraceState != "" {
+		header.Set(tracestateHeader, context.TraceState)
+	}
+}
+
+// parseTraceparent reads a header of the form version-traceid-spanid-flags.
+// Versions after 00 may append fields, which are ignored.
+func parseTraceparent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+	version, ok := decodeHex(value[0:2])
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(value) != 55) || (len(value) > 55 && value[55] != '-') {
+		return SpanContext{}, false
+	}
+
+	var context SpanContext
+	traceID, ok := decodeHex(value[3:35])
+	if !ok {
+		return SpanContext{}, false
+	}
+	copy(context.TraceID[:], traceID)
+	spanID, ok := decodeHex(value[36:52])
+	if !ok {
+		return SpanContext{}, false
+	}
+	copy(context.SpanID[:], spanID)
+	flags, ok := decodeHex(value[53:55])
+	if !ok {
+		return SpanContext{}, false
+	}
+	context.Sampled = flags[0]&flagSampled != 0
+	return context, context.IsValid()
+}
+
+// decodeHex accepts lowercase hex only, as trace context requires.
+func decodeHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// sampleBound turns a ratio into a bound on the low 63 bits of trace IDs, the
+// same way OpenTelemetry's TraceIdRatioBased sampler does, so every service
+// using a ratio makes the same decision for a trace.
+func sampleBound(ratio float64) uint64 {
+	if ratio >= 1 {
+		return 1 << 63
+	}
+	return uint64(ratio * (1 << 63))
+}
+
+func sampled(traceID TraceID, bound uint64) bool {
+	return binary.BigEndian.Uint64(traceID[8:])>>1 < bound
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		valid       bool
+		sampled     bool
+	}{
+		{name: "sampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true, sampled: true},
+		{name: "not sampled", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{name: "future version with extra fields", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true, sampled: true},
+		{name: "version 00 with extra fields", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "invalid version", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "uppercase", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "zero trace ID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero span ID", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "truncated", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{name: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.traceparent != "" {
+				header.Set("traceparent", tt.traceparent)
+			}
+			header.Add("tracestate", "a=1")
+			header.Add("tracestate", "b=2")
+
+			context := Extract(header)
+			assert.Equal(t, tt.valid, context.IsValid())
+			if tt.valid {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", context.TraceID.String())
+				assert.Equal(t, "00f067aa0ba902b7", context.SpanID.String())
+				assert.Equal(t, tt.sampled, context.Sampled)
+				assert.Equal(t, "a=1,b=2", context.TraceState)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Add("tracestate", "old=1")
+	context := Extract(header)
+	context.SpanID = SpanID{1, 2, 3, 4, 5, 6, 7, 8}
+	context.TraceState = "new=1"
+
+	Inject(header, context)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-0102030405060708-01", header.Get("traceparent"))
+	assert.Equal(t, []string{"new=1"}, header.Values("tracestate"))
+
+	context.Sampled = false
+	context.TraceState = ""
+	Inject(header, context)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-0102030405060708-00", header.Get("traceparent"))
+	assert.Empty(t, header.Values("tracestate"))
+
+	Inject(header, SpanContext{})
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-0102030405060708-00", header.Get("traceparent"))
+}
+
+func TestSampled(t *testing.T) {
+	low := TraceID{8: 0x00, 15: 0x02}
+	high := TraceID{8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff}
+
+	assert.False(t, sampled(low, sampleBound(0)))
+	assert.True(t, sampled(low, sampleBound(0.5)))
+	assert.False(t, sampled(high, sampleBound(0.5)))
+	assert.True(t, sampled(high, sampleBound(1)))
+}
@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+)
+
+type SpanKind int
+
+// Span kinds, numbered as in OTLP.
+const (
+	SpanKindServer SpanKind = 2
+	SpanKindClient SpanKind = 3
+)
+
+// Attribute is a key and a string, int64 or bool value.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attribute    { return Attribute{key, value} }
+func Int(key string, value int) Attribute   { return Attribute{key, int64(value)} }
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// Tracer starts spans and exports those that are sampled. A nil Tracer starts
+// nil spans, whose methods do nothing, so callers need not check whether
+// tracing is enabled.
+type Tracer struct {
+	bound    uint64
+	exporter *exporter
+}
+
+var global atomic.Pointer[Tracer]
+
+func NewTracer(tracing config.TracingConfig, logger logger.Logger) *Tracer {
+	return &Tracer{
+		bound:    sampleBound(tracing.SampleRatio),
+		exporter: newExporter(tracing.Endpoint, tracing.ServiceName, logger),
+	}
+}
+
+// SetGlobal makes t the tracer returned by Global.
+func SetGlobal(t *Tracer) {
+	global.Store(t)
+}
+
+// Global returns the tracer set with SetGlobal, or nil when tracing is off.
+func Global() *Tracer {
+	return global.Load()
+}
+
+// Start begins exporting spans in the background.
+func (t *Tracer) Start() {
+	go t.exporter.run()
+}
+
+// Shutdown exports the spans that are still queued, giving up when ctx is done.
+func (t *Tracer) Shutdown(ctx context.Context) {
+	t.exporter.shutdown(ctx)
+}
+
+// StartSpan begins a span as a child of parent, or as the root of a new trace
+// when parent is not valid. Children follow the sampling decision of their
+// parent; new traces are sampled at the configured ratio.
+func (t *Tracer) StartSpan(name string, kind SpanKind, parent SpanContext) *Span {
+	if t == nil {
+		return nil
+	}
+
+	spanContext := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		spanContext.TraceID = parent.TraceID
+		spanContext.Sampled = parent.Sampled
+		spanContext.TraceState = parent.TraceState
+	} else {
+		spanContext.TraceID = newTraceID()
+		spanContext.Sampled = sampled(spanContext.TraceID, t.bound)
+	}
+	return &Span{
+		tracer:  t,
+		context: spanContext,
+		parent:  parent.SpanID,
+		name:    name,
+		kind:    kind,
+		start:   time.Now(),
+	}
+}
+
+// Span is an operation within a trace. Only sampled spans keep attributes and
+// are exported when they end.
+type Span struct {
+	tracer     *Tracer
+	context    SpanContext
+	parent     SpanID
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	mutex      sync.Mutex
+	attributes []Attribute
+	failed     bool
+	message    string
+	ended      bool
+}
+
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// Recording reports whether the span will be exported, for skipping attributes
+// that are costly to compute.
+func (s *Span) Recording() bool {
+	return s != nil && s.context.Sampled
+}
+
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if !s.Recording() {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// SetError marks the operation as failed.
+func (s *Span) SetError(message string) {
+	if !s.Recording() {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failed = true
+	s.message = message
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if !s.Recording() {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mutex.Unlock()
+
+	s.tracer.exporter.enqueue(s)
+}
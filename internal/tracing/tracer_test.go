@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"routing-api/internal/config"
+	"routing-api/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testLogger struct{}
+
+func (l *testLogger) Debug(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Info(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Warn(msg string, fields ...zap.Field)   {}
+func (l *testLogger) Error(msg string, fields ...zap.Field)  {}
+func (l *testLogger) Fatal(msg string, fields ...zap.Field)  {}
+func (l *testLogger) With(fields ...zap.Field) logger.Logger { return l }
+func (l *testLogger) Sync() error                            { return nil }
+
+// collector records the export requests it receives.
+type collector struct {
+	mutex    sync.Mutex
+	requests []otlpRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var request otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mutex.Lock()
+	c.requests = append(c.requests, request)
+	c.mutex.Unlock()
+}
+
+func (c *collector) received() []otlpRequest {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.requests
+}
+
+func (c *collector) spans() []otlpSpan {
+	var spans []otlpSpan
+	for _, request := range c.received() {
+		for _, resourceSpans := range request.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func newTestTracer(t *testing.T, ratio float64) (*Tracer, *collector) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	t.Cleanup(server.Close)
+
+	tracer := NewTracer(config.TracingConfig{
+		Endpoint:    server.URL + "/v1/traces",
+		SampleRatio: ratio,
+		ServiceName: "routing-api-test",
+	}, &testLogger{})
+	tracer.Start()
+	return tracer, c
+}
+
+func TestTracer_ExportsSpans(t *testing.T) {
+	tracer, collector := newTestTracer(t, 1)
+
+	parent := Extract(http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	server := tracer.StartSpan("GET /orders", SpanKindServer, parent)
+	server.SetAttributes(String("http.route", "/orders"), Int("http.response.status_code", 502), Bool("retried", false))
+	client := tracer.StartSpan("GET", SpanKindClient, server.Context())
+	client.SetError("connection refused")
+	client.End()
+	server.End()
+	server.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tracer.Shutdown(ctx)
+
+	requests := collector.received()
+	require.Len(t, requests, 1)
+	resource := requests[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", resource.Resource.Attributes[0].Key)
+	assert.Equal(t, "routing-api-test", *resource.Resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, scopeName, resource.ScopeSpans[0].Scope.Name)
+
+	spans := collector.spans()
+	require.Len(t, spans, 2)
+	clientSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.ParentSpanID)
+	assert.Equal(t, SpanKindServer, serverSpan.Kind)
+	assert.Equal(t, statusUnset, serverSpan.Status.Code)
+	require.Len(t, serverSpan.Attributes, 3)
+	assert.Equal(t, "/orders", *serverSpan.Attributes[0].Value.StringValue)
+	assert.Equal(t, "502", *serverSpan.Attributes[1].Value.IntValue)
+	assert.False(t, *serverSpan.Attributes[2].Value.BoolValue)
+	assert.NotEmpty(t, serverSpan.StartTimeUnixNano)
+	assert.NotEmpty(t, serverSpan.EndTimeUnixNano)
+
+	assert.Equal(t, serverSpan.TraceID, clientSpan.TraceID)
+	assert.Equal(t, serverSpan.SpanID, clientSpan.ParentSpanID)
+	assert.Equal(t, SpanKindClient, clientSpan.Kind)
+	assert.Equal(t, otlpStatus{Code: statusError, Message: "connection refused"}, clientSpan.Status)
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	tracer, collector := newTestTracer(t, 0)
+
+	root := tracer.StartSpan("GET /", SpanKindServer, SpanContext{})
+	assert.True(t, root.Context().IsValid())
+	assert.False(t, root.Recording())
+	root.End()
+
+	// A caller that sampled the trace overrides the ratio, as does one that did
+	// not.
+	sampledParent := Extract(http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	child := tracer.StartSpan("GET /", SpanKindServer, sampledParent)
+	assert.True(t, child.Recording())
+	child.End()
+
+	unsampledParent := Extract(http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}})
+	assert.False(t, tracer.StartSpan("GET /", SpanKindServer, unsampledParent).Recording())
+
+	tracer.Shutdown(context.Background())
+	spans := collector.spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, child.Context().SpanID.String(), spans[0].SpanID)
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartSpan("GET /", SpanKindServer, SpanContext{})
+	assert.Nil(t, span)
+	assert.False(t, span.Recording())
+	assert.False(t, span.Context().IsValid())
+	span.SetAttributes(String("key", "value"))
+	span.SetError("failed")
+	span.End()
+}
+
+func TestExporter_DropsWhenQueueIsFull(t *testing.T) {
+	tracer := NewTracer(config.TracingConfig{Endpoint: "http://127.0.0.1:0/v1/traces", SampleRatio: 1, ServiceName: "test"}, &testLogger{})
+	for range maxQueuedSpans + 10 {
+		tracer.StartSpan("GET /", SpanKindServer, SpanContext{}).End()
+	}
+	assert.Len(t, tracer.exporter.queue, maxQueuedSpans)
+	assert.Equal(t, int64(10), tracer.exporter.dropped.Load())
+}